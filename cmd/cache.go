@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing"
+	"github.com/ethpandaops/xatu-cbt/internal/testing/output"
+	"github.com/olekukonko/tablewriter"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// defaultCacheMaxSize is the default parquet cache size limit (10GB).
+const defaultCacheMaxSize int64 = 10 * 1024 * 1024 * 1024
+
+var (
+	cacheDir     string
+	cacheMaxSize int64
+	cacheVerbose bool
+	cacheFix     bool
+
+	errCacheVerifyFailed = errors.New("some cached files failed verification")
+)
+
+// cacheCmd represents the parquet cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local parquet cache",
+	Long: `Inspect and maintain the parquet cache used by 'xatu-cbt test'.
+
+All subcommands read the same manifest as the test runner, so they must point
+at the same --cache-dir.`,
+	SilenceUsage: true,
+}
+
+// cacheLsCmd lists cached files
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List cached parquet files",
+	Long: `List cached parquet files, most recently used first.
+
+Example:
+  xatu-cbt cache ls`,
+	RunE: runCacheLs,
+}
+
+// cachePruneCmd evicts least recently used files
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Evict least recently used files until the cache fits --cache-max-size",
+	Long: `Evict least recently used parquet files until the cache fits within
+--cache-max-size, and remove files that are not tracked by the manifest
+(e.g. interrupted downloads).
+
+Example:
+  xatu-cbt cache prune
+  xatu-cbt cache prune --cache-max-size 2147483648`,
+	RunE: runCachePrune,
+}
+
+// cacheVerifyCmd checks cached files against their recorded SHA256
+var cacheVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify cached files against their recorded SHA256",
+	Long: `Verify every cached parquet file against the SHA256 recorded when it
+was downloaded. Use --fix to remove missing or corrupt entries so they are
+downloaded again on the next test run.
+
+Example:
+  xatu-cbt cache verify
+  xatu-cbt cache verify --fix`,
+	RunE: runCacheVerify,
+}
+
+// cacheClearCmd removes all cached files
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached parquet files",
+	Long: `Remove all cached parquet files and reset the manifest.
+
+Example:
+  xatu-cbt cache clear`,
+	RunE: runCacheClear,
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheLsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheVerifyCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", getDefaultCacheDir(), "Parquet cache directory")
+	cacheCmd.PersistentFlags().Int64Var(&cacheMaxSize, "cache-max-size", defaultCacheMaxSize, "Max cache size in bytes (10GB)")
+	cacheCmd.PersistentFlags().BoolVar(&cacheVerbose, "verbose", false, "Verbose output")
+	cacheVerifyCmd.Flags().BoolVar(&cacheFix, "fix", false, "Remove missing or corrupt entries from the cache")
+}
+
+// openParquetCache loads the parquet cache manifest from --cache-dir.
+func openParquetCache(cmd *cobra.Command) (*testing.ParquetCache, logrus.FieldLogger, error) {
+	log := newLogger(cacheVerbose)
+
+	cache := testing.NewParquetCache(log, nil, cacheDir, cacheMaxSize, testing.NewCollector(log))
+	if err := cache.Start(cmd.Context()); err != nil {
+		return nil, nil, fmt.Errorf("opening parquet cache: %w", err)
+	}
+
+	return cache, log, nil
+}
+
+func runCacheLs(cmd *cobra.Command, _ []string) error {
+	cache, _, err := openParquetCache(cmd)
+	if err != nil {
+		return err
+	}
+
+	files := cache.Entries()
+	if len(files) == 0 {
+		fmt.Printf("Parquet cache %s is empty\n", cacheDir)
+
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Table", "Size", "Last Used", "Downloaded", "URL"})
+	table.SetBorder(true)
+	table.SetRowLine(false)
+	table.SetAutoWrapText(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+
+	var total int64
+
+	for _, file := range files {
+		total += file.Size
+
+		table.Append([]string{
+			file.Table,
+			output.FormatBytes(file.Size),
+			file.LastUsed.Local().Format(time.DateTime),
+			file.Downloaded.Local().Format(time.DateTime),
+			file.URL,
+		})
+	}
+
+	table.Render()
+
+	fmt.Printf("%d files, %s of %s max\n", len(files), output.FormatBytes(total), output.FormatBytes(cacheMaxSize))
+
+	return nil
+}
+
+func runCachePrune(cmd *cobra.Command, _ []string) error {
+	cache, log, err := openParquetCache(cmd)
+	if err != nil {
+		return err
+	}
+
+	evicted, err := cache.Prune(cacheMaxSize)
+	if err != nil {
+		return fmt.Errorf("pruning parquet cache: %w", err)
+	}
+
+	var freed int64
+	for _, file := range evicted {
+		freed += file.Size
+
+		log.WithFields(logrus.Fields{
+			"table": file.Table,
+			"url":   file.URL,
+			"size":  output.FormatBytes(file.Size),
+		}).Info("evicted cached file")
+	}
+
+	log.WithFields(logrus.Fields{
+		"evicted": len(evicted),
+		"freed":   output.FormatBytes(freed),
+	}).Info("parquet cache pruned")
+
+	return nil
+}
+
+func runCacheVerify(cmd *cobra.Command, _ []string) error {
+	cache, log, err := openParquetCache(cmd)
+	if err != nil {
+		return err
+	}
+
+	results, err := cache.Verify(cacheFix)
+	if err != nil {
+		return fmt.Errorf("verifying parquet cache: %w", err)
+	}
+
+	failed := 0
+
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+
+		failed++
+
+		log.WithError(result.Err).WithFields(logrus.Fields{
+			"table": result.File.Table,
+			"url":   result.File.URL,
+		}).Warn("cached file failed verification")
+	}
+
+	log.WithFields(logrus.Fields{
+		"verified": len(results),
+		"failed":   failed,
+		"removed":  cacheFix && failed > 0,
+	}).Info("parquet cache verified")
+
+	if failed > 0 && !cacheFix {
+		return errCacheVerifyFailed
+	}
+
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, _ []string) error {
+	cache, log, err := openParquetCache(cmd)
+	if err != nil {
+		return err
+	}
+
+	removed, err := cache.Clear()
+	if err != nil {
+		return fmt.Errorf("clearing parquet cache: %w", err)
+	}
+
+	log.WithField("removed", removed).Info("parquet cache cleared")
+
+	return nil
+}
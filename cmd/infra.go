@@ -33,6 +33,7 @@ var (
 	infraCleanupTestDBs bool
 	infraVerbose        bool
 	infraClickhouseURL  string
+	infraXatuURL        string
 	infraRedisURL       string
 	infraRunMigrations  bool
 	infraXatuRef        string
@@ -142,6 +143,7 @@ func init() {
 	infraMigrateXatuCmd.Flags().StringVar(&infraXatuRef, "xatu-ref", config.XatuDefaultRef, "Xatu repository ref (branch/tag/commit)")
 	infraCmd.PersistentFlags().StringVar(&infraProjectName, "project-name", config.GetProjectName(), fmt.Sprintf("Docker Compose project name (env %s)", config.ProjectNameEnvVar))
 	infraCmd.PersistentFlags().StringVar(&infraClickhouseURL, "clickhouse-url", config.GetCBTClickHouseURL(), "CBT ClickHouse cluster URL")
+	infraCmd.PersistentFlags().StringVar(&infraXatuURL, "xatu-clickhouse-url", config.GetXatuClickHouseURL(), "Xatu ClickHouse cluster URL (external test databases)")
 	infraCmd.PersistentFlags().StringVar(&infraRedisURL, "redis-url", config.GetRedisURL(), "Redis connection URL")
 	infraStopCmd.Flags().BoolVar(&infraCleanupTestDBs, "cleanup-test-dbs", true, "Cleanup ephemeral test databases")
 	infraStatusCmd.Flags().BoolVar(&infraVerbose, "verbose", false, "Show detailed container and database information")
@@ -184,6 +186,7 @@ func createInfraManagers(log logrus.FieldLogger) (infra.DockerManager, infra.Cli
 		log,
 		dockerManager,
 		infraClickhouseURL,
+		infraXatuURL,
 		cfg.SafeHostnames,
 	)
 
@@ -60,6 +60,8 @@ func init() {
 
 	rootCmd.AddCommand(testCmd)
 	rootCmd.AddCommand(infraCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(testdataCmd)
 }
 
 // loadEnvFile loads the specified environment file.
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -11,8 +12,10 @@ import (
 	"time"
 
 	"github.com/ethpandaops/xatu-cbt/internal/config"
+	"github.com/ethpandaops/xatu-cbt/internal/infra"
 	"github.com/ethpandaops/xatu-cbt/internal/testing"
 	"github.com/ethpandaops/xatu-cbt/internal/testing/assertion"
+	"github.com/ethpandaops/xatu-cbt/internal/testing/report"
 	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -30,9 +33,36 @@ var (
 	testCleanupTestDB bool
 	xatuClickhouseURL string
 	cbtClickhouseURL  string
+	cbtClickhouseHTTP string
 	redisURL          string
 	xatuRepoURL       string
 	xatuRef           string
+	testReport        string
+	testChangedBase   string
+	testNewForce      bool
+	testUpdateSnaps   bool
+	testScenario      string
+	testIdempotency   bool
+	testChunking      bool
+	testChunkMax      uint64
+	testDiffBase      string
+	testAllowColumns  string
+	testKeepFailed    bool
+	testManifest      string
+	testArtifactsDir  string
+	testCBTRunner     string
+	testCBTBinary     string
+	testInspectQuery  string
+	testInspectOn     string
+	testInspectConfig bool
+	testInspectSQL    bool
+	testInspectRows   int
+	testGCOlderThan   time.Duration
+	testGCDryRun      bool
+
+	errTestDefinitionExists = errors.New("test definition already exists (use --force to overwrite)")
+	errDiffsFound           = errors.New("outputs differ from base ref")
+	errGCFailed             = errors.New("some ephemeral databases could not be dropped")
 )
 
 // testCmd represents the test command
@@ -61,9 +91,14 @@ Models are specified as a comma-separated list. Each model test:
 - Executes assertions
 - Cleans up database
 
+Models with scenarios run once per scenario, reported as model[scenario];
+--scenario runs only the named scenarios; a name that matches no test is an error.
+
 Example:
   xatu-cbt test models fct_block --network mainnet
-  xatu-cbt test models fct_block,fct_attestation --network mainnet`,
+  xatu-cbt test models fct_block,fct_attestation --network mainnet
+  xatu-cbt test models fct_block --scenario reorg --network mainnet
+  xatu-cbt test models fct_block --report junit=report.xml,json=report.json`,
 	Args:         cobra.ExactArgs(1),
 	RunE:         runTestModels,
 	SilenceUsage: true,
@@ -85,6 +120,128 @@ Example:
 	SilenceUsage: true,
 }
 
+// testChangedCmd tests the models affected by a git diff
+var testChangedCmd = &cobra.Command{
+	Use:   "changed",
+	Short: "Test models affected by changes relative to a git ref",
+	Long: `Test only the models affected by changes relative to a git ref.
+
+Diffs models/, migrations/ and tests/ against the merge base of --base and
+HEAD (including uncommitted and untracked files), maps changed files to
+models and walks the reverse dependency graph to find every downstream
+transformation. Changed external models and changed parquet URLs in test
+definitions mark their dependents as affected. Changes outside these
+directories that may affect any test (e.g. overrides or harness code) run
+the full suite.
+
+Example:
+  xatu-cbt test changed --base origin/master --network mainnet`,
+	RunE:         runTestChanged,
+	SilenceUsage: true,
+}
+
+// testDiffCmd compares model output between a base git ref and the working tree
+var testDiffCmd = &cobra.Command{
+	Use:   "diff [model1,model2,...]",
+	Short: "Compare model output between a base git ref and the working tree",
+	Long: `Run the same fixtures through the models of a base git ref and of the
+working tree, and report per-table differences.
+
+The base ref is checked out into a temporary git worktree. Its migrations
+build a separate CBT template database, and each test runs both versions
+in separate cbt_* databases that read one shared external database. Every
+transformation table in the dependency graph is compared:
+- row counts and rows found in only one version (under FINAL)
+- added, removed and retyped columns
+- columns whose values differ
+
+--allow-columns accepts expected changes, as column (any table) or
+table.column. updated_date_time is always ignored. The command fails if
+any other difference is found.
+
+Example:
+  xatu-cbt test diff fct_block --base origin/master --network mainnet
+  xatu-cbt test diff fct_block --allow-columns fct_block.block_total_bytes`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runTestDiff,
+	SilenceUsage: true,
+}
+
+// testNewCmd scaffolds a test definition for a model
+var testNewCmd = &cobra.Command{
+	Use:   "new <model>",
+	Short: "Scaffold a test definition for a model",
+	Long: `Generate tests/{network}/models/<model>.yaml for a model.
+
+The generated definition contains:
+- external_data for every leaf external table the model depends on, with
+  placeholder URLs (fill them with 'xatu-cbt testdata export')
+- baseline assertions derived from the model's table DDL in the CBT template
+  database: row count > 0, no empty ORDER BY key columns and no duplicate
+  keys under FINAL
+
+If tests/_common/models/<model>.yaml exists, the definition extends it and
+holds only the external_data instead of baseline assertions.
+
+Requires a running platform ('xatu-cbt infra start') with the CBT template
+database prepared (any previous test run creates it).
+
+Example:
+  xatu-cbt test new fct_block --network mainnet`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runTestNew,
+	SilenceUsage: true,
+}
+
+// testInspectCmd explores the databases kept by a previous test run
+var testInspectCmd = &cobra.Command{
+	Use:   "inspect <model|model[scenario]>",
+	Short: "Inspect the databases kept by a previous test run",
+	Long: `Inspect the external and CBT databases a previous test run kept for a test.
+
+Test runs record the databases they keep in a run manifest, together with the
+rendered CBT config and the assertion SQL. Databases are kept for every test
+unless --cleanup-test-db is set; with --keep-failed only failing tests keep
+theirs.
+
+Without flags, prints the connection details of the ext/cbt pair and lists
+their tables with row counts.
+
+Examples:
+  xatu-cbt test models fct_block --keep-failed
+  xatu-cbt test inspect fct_block
+  xatu-cbt test inspect fct_block --query "SELECT count() FROM fct_block FINAL"
+  xatu-cbt test inspect fct_block --on ext --query "SELECT * FROM canonical_beacon_block LIMIT 5"
+  xatu-cbt test inspect fct_block --assertions
+  xatu-cbt test inspect fct_block --config`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runTestInspect,
+	SilenceUsage: true,
+}
+
+// testGCCmd drops per-test databases left behind by earlier runs
+var testGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Drop orphaned per-test databases on both clusters",
+	Long: `Find and drop per-test databases left behind by interrupted or kept test runs.
+
+Collects ext_* databases on the xatu cluster and cbt_* (and legacy test_*)
+databases on the CBT cluster, dropping them ON CLUSTER. Template databases are
+never collected. A database's age comes from the timestamp in its name, or
+from its table metadata when the name has none.
+
+Replica paths of collected tables are removed from Keeper, as are replicas
+left behind by per-test databases that no longer exist.
+
+Examples:
+  xatu-cbt test gc --dry-run
+  xatu-cbt test gc --older-than 6h
+  xatu-cbt test gc --older-than 0   # everything, including running tests`,
+	Args:         cobra.NoArgs,
+	RunE:         runTestGC,
+	SilenceUsage: true,
+}
+
 // runTestsWithConfig sets up the orchestrator, runs tests, and returns results.
 // The testRunner function is called to execute the actual tests.
 // Output is handled by the orchestrator's internal formatter.
@@ -125,19 +282,47 @@ func runTestsWithConfig(
 func init() {
 	testCmd.AddCommand(testModelsCmd)
 	testCmd.AddCommand(testAllCmd)
+	testCmd.AddCommand(testChangedCmd)
+	testCmd.AddCommand(testDiffCmd)
+	testCmd.AddCommand(testNewCmd)
+	testCmd.AddCommand(testInspectCmd)
+	testCmd.AddCommand(testGCCmd)
+	testModelsCmd.Flags().StringVar(&testScenario, "scenario", "", "Run only these scenarios of the models (comma-separated)")
+	testNewCmd.Flags().BoolVar(&testNewForce, "force", false, "Overwrite an existing test definition")
+	testInspectCmd.Flags().StringVar(&testInspectQuery, "query", "", "Run an ad-hoc SQL query against the kept databases")
+	testInspectCmd.Flags().StringVar(&testInspectOn, "on", "", "Database to query: cbt or ext (default: the one the assertions ran against)")
+	testInspectCmd.Flags().BoolVar(&testInspectConfig, "config", false, "Print the rendered CBT config the test ran with")
+	testInspectCmd.Flags().BoolVar(&testInspectSQL, "assertions", false, "Print the assertion SQL as it ran against the kept database")
+	testInspectCmd.Flags().IntVar(&testInspectRows, "max-rows", 100, "Maximum rows to print for --query")
+	testGCCmd.Flags().DurationVar(&testGCOlderThan, "older-than", 6*time.Hour, "Only drop databases older than this (0 drops all)")
+	testGCCmd.Flags().BoolVar(&testGCDryRun, "dry-run", false, "List what would be dropped without dropping it")
+	testChangedCmd.Flags().StringVar(&testChangedBase, "base", "origin/master", "Git ref to diff against")
+	testDiffCmd.Flags().StringVar(&testDiffBase, "base", "origin/master", "Git ref whose models to compare against")
+	testDiffCmd.Flags().StringVar(&testAllowColumns, "allow-columns", "", "Columns allowed to differ (comma-separated, column or table.column)")
 	testCmd.PersistentFlags().StringVar(&testNetwork, "network", "mainnet", "Network name (mainnet, sepolia)")
 	testCmd.PersistentFlags().DurationVar(&testTimeout, "timeout", 30*time.Minute, "Test timeout")
 	testCmd.PersistentFlags().BoolVar(&testVerbose, "verbose", false, "Verbose output")
 	testCmd.PersistentFlags().StringVar(&testCacheDir, "cache-dir", getDefaultCacheDir(), "Parquet cache directory")
-	testCmd.PersistentFlags().Int64Var(&testCacheSize, "cache-max-size", 10*1024*1024*1024, "Max cache size in bytes (10GB)")
-	testCmd.PersistentFlags().IntVar(&testConcurrency, "concurrency", 15, "Number of tests to run in parallel (max 15)")
+	testCmd.PersistentFlags().Int64Var(&testCacheSize, "cache-max-size", defaultCacheMaxSize, "Max cache size in bytes (10GB)")
+	testCmd.PersistentFlags().IntVar(&testConcurrency, "concurrency", 15, "Number of tests to run in parallel")
 	testCmd.PersistentFlags().BoolVar(&testForceRebuild, "force-rebuild", false, "Force rebuild of xatu cluster (clear tables and re-run migrations)")
 	testCmd.PersistentFlags().BoolVar(&testCleanupTestDB, "cleanup-test-db", false, "Cleanup test database on completion (useful for CI, disabled by default for debugging)")
+	testCmd.PersistentFlags().BoolVar(&testKeepFailed, "keep-failed", false, "Keep only the databases of failing tests, dropping the rest (see 'test inspect')")
+	testCmd.PersistentFlags().StringVar(&testManifest, "manifest", getDefaultManifestPath(), "Run manifest recording kept test databases")
+	testCmd.PersistentFlags().StringVar(&testArtifactsDir, "artifacts-dir", getDefaultArtifactsDir(), "Directory for per-run test artifacts such as CBT logs (empty disables)")
 	testCmd.PersistentFlags().StringVar(&xatuClickhouseURL, "xatu-clickhouse-url", config.GetXatuClickHouseURL(), "Xatu ClickHouse cluster URL (external data)")
 	testCmd.PersistentFlags().StringVar(&cbtClickhouseURL, "cbt-clickhouse-url", config.GetCBTClickHouseURL(), "CBT ClickHouse cluster URL (transformations)")
+	testCmd.PersistentFlags().StringVar(&cbtClickhouseHTTP, "cbt-clickhouse-http-url", "", "CBT ClickHouse HTTP URL for --cbt-runner=binary (default: host and credentials of --cbt-clickhouse-url on the CBT HTTP port)")
 	testCmd.PersistentFlags().StringVar(&redisURL, "redis-url", config.GetRedisURL(), "Redis connection URL")
+	testCmd.PersistentFlags().StringVar(&testCBTRunner, "cbt-runner", testing.CBTRunnerDocker, "How to run CBT per test: docker (a container) or binary (a local subprocess)")
+	testCmd.PersistentFlags().StringVar(&testCBTBinary, "cbt-binary", "cbt", "CBT binary for --cbt-runner=binary (path or name on PATH)")
 	testCmd.PersistentFlags().StringVar(&xatuRepoURL, "xatu-repo", config.XatuRepoURL, "Xatu repository URL")
 	testCmd.PersistentFlags().StringVar(&xatuRef, "xatu-ref", config.XatuDefaultRef, "Xatu repository ref (branch/tag/commit)")
+	testCmd.PersistentFlags().BoolVar(&testUpdateSnaps, "update-snapshots", false, "Rewrite snapshot assertion files from query results instead of comparing")
+	testCmd.PersistentFlags().BoolVar(&testIdempotency, "check-idempotency", false, "After assertions pass, reprocess each incremental model and fail if its rows change")
+	testCmd.PersistentFlags().BoolVar(&testChunking, "check-chunking", false, "After assertions pass, rerun each incremental model in small intervals and fail if its rows differ")
+	testCmd.PersistentFlags().Uint64Var(&testChunkMax, "chunk-interval-max", 0, "Interval max of the model in chunked runs, in positions: seconds for slot models, blocks for block models (0 = one slot or block)")
+	testCmd.PersistentFlags().StringVar(&testReport, "report", "", "Write machine-readable reports, e.g. junit=report.xml,json=report.json")
 }
 
 func runTestModels(cmd *cobra.Command, args []string) error {
@@ -149,8 +334,16 @@ func runTestModels(cmd *cobra.Command, args []string) error {
 		modelNames[i] = strings.TrimSpace(name)
 	}
 
+	var scenarios []string
+	if testScenario != "" {
+		scenarios = strings.Split(testScenario, ",")
+		for i, name := range scenarios {
+			scenarios[i] = strings.TrimSpace(name)
+		}
+	}
+
 	return runTestsWithConfig(ctx, cmd, len(modelNames), func(orchestrator *testing.Orchestrator) ([]*testing.TestResult, error) {
-		return orchestrator.TestModels(ctx, testNetwork, modelNames, testConcurrency)
+		return orchestrator.TestModels(ctx, testNetwork, modelNames, scenarios, testConcurrency)
 	})
 }
 
@@ -163,9 +356,313 @@ func runTestAll(cmd *cobra.Command, _ []string) error {
 	})
 }
 
+func runTestChanged(cmd *cobra.Command, _ []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	log := newLogger(testVerbose)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+
+	modelCache := testing.NewModelCache(log)
+	if err := modelCache.LoadAll(
+		ctx,
+		filepath.Join(wd, config.ModelsExternalDir),
+		filepath.Join(wd, config.ModelsTransformationsDir),
+	); err != nil {
+		return fmt.Errorf("loading models: %w", err)
+	}
+
+	detector := testing.NewChangeDetector(
+		log,
+		wd,
+		modelCache,
+		testdef.NewLoader(log, filepath.Join(wd, config.TestsDir)),
+	)
+
+	changes, err := detector.Detect(ctx, testChangedBase, testNetwork)
+	if err != nil {
+		return fmt.Errorf("detecting changed models: %w", err)
+	}
+
+	if changes.All {
+		log.Info("Changes affect all models, running full test suite")
+
+		return runTestsWithConfig(ctx, cmd, 0, func(orchestrator *testing.Orchestrator) ([]*testing.TestResult, error) {
+			return orchestrator.TestAll(ctx, testNetwork, testConcurrency)
+		})
+	}
+
+	if len(changes.Models) == 0 {
+		log.WithField("base", testChangedBase).Info("No tested models affected by changes")
+
+		return nil
+	}
+
+	log.WithField("models", strings.Join(changes.Models, ",")).Info("Testing affected models")
+
+	return runTestsWithConfig(ctx, cmd, len(changes.Models), func(orchestrator *testing.Orchestrator) ([]*testing.TestResult, error) {
+		return orchestrator.TestModels(ctx, testNetwork, changes.Models, nil, testConcurrency)
+	})
+}
+
+func runTestDiff(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	log := newLogger(testVerbose)
+
+	modelNames := strings.Split(args[0], ",")
+	for i, name := range modelNames {
+		modelNames[i] = strings.TrimSpace(name)
+	}
+
+	allow, err := testing.ParseAllowColumns(testAllowColumns)
+	if err != nil {
+		return fmt.Errorf("parsing --allow-columns: %w", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+
+	baseDir, removeWorktree, err := testing.CheckoutRef(ctx, log, wd, testDiffBase)
+	if err != nil {
+		return err
+	}
+	defer removeWorktree()
+
+	baseModels := testing.NewModelCache(log)
+	if err := baseModels.LoadAll(
+		ctx,
+		filepath.Join(baseDir, config.ModelsExternalDir),
+		filepath.Join(baseDir, config.ModelsTransformationsDir),
+	); err != nil {
+		return fmt.Errorf("loading base models: %w", err)
+	}
+
+	base := &testing.DiffBase{
+		Ref:          testDiffBase,
+		ModelCache:   baseModels,
+		ModelsDir:    filepath.Join(baseDir, config.ModelsDir),
+		MigrationDir: filepath.Join(baseDir, config.MigrationsDir),
+	}
+
+	orchestrator, err := setupOrchestrator(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("setting up orchestrator: %w", err)
+	}
+
+	setupCleanupHandler(orchestrator)
+	defer func() {
+		if stopErr := orchestrator.Stop(); stopErr != nil {
+			logrus.WithError(stopErr).Error("error stopping orchestrator")
+		}
+	}()
+
+	if startErr := orchestrator.Start(ctx); startErr != nil {
+		return fmt.Errorf("starting orchestrator: %w", startErr)
+	}
+
+	results, err := orchestrator.DiffModels(ctx, testNetwork, modelNames, base, allow, testConcurrency)
+	if err != nil {
+		return fmt.Errorf("diffing models: %w", err)
+	}
+
+	for _, result := range results {
+		if !result.Identical() {
+			return errDiffsFound
+		}
+	}
+
+	return nil
+}
+
+func runTestNew(_ *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	model := strings.TrimSpace(args[0])
+	log := newLogger(testVerbose)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+
+	testsDir := filepath.Join(wd, config.TestsDir)
+	path := testdef.Path(testsDir, testNetwork, model)
+
+	if _, statErr := os.Stat(path); statErr == nil && !testNewForce {
+		return fmt.Errorf("%w: %s", errTestDefinitionExists, path)
+	}
+
+	modelCache := testing.NewModelCache(log)
+	if err := modelCache.LoadAll(
+		ctx,
+		filepath.Join(wd, config.ModelsExternalDir),
+		filepath.Join(wd, config.ModelsTransformationsDir),
+	); err != nil {
+		return fmt.Errorf("loading models: %w", err)
+	}
+
+	dbManager := testing.NewDatabaseManager(log, testing.DefaultTestConfig(), xatuClickhouseURL, cbtClickhouseURL, "", false)
+	if err := dbManager.Start(ctx); err != nil {
+		return fmt.Errorf("starting database manager: %w", err)
+	}
+
+	defer func() {
+		if stopErr := dbManager.Stop(); stopErr != nil {
+			log.WithError(stopErr).Warn("failed to stop database manager")
+		}
+	}()
+
+	definition, err := testing.NewScaffolder(log, modelCache, dbManager).Scaffold(ctx, model, testNetwork)
+	if err != nil {
+		return fmt.Errorf("scaffolding test definition: %w", err)
+	}
+
+	// Assertions shared between networks come from the common layer; keep only the fixtures.
+	if _, statErr := os.Stat(testdef.Path(testsDir, testdef.CommonLayer, model)); statErr == nil {
+		definition.Extends = testdef.CommonLayer
+		definition.Assertions = nil
+	}
+
+	if err := testdef.Write(path, definition); err != nil {
+		return fmt.Errorf("writing test definition: %w", err)
+	}
+
+	// The scaffold must load as-is; a failure here is a generator bug.
+	if _, err := testdef.NewLoader(log, testsDir).LoadForModel(testNetwork, model); err != nil {
+		return fmt.Errorf("validating generated test definition: %w", err)
+	}
+
+	log.WithFields(logrus.Fields{
+		"path":            path,
+		"external_tables": len(definition.ExternalData),
+		"assertions":      len(definition.Assertions),
+	}).Info("created test definition")
+
+	return nil
+}
+
+func runTestInspect(_ *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	log := newLogger(testVerbose)
+
+	manifest, err := testing.LoadManifest(testManifest)
+	if err != nil {
+		return err
+	}
+
+	retained, err := manifest.Find(testNetwork, strings.TrimSpace(args[0]))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case testInspectConfig:
+		retained.WriteConfig(os.Stdout)
+
+		return nil
+	case testInspectSQL:
+		retained.WriteAssertions(os.Stdout)
+
+		return nil
+	}
+
+	dbManager := testing.NewDatabaseManager(log, testing.DefaultTestConfig(), xatuClickhouseURL, cbtClickhouseURL, "", false)
+	if err := dbManager.Start(ctx); err != nil {
+		return fmt.Errorf("starting database manager: %w", err)
+	}
+
+	defer func() {
+		if stopErr := dbManager.Stop(); stopErr != nil {
+			log.WithError(stopErr).Warn("failed to stop database manager")
+		}
+	}()
+
+	inspector := testing.NewInspector(log, dbManager, os.Stdout)
+
+	if testInspectQuery != "" {
+		return inspector.Query(ctx, retained, testInspectOn, testInspectQuery, testInspectRows)
+	}
+
+	return inspector.Describe(ctx, retained)
+}
+
+func runTestGC(_ *cobra.Command, _ []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	log := newLogger(testVerbose)
+
+	dbManager := testing.NewDatabaseManager(log, testing.DefaultTestConfig(), xatuClickhouseURL, cbtClickhouseURL, "", false)
+	if err := dbManager.Start(ctx); err != nil {
+		return fmt.Errorf("starting database manager: %w", err)
+	}
+
+	defer func() {
+		if stopErr := dbManager.Stop(); stopErr != nil {
+			log.WithError(stopErr).Warn("failed to stop database manager")
+		}
+	}()
+
+	gc := infra.NewDatabaseGC(log, dbManager.EphemeralClusters()...)
+
+	plan, err := gc.Plan(ctx, testGCOlderThan)
+	if err != nil {
+		return fmt.Errorf("finding ephemeral databases: %w", err)
+	}
+
+	if testGCDryRun {
+		for _, db := range plan.Databases {
+			age := "unknown age"
+			if !db.CreatedAt.IsZero() {
+				age = time.Since(db.CreatedAt).Round(time.Second).String() + " old"
+			}
+
+			fmt.Printf("would drop database %s on %s (%s)\n", db.Name, db.Cluster, age)
+		}
+
+		for _, replica := range plan.Replicas {
+			fmt.Printf("would drop Keeper replica %s of %s on %s\n", replica.Replica, replica.Path, replica.Cluster)
+		}
+
+		fmt.Printf("%d databases and %d orphaned Keeper replicas to drop\n", len(plan.Databases), len(plan.Replicas))
+
+		return nil
+	}
+
+	result := gc.Apply(ctx, plan)
+
+	log.WithFields(logrus.Fields{
+		"databases": result.Databases,
+		"replicas":  result.Replicas,
+		"failed":    result.Failed,
+	}).Info("garbage collection complete")
+
+	if result.Failed > 0 {
+		return fmt.Errorf("%w: %d failures", errGCFailed, result.Failed)
+	}
+
+	return nil
+}
+
 func setupOrchestrator(ctx context.Context, _ *cobra.Command) (*testing.Orchestrator, error) {
 	log := newLogger(testVerbose)
 
+	reportTargets, err := report.ParseTargets(testReport)
+	if err != nil {
+		return nil, fmt.Errorf("parsing --report: %w", err)
+	}
+
 	wd, err := os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("getting working directory: %w", err)
@@ -180,12 +677,19 @@ func setupOrchestrator(ctx context.Context, _ *cobra.Command) (*testing.Orchestr
 	metricsCollector := testing.NewCollector(log)
 	testConfig := testing.DefaultTestConfig()
 
-	// Cap concurrency at 15 (Redis DB limit: databases 1-15)
-	if testConcurrency > 15 {
-		testConcurrency = 15
+	testConfig.CBTConcurrency = testConcurrency
+	testConfig.CBTRunner = testCBTRunner
+	testConfig.CBTBinary = testCBTBinary
+	testConfig.UpdateSnapshots = testUpdateSnaps
+
+	// A local CBT binary reaches the cluster of --cbt-clickhouse-url over HTTP.
+	testConfig.CBTClickHouseHTTPURL = cbtClickhouseHTTP
+	if testConfig.CBTClickHouseHTTPURL == "" {
+		if testConfig.CBTClickHouseHTTPURL, err = config.CBTClickHouseHTTPURLFor(cbtClickhouseURL); err != nil {
+			return nil, fmt.Errorf("parsing --cbt-clickhouse-url: %w", err)
+		}
 	}
 
-	testConfig.CBTConcurrency = testConcurrency
 	configLoader := testdef.NewLoader(log, filepath.Join(wd, config.TestsDir))
 
 	// Create and initialize model cache
@@ -223,6 +727,7 @@ func setupOrchestrator(ctx context.Context, _ *cobra.Command) (*testing.Orchestr
 		testConfig.AssertionTimeout,
 		testConfig.AssertionMaxRetries,
 		testConfig.AssertionRetryDelay,
+		testConfig.UpdateSnapshots,
 	)
 	// Xatu cluster assertion runner (for external models)
 	xatuAssertionRunner := assertion.NewRunner(
@@ -232,6 +737,7 @@ func setupOrchestrator(ctx context.Context, _ *cobra.Command) (*testing.Orchestr
 		testConfig.AssertionTimeout,
 		testConfig.AssertionMaxRetries,
 		testConfig.AssertionRetryDelay,
+		testConfig.UpdateSnapshots,
 	)
 
 	// Use simplified config struct for orchestrator initialization
@@ -249,6 +755,13 @@ func setupOrchestrator(ctx context.Context, _ *cobra.Command) (*testing.Orchestr
 		AssertionRunner:  cbtAssertionRunner,
 		XatuAssertion:    xatuAssertionRunner,
 		MigrationDir:     filepath.Join(wd, config.MigrationsDir),
+		Reports:          reportTargets,
+		CheckIdempotency: testIdempotency,
+		CheckChunking:    testChunking,
+		ChunkIntervalMax: testChunkMax,
+		KeepFailed:       testKeepFailed,
+		ManifestPath:     testManifest,
+		ArtifactsDir:     runArtifactsDir(),
 	})
 
 	return orchestrator, nil
@@ -280,3 +793,20 @@ func hasFailures(results []*testing.TestResult) bool {
 func getDefaultCacheDir() string {
 	return ".parquet_cache"
 }
+
+func getDefaultManifestPath() string {
+	return filepath.Join(".test_runs", "manifest.json")
+}
+
+func getDefaultArtifactsDir() string {
+	return ".test_runs"
+}
+
+// runArtifactsDir returns the artifacts directory of this run, below --artifacts-dir.
+func runArtifactsDir() string {
+	if testArtifactsDir == "" {
+		return ""
+	}
+
+	return filepath.Join(testArtifactsDir, "run-"+time.Now().Format("20060102-150405"))
+}
@@ -2,7 +2,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -10,6 +13,8 @@ import (
 	"github.com/joho/godotenv"
 )
 
+var errClickHouseURLWithoutHost = errors.New("clickhouse url has no host")
+
 // AppConfig holds the application deployment configuration loaded from environment variables.
 type AppConfig struct {
 	Network                  string
@@ -137,6 +142,73 @@ func GetXatuClickHouseURL() string {
 	return fmt.Sprintf("clickhouse://%s:%s@%s:%s", username, password, host, port)
 }
 
+// GetCBTClickHouseHTTPURL returns the CBT ClickHouse HTTP URL built from environment variables.
+// It is the host address of the HTTP interface CBT itself connects to.
+func GetCBTClickHouseHTTPURL() string {
+	username := os.Getenv("CLICKHOUSE_USERNAME")
+	if username == "" {
+		username = "default"
+	}
+
+	password := os.Getenv("CLICKHOUSE_PASSWORD")
+	if password == "" {
+		password = "supersecret"
+	}
+
+	host := os.Getenv("CLICKHOUSE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := getEnv(ClickHouseCBT01HTTPPortEnvVar, "8123")
+
+	return fmt.Sprintf("http://%s:%s@%s:%s", username, password, host, port)
+}
+
+// CBTClickHouseHTTPURLFor returns the CBT ClickHouse HTTP URL matching a native connection
+// URL such as the --cbt-clickhouse-url flag: its credentials and host, on the CBT HTTP port.
+func CBTClickHouseHTTPURLFor(nativeURL string) (string, error) {
+	parsed, err := url.Parse(nativeURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing clickhouse url: %w", err)
+	}
+
+	if parsed.Hostname() == "" {
+		return "", fmt.Errorf("%w: %q", errClickHouseURLWithoutHost, nativeURL)
+	}
+
+	httpURL := url.URL{
+		Scheme: "http",
+		User:   parsed.User,
+		Host:   net.JoinHostPort(parsed.Hostname(), getEnv(ClickHouseCBT01HTTPPortEnvVar, "8123")),
+	}
+
+	return httpURL.String(), nil
+}
+
+// GetXatuClickHouseHTTPURL returns the Xatu ClickHouse HTTP URL built from environment variables.
+// The HTTP interface is needed for exports in formats the native driver cannot stream (e.g. Parquet).
+func GetXatuClickHouseHTTPURL() string {
+	username := os.Getenv("CLICKHOUSE_USERNAME")
+	if username == "" {
+		username = "default"
+	}
+
+	password := os.Getenv("CLICKHOUSE_PASSWORD")
+	if password == "" {
+		password = "supersecret"
+	}
+
+	host := os.Getenv("CLICKHOUSE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := getEnv(ClickHouseXatu01HTTPPortEnvVar, "8125")
+
+	return fmt.Sprintf("http://%s:%s@%s:%s", username, password, host, port)
+}
+
 // GetRedisURL returns the Redis connection URL built from the compose host port.
 func GetRedisURL() string {
 	return fmt.Sprintf("redis://localhost:%s", getEnv(RedisPortEnvVar, "6380"))
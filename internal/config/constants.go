@@ -24,8 +24,12 @@ const (
 	ClickHouseNativePortEnvVar = "CLICKHOUSE_NATIVE_PORT"
 	// ClickHouseCBT01NativePortEnvVar is the CBT ClickHouse node 01 native host port environment variable.
 	ClickHouseCBT01NativePortEnvVar = "CLICKHOUSE_CBT_01_NATIVE_PORT"
+	// ClickHouseCBT01HTTPPortEnvVar is the CBT ClickHouse node 01 HTTP host port environment variable.
+	ClickHouseCBT01HTTPPortEnvVar = "CLICKHOUSE_CBT_01_HTTP_PORT"
 	// ClickHouseXatu01NativePortEnvVar is the Xatu ClickHouse node 01 native host port environment variable.
 	ClickHouseXatu01NativePortEnvVar = "CLICKHOUSE_XATU_01_NATIVE_PORT"
+	// ClickHouseXatu01HTTPPortEnvVar is the Xatu ClickHouse node 01 HTTP host port environment variable.
+	ClickHouseXatu01HTTPPortEnvVar = "CLICKHOUSE_XATU_01_HTTP_PORT"
 	// RedisPortEnvVar is the Redis host port environment variable.
 	RedisPortEnvVar = "REDIS_PORT"
 	// PlatformComposeFile is the Docker Compose file for the platform infrastructure.
@@ -50,6 +54,8 @@ const (
 	DefaultDatabase = "default"
 	// CBTTemplateDatabase is the name of the CBT template database for cloning.
 	CBTTemplateDatabase = "cbt_template"
+	// CBTBaseTemplateDatabase is the CBT template database built from a base git ref's migrations.
+	CBTBaseTemplateDatabase = "cbt_template_base"
 	// ExternalDBPrefix is the prefix for per-test external databases.
 	ExternalDBPrefix = "ext_"
 	// CBTDBPrefix is the prefix for per-test CBT databases.
@@ -61,6 +67,8 @@ const (
 	// XatuMigrationsPath is the path to migrations within the xatu repository.
 	// Each per-schema set lives in its own subdirectory below this path.
 	XatuMigrationsPath = "deploy/migrations/clickhouse"
+	// FixtureBaseURL is where parquet test fixtures are published, followed by /<network>.
+	FixtureBaseURL = "https://data.ethpandaops.io/xatu-cbt/tests"
 	// DefaultRedisURL is the default redis url.
 	DefaultRedisURL = "redis://localhost:6380"
 )
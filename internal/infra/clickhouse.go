@@ -33,17 +33,26 @@ type ClickHouseManager interface {
 type clickhouseManager struct {
 	dockerManager DockerManager
 	connStr       string
+	xatuConnStr   string
 	log           logrus.FieldLogger
 	validator     Validator
 	validated     bool
 	conn          *sql.DB
+	xatuConn      *sql.DB
 }
 
 // NewClickHouseManager creates a new ClickHouse cluster manager.
-func NewClickHouseManager(log logrus.FieldLogger, dockerManager DockerManager, connStr string, safeHostnames []string) ClickHouseManager {
+// xatuConnStr is optional and only used to clean up external test databases.
+func NewClickHouseManager(
+	log logrus.FieldLogger,
+	dockerManager DockerManager,
+	connStr, xatuConnStr string,
+	safeHostnames []string,
+) ClickHouseManager {
 	return &clickhouseManager{
 		dockerManager: dockerManager,
 		connStr:       connStr,
+		xatuConnStr:   xatuConnStr,
 		log:           log.WithField("component", "clickhouse_manager"),
 		validator:     NewValidator(safeHostnames, log),
 		validated:     false,
@@ -79,6 +88,13 @@ func (m *clickhouseManager) Stop(profiles ...string) error {
 		m.conn = nil
 	}
 
+	if m.xatuConn != nil {
+		if err := m.xatuConn.Close(); err != nil {
+			m.log.WithError(err).Warn("failed to close xatu connection")
+		}
+		m.xatuConn = nil
+	}
+
 	if err := m.dockerManager.Stop(profiles...); err != nil {
 		return fmt.Errorf("stopping docker compose: %w", err)
 	}
@@ -110,9 +126,10 @@ func (m *clickhouseManager) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
-// CleanupEphemeralDatabases drops test databases.
-// If maxAge is 0, all test databases are dropped. Otherwise, only databases
-// older than maxAge are dropped (based on creation time if available).
+// CleanupEphemeralDatabases drops per-test databases and their Keeper replicas.
+// If maxAge is 0, all per-test databases are dropped. Otherwise, only databases
+// older than maxAge are dropped, dated by the timestamp in their name or their table metadata.
+// External test databases are collected too when a Xatu connection string is configured.
 func (m *clickhouseManager) CleanupEphemeralDatabases(ctx context.Context, maxAge time.Duration) error {
 	m.log.WithField("max_age", maxAge).Info("cleaning up ephemeral databases")
 
@@ -121,45 +138,38 @@ func (m *clickhouseManager) CleanupEphemeralDatabases(ctx context.Context, maxAg
 		return fmt.Errorf("getting connection: %w", err)
 	}
 
-	// Query for test databases - only select name since metadata columns vary by ClickHouse version
-	query := `
-		SELECT name
-		FROM system.databases
-		WHERE name LIKE 'test_%' OR name LIKE 'cbt_%'
-	`
-
-	rows, err := conn.QueryContext(ctx, query)
-	if err != nil {
-		return fmt.Errorf("querying databases: %w", err)
+	clusters := []EphemeralCluster{{
+		Name:       config.CBTClusterName,
+		Conn:       conn,
+		Prefixes:   []string{"test_", config.CBTDBPrefix},
+		TemplateDB: config.CBTTemplateDatabase,
+	}}
+
+	if xatuConn, xatuErr := m.getXatuConnection(ctx); xatuErr != nil {
+		m.log.WithError(xatuErr).Warn("skipping external test databases, xatu cluster unavailable")
+	} else if xatuConn != nil {
+		clusters = append(clusters, EphemeralCluster{
+			Name:       config.XatuClusterName,
+			Conn:       xatuConn,
+			Prefixes:   []string{config.ExternalDBPrefix},
+			TemplateDB: config.DefaultDatabase,
+		})
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			m.log.WithError(err).Debug("error closing rows")
-		}
-	}()
-
-	var dropped int
 
-	for rows.Next() {
-		var dbName string
+	gc := NewDatabaseGC(m.log, clusters...)
 
-		if err := rows.Scan(&dbName); err != nil {
-			m.log.WithError(err).Warn("failed to scan database row")
-			continue
-		}
-
-		m.log.WithField("database", dbName).Info("dropping ephemeral test database")
-
-		dropSQL := fmt.Sprintf("DROP DATABASE IF EXISTS `%s` ON CLUSTER %s", dbName, config.CBTClusterName)
-		if _, err := conn.ExecContext(ctx, dropSQL); err != nil {
-			m.log.WithError(err).WithField("database", dbName).Error("failed to drop database")
-			continue
-		}
-
-		dropped++
+	plan, err := gc.Plan(ctx, maxAge)
+	if err != nil {
+		return fmt.Errorf("finding ephemeral databases: %w", err)
 	}
 
-	m.log.WithField("dropped", dropped).Info("cleanup complete")
+	result := gc.Apply(ctx, plan)
+
+	m.log.WithFields(logrus.Fields{
+		"dropped":  result.Databases,
+		"replicas": result.Replicas,
+		"failed":   result.Failed,
+	}).Info("cleanup complete")
 
 	return nil
 }
@@ -218,3 +228,27 @@ func (m *clickhouseManager) getConnection() (*sql.DB, error) {
 	m.conn = conn
 	return m.conn, nil
 }
+
+// getXatuConnection returns or creates a connection to the Xatu cluster, or nil if no
+// Xatu connection string is configured.
+func (m *clickhouseManager) getXatuConnection(ctx context.Context) (*sql.DB, error) {
+	if m.xatuConn != nil || m.xatuConnStr == "" {
+		return m.xatuConn, nil
+	}
+
+	conn, err := sql.Open("clickhouse", m.xatuConnStr)
+	if err != nil {
+		return nil, fmt.Errorf("opening xatu connection: %w", err)
+	}
+
+	validateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := m.validator.Validate(validateCtx, conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("xatu hostname validation failed: %w", err)
+	}
+
+	m.xatuConn = conn
+	return m.xatuConn, nil
+}
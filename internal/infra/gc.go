@@ -0,0 +1,534 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// keeperWalkDepth bounds the walk below an orphaned database node in Keeper. Replica paths put
+// the database either directly above the table (.../{database}/{table}) or up to three
+// levels above it (.../{database}/tables/{table}/{shard}).
+const keeperWalkDepth = 4
+
+// EphemeralCluster is a ClickHouse cluster holding per-test databases.
+type EphemeralCluster struct {
+	Name       string   // Cluster name for ON CLUSTER DDL
+	Conn       *sql.DB  // Connection to any node of the cluster
+	Prefixes   []string // Name prefixes of per-test databases
+	TemplateDB string   // Database per-test databases are cloned from, used to locate Keeper paths
+}
+
+// EphemeralDatabase is a per-test database found on a cluster.
+type EphemeralDatabase struct {
+	Cluster   string
+	Name      string
+	CreatedAt time.Time // Zero when neither the name nor table metadata carry a creation time
+}
+
+// KeeperReplica is a replica of a replicated table registered in Keeper.
+type KeeperReplica struct {
+	Cluster string
+	Path    string // Table path, the ZKPATH of SYSTEM DROP REPLICA
+	Replica string
+}
+
+// GCPlan lists what a garbage collection drops.
+type GCPlan struct {
+	Databases []EphemeralDatabase
+	Replicas  []KeeperReplica // Replicas left in Keeper by databases that no longer exist
+}
+
+// GCResult counts what a garbage collection dropped.
+type GCResult struct {
+	Databases int
+	Replicas  int
+	Failed    int
+}
+
+// DatabaseGC finds and drops per-test databases left behind by test runs, together with
+// their replica paths in Keeper.
+type DatabaseGC struct {
+	log      logrus.FieldLogger
+	clusters []EphemeralCluster
+	now      func() time.Time
+}
+
+// NewDatabaseGC creates a garbage collector for the per-test databases of the given clusters.
+func NewDatabaseGC(log logrus.FieldLogger, clusters ...EphemeralCluster) *DatabaseGC {
+	return &DatabaseGC{
+		log:      log.WithField("component", "database_gc"),
+		clusters: clusters,
+		now:      time.Now,
+	}
+}
+
+// Plan finds the per-test databases older than olderThan, or all of them if olderThan is 0,
+// and the Keeper replicas of per-test databases that were dropped without them.
+func (g *DatabaseGC) Plan(ctx context.Context, olderThan time.Duration) (*GCPlan, error) {
+	plan := &GCPlan{}
+
+	for _, cluster := range g.clusters {
+		live, err := g.listDatabases(ctx, cluster)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, db := range live {
+			if g.expired(db.CreatedAt, olderThan) {
+				plan.Databases = append(plan.Databases, db)
+			}
+		}
+
+		// Keeper may be unreadable (e.g. restricted users); databases are still collected.
+		replicas, err := g.orphanedReplicas(ctx, cluster, live, olderThan)
+		if err != nil {
+			g.log.WithError(err).WithField("cluster", cluster.Name).Warn("failed to scan Keeper for orphaned replicas")
+		}
+
+		plan.Replicas = append(plan.Replicas, replicas...)
+	}
+
+	return plan, nil
+}
+
+// Apply drops the databases and Keeper replicas of a plan. Failures are logged and counted,
+// and the remaining entries are still dropped.
+func (g *DatabaseGC) Apply(ctx context.Context, plan *GCPlan) *GCResult {
+	result := &GCResult{}
+	replicas := append([]KeeperReplica(nil), plan.Replicas...)
+
+	for _, db := range plan.Databases {
+		cluster := g.cluster(db.Cluster)
+		logCtx := g.log.WithFields(logrus.Fields{
+			"cluster":  db.Cluster,
+			"database": db.Name,
+		})
+
+		// Replicas normally unregister when their table is dropped; remember them so any left
+		// behind by a failed drop are removed below.
+		dbReplicas, err := g.databaseReplicas(ctx, cluster, db.Name)
+		if err != nil {
+			logCtx.WithError(err).Warn("failed to list replicas of database")
+		}
+
+		if err := g.dropDatabase(ctx, cluster, db.Name); err != nil {
+			logCtx.WithError(err).Error("failed to drop database")
+			result.Failed++
+
+			continue
+		}
+
+		logCtx.Info("dropped ephemeral database")
+		result.Databases++
+
+		replicas = append(replicas, dbReplicas...)
+	}
+
+	for _, replica := range replicas {
+		logCtx := g.log.WithFields(logrus.Fields{
+			"cluster": replica.Cluster,
+			"path":    replica.Path,
+			"replica": replica.Replica,
+		})
+
+		dropped, err := g.dropReplica(ctx, g.cluster(replica.Cluster), replica)
+		if err != nil {
+			logCtx.WithError(err).Error("failed to drop Keeper replica")
+			result.Failed++
+
+			continue
+		}
+
+		if dropped {
+			logCtx.Info("dropped Keeper replica")
+			result.Replicas++
+		}
+	}
+
+	return result
+}
+
+// expired reports whether a database created at createdAt is older than olderThan.
+// Databases of unknown age only expire when every database is collected.
+func (g *DatabaseGC) expired(createdAt time.Time, olderThan time.Duration) bool {
+	if olderThan <= 0 {
+		return true
+	}
+
+	if createdAt.IsZero() {
+		return false
+	}
+
+	return g.now().Sub(createdAt) >= olderThan
+}
+
+func (g *DatabaseGC) cluster(name string) EphemeralCluster {
+	for _, cluster := range g.clusters {
+		if cluster.Name == name {
+			return cluster
+		}
+	}
+
+	return EphemeralCluster{}
+}
+
+// listDatabases returns the per-test databases of a cluster. Databases whose name carries no
+// creation time are dated by the oldest metadata change of their tables.
+func (g *DatabaseGC) listDatabases(ctx context.Context, cluster EphemeralCluster) ([]EphemeralDatabase, error) {
+	rows, err := cluster.Conn.QueryContext(ctx, "SELECT name FROM system.databases")
+	if err != nil {
+		return nil, fmt.Errorf("listing databases on %s: %w", cluster.Name, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var databases []EphemeralDatabase
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning database on %s: %w", cluster.Name, err)
+		}
+
+		if !IsEphemeralDatabase(name, cluster.Prefixes) {
+			continue
+		}
+
+		createdAt, _ := DatabaseCreatedAt(name)
+		databases = append(databases, EphemeralDatabase{Cluster: cluster.Name, Name: name, CreatedAt: createdAt})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading databases on %s: %w", cluster.Name, err)
+	}
+
+	metadataTimes, err := g.metadataTimes(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range databases {
+		if databases[i].CreatedAt.IsZero() {
+			databases[i].CreatedAt = metadataTimes[databases[i].Name]
+		}
+	}
+
+	return databases, nil
+}
+
+// metadataTimes returns the oldest table metadata change per database.
+func (g *DatabaseGC) metadataTimes(ctx context.Context, cluster EphemeralCluster) (map[string]time.Time, error) {
+	rows, err := cluster.Conn.QueryContext(ctx,
+		"SELECT database, min(metadata_modification_time) FROM system.tables GROUP BY database",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading table metadata on %s: %w", cluster.Name, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	times := make(map[string]time.Time)
+
+	for rows.Next() {
+		var (
+			database string
+			modified time.Time
+		)
+
+		if err := rows.Scan(&database, &modified); err != nil {
+			return nil, fmt.Errorf("scanning table metadata on %s: %w", cluster.Name, err)
+		}
+
+		times[database] = modified
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading table metadata on %s: %w", cluster.Name, err)
+	}
+
+	return times, nil
+}
+
+// databaseReplicas returns the Keeper replicas of a database's tables on every node of the cluster.
+func (g *DatabaseGC) databaseReplicas(ctx context.Context, cluster EphemeralCluster, database string) ([]KeeperReplica, error) {
+	query := fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled identifiers
+		"SELECT DISTINCT zookeeper_path, replica_name FROM clusterAllReplicas('%s', system.replicas) WHERE database = ?",
+		cluster.Name,
+	)
+
+	rows, err := cluster.Conn.QueryContext(ctx, query, database)
+	if err != nil {
+		return nil, fmt.Errorf("listing replicas of %s: %w", database, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var replicas []KeeperReplica
+
+	for rows.Next() {
+		replica := KeeperReplica{Cluster: cluster.Name}
+		if err := rows.Scan(&replica.Path, &replica.Replica); err != nil {
+			return nil, fmt.Errorf("scanning replica of %s: %w", database, err)
+		}
+
+		replicas = append(replicas, replica)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading replicas of %s: %w", database, err)
+	}
+
+	return replicas, nil
+}
+
+// orphanedReplicas finds Keeper replicas under database nodes of per-test databases that no
+// longer exist on the cluster. Database nodes are located next to the template database's node.
+func (g *DatabaseGC) orphanedReplicas(
+	ctx context.Context,
+	cluster EphemeralCluster,
+	live []EphemeralDatabase,
+	olderThan time.Duration,
+) ([]KeeperReplica, error) {
+	if cluster.TemplateDB == "" {
+		return nil, nil
+	}
+
+	templateReplicas, err := g.databaseReplicas(ctx, cluster, cluster.TemplateDB)
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make(map[string]bool, len(live))
+	for _, db := range live {
+		exists[db.Name] = true
+	}
+
+	roots := KeeperDatabaseRoots(templateReplicas, cluster.TemplateDB)
+	orphans := make([]KeeperReplica, 0)
+
+	for _, root := range roots {
+		children, err := g.keeperChildren(ctx, cluster, root)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range children {
+			if exists[name] || !IsEphemeralDatabase(name, cluster.Prefixes) {
+				continue
+			}
+
+			createdAt, _ := DatabaseCreatedAt(name)
+			if !g.expired(createdAt, olderThan) {
+				continue
+			}
+
+			replicas, err := g.walkReplicas(ctx, cluster, root+"/"+name, keeperWalkDepth)
+			if err != nil {
+				return nil, err
+			}
+
+			orphans = append(orphans, replicas...)
+		}
+	}
+
+	return orphans, nil
+}
+
+// walkReplicas returns the replicas of the replicated tables at or below a Keeper node.
+func (g *DatabaseGC) walkReplicas(ctx context.Context, cluster EphemeralCluster, path string, depth int) ([]KeeperReplica, error) {
+	if depth == 0 {
+		return nil, nil
+	}
+
+	children, err := g.keeperChildren(ctx, cluster, path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range children {
+		if child != "replicas" {
+			continue
+		}
+
+		names, err := g.keeperChildren(ctx, cluster, path+"/replicas")
+		if err != nil {
+			return nil, err
+		}
+
+		replicas := make([]KeeperReplica, 0, len(names))
+		for _, name := range names {
+			replicas = append(replicas, KeeperReplica{Cluster: cluster.Name, Path: path, Replica: name})
+		}
+
+		return replicas, nil
+	}
+
+	var replicas []KeeperReplica
+
+	for _, child := range children {
+		found, err := g.walkReplicas(ctx, cluster, path+"/"+child, depth-1)
+		if err != nil {
+			return nil, err
+		}
+
+		replicas = append(replicas, found...)
+	}
+
+	return replicas, nil
+}
+
+// keeperChildren lists the children of a Keeper node. A missing node has no children.
+func (g *DatabaseGC) keeperChildren(ctx context.Context, cluster EphemeralCluster, path string) ([]string, error) {
+	rows, err := cluster.Conn.QueryContext(ctx, "SELECT name FROM system.zookeeper WHERE path = ?", path)
+	if err != nil {
+		if isKeeperNoNode(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("listing Keeper node %s: %w", path, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var children []string
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning Keeper node %s: %w", path, err)
+		}
+
+		children = append(children, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		if isKeeperNoNode(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("reading Keeper node %s: %w", path, err)
+	}
+
+	return children, nil
+}
+
+// dropDatabase drops a database on every node, and the migrations table CBT test databases
+// keep in the default database.
+func (g *DatabaseGC) dropDatabase(ctx context.Context, cluster EphemeralCluster, database string) error {
+	dropSQL := fmt.Sprintf("DROP DATABASE IF EXISTS `%s` ON CLUSTER %s SYNC", database, cluster.Name)
+	if _, err := cluster.Conn.ExecContext(ctx, dropSQL); err != nil {
+		return fmt.Errorf("dropping database: %w", err)
+	}
+
+	migrationTable := config.SchemaMigrationsPrefix + database
+	dropMigrationsSQL := fmt.Sprintf("DROP TABLE IF EXISTS `%s`.`%s`", config.DefaultDatabase, migrationTable)
+
+	if _, err := cluster.Conn.ExecContext(ctx, dropMigrationsSQL); err != nil {
+		g.log.WithError(err).WithField("table", migrationTable).Warn("failed to drop migrations table (non-fatal)")
+	}
+
+	return nil
+}
+
+// dropReplica removes a replica from Keeper, reporting false if it was already gone.
+func (g *DatabaseGC) dropReplica(ctx context.Context, cluster EphemeralCluster, replica KeeperReplica) (bool, error) {
+	replicas, err := g.keeperChildren(ctx, cluster, replica.Path+"/replicas")
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+
+	for _, name := range replicas {
+		if name == replica.Replica {
+			found = true
+
+			break
+		}
+	}
+
+	if !found {
+		return false, nil
+	}
+
+	dropSQL := fmt.Sprintf("SYSTEM DROP REPLICA '%s' FROM ZKPATH '%s'", //nolint:gosec // G201: Safe SQL with Keeper names read from ClickHouse
+		escapeString(replica.Replica), escapeString(replica.Path))
+	if _, err := cluster.Conn.ExecContext(ctx, dropSQL); err != nil {
+		return false, fmt.Errorf("dropping replica: %w", err)
+	}
+
+	return true, nil
+}
+
+// IsEphemeralDatabase reports whether name is a per-test database with one of the given prefixes.
+// Template databases share the cbt_ prefix but are never ephemeral.
+func IsEphemeralDatabase(name string, prefixes []string) bool {
+	if name == config.CBTTemplateDatabase || name == config.CBTBaseTemplateDatabase {
+		return false
+	}
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DatabaseCreatedAt returns the creation time embedded in a per-test database name:
+// <prefix><unix nanos>_<counter> for cloned databases, or test_..._<unix seconds>_<suffix>.
+func DatabaseCreatedAt(name string) (time.Time, bool) {
+	parts := strings.Split(name, "_")
+	if len(parts) < 3 {
+		return time.Time{}, false
+	}
+
+	// The timestamp is the second to last part in both formats.
+	stamp := parts[len(parts)-2]
+
+	value, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil || value <= 0 {
+		return time.Time{}, false
+	}
+
+	switch len(stamp) {
+	case 19:
+		return time.Unix(0, value), true
+	case 10:
+		return time.Unix(value, 0), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// KeeperDatabaseRoots returns the Keeper nodes whose children are database nodes, from the
+// replica paths of the template database: the part of each path before the template's node.
+func KeeperDatabaseRoots(templateReplicas []KeeperReplica, templateDB string) []string {
+	seen := make(map[string]bool)
+	roots := make([]string, 0)
+
+	for _, replica := range templateReplicas {
+		idx := strings.Index(replica.Path+"/", "/"+templateDB+"/")
+		if idx <= 0 || seen[replica.Path[:idx]] {
+			continue
+		}
+
+		seen[replica.Path[:idx]] = true
+		roots = append(roots, replica.Path[:idx])
+	}
+
+	return roots
+}
+
+// isKeeperNoNode reports whether err is ClickHouse failing to read a missing Keeper node.
+func isKeeperNoNode(err error) bool {
+	return strings.Contains(err.Error(), "No node")
+}
+
+func escapeString(value string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(value, `\`, `\\`), `'`, `\'`)
+}
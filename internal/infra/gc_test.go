@@ -0,0 +1,109 @@
+package infra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsEphemeralDatabase(t *testing.T) {
+	t.Parallel()
+
+	prefixes := []string{"cbt_", "test_"}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "cbt_1760000000000000000_1", want: true},
+		{name: "test_mainnet_pectra_1760000000_ab12cd34", want: true},
+		{name: "cbt_template", want: false},
+		{name: "cbt_template_base", want: false},
+		{name: "cbt_", want: false},
+		{name: "ext_1760000000000000000_1", want: false},
+		{name: "default", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, IsEphemeralDatabase(tt.name, prefixes))
+		})
+	}
+}
+
+func TestDatabaseCreatedAt(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		want   time.Time
+		wantOK bool
+	}{
+		{name: "ext_1760000000123456789_42", want: time.Unix(0, 1760000000123456789), wantOK: true},
+		{name: "cbt_1760000000123456789_1", want: time.Unix(0, 1760000000123456789), wantOK: true},
+		{name: "test_mainnet_pectra_1760000000_ab12cd34", want: time.Unix(1760000000, 0), wantOK: true},
+		{name: "cbt_template"},
+		{name: "cbt_custom_name_x"},
+		{name: "ext_12345_1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := DatabaseCreatedAt(tt.name)
+			require.Equal(t, tt.wantOK, ok)
+			require.True(t, tt.want.Equal(got), "got %s, want %s", got, tt.want)
+		})
+	}
+}
+
+func TestKeeperDatabaseRoots(t *testing.T) {
+	t.Parallel()
+
+	replicas := []KeeperReplica{
+		{Path: "/clickhouse/local/cluster_2S_1R/tables/01/cbt_template/fct_block", Replica: "01"},
+		{Path: "/clickhouse/local/cluster_2S_1R/tables/02/cbt_template/fct_block", Replica: "02"},
+		{Path: "/clickhouse/local/cluster_2S_1R/tables/01/cbt_template/fct_block_head", Replica: "01"},
+		{Path: "/clickhouse/cbt_template_other/fct_block", Replica: "01"},
+		{Path: "/cbt_template/fct_block", Replica: "01"},
+	}
+
+	require.Equal(t, []string{
+		"/clickhouse/local/cluster_2S_1R/tables/01",
+		"/clickhouse/local/cluster_2S_1R/tables/02",
+	}, KeeperDatabaseRoots(replicas, "cbt_template"))
+}
+
+func TestDatabaseGCExpired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	gc := NewDatabaseGC(logrus.New())
+	gc.now = func() time.Time { return now }
+
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		olderThan time.Duration
+		want      bool
+	}{
+		{name: "all", createdAt: now, olderThan: 0, want: true},
+		{name: "all unknown age", olderThan: 0, want: true},
+		{name: "old", createdAt: now.Add(-7 * time.Hour), olderThan: 6 * time.Hour, want: true},
+		{name: "young", createdAt: now.Add(-time.Hour), olderThan: 6 * time.Hour, want: false},
+		{name: "unknown age", olderThan: 6 * time.Hour, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, gc.expired(tt.createdAt, tt.olderThan))
+		})
+	}
+}
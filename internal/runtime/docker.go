@@ -0,0 +1,352 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// dockerAPIVersion is the Engine API version requested, supported since Docker 20.10.
+	dockerAPIVersion = "v1.41"
+	// DefaultDockerHost is the Docker daemon address used when DOCKER_HOST is unset.
+	DefaultDockerHost = "unix:///var/run/docker.sock"
+	// dockerErrorBodyLimit bounds how much of an error response is read for its message.
+	dockerErrorBodyLimit = 64 << 10
+)
+
+var (
+	errUnsupportedDockerHost = errors.New("unsupported docker host (must be unix://, tcp://, http:// or https://)")
+	errDockerLogFrame        = errors.New("malformed docker log frame")
+)
+
+// ContainerSpec describes a container to create.
+type ContainerSpec struct {
+	Name    string
+	Image   string
+	Cmd     []string
+	Env     []string // KEY=value
+	Binds   []string // host-path:container-path[:options]
+	Network string
+}
+
+// Docker is the subset of the Docker Engine API the test harness uses.
+type Docker interface {
+	// CreateContainer creates a container, pulling its image if missing, and returns its ID.
+	CreateContainer(ctx context.Context, spec ContainerSpec) (string, error)
+	StartContainer(ctx context.Context, id string) error
+	KillContainer(ctx context.Context, id string) error
+	// RemoveContainer force-removes a container. Removing a missing container is not an error.
+	RemoveContainer(ctx context.Context, id string) error
+	// ContainerLogs writes the stdout and stderr a container has produced so far to w.
+	ContainerLogs(ctx context.Context, id string, w io.Writer) error
+	Close() error
+}
+
+// DockerError is an error response from the Docker daemon.
+type DockerError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *DockerError) Error() string {
+	return fmt.Sprintf("docker: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// IsNotFound reports whether err is a Docker "not found" response, e.g. for a missing
+// container or image.
+func IsNotFound(err error) bool {
+	var dockerErr *DockerError
+	return errors.As(err, &dockerErr) && dockerErr.StatusCode == http.StatusNotFound
+}
+
+// isMissingImage reports whether err is the "not found" response of creating a container
+// from an image the daemon doesn't have. Other missing resources, such as the network,
+// are reported as not found too.
+func isMissingImage(err error) bool {
+	var dockerErr *DockerError
+	return errors.As(err, &dockerErr) && dockerErr.StatusCode == http.StatusNotFound &&
+		strings.Contains(dockerErr.Message, "No such image")
+}
+
+// DockerClient talks to the Docker Engine API over HTTP.
+type DockerClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+var _ Docker = (*DockerClient)(nil)
+
+// NewDockerClient creates a client for the daemon at host, e.g. unix:///var/run/docker.sock
+// or tcp://127.0.0.1:2375. An empty host uses DOCKER_HOST, then DefaultDockerHost.
+func NewDockerClient(host string) (*DockerClient, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+
+	if host == "" {
+		host = DefaultDockerHost
+	}
+
+	parsed, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("parsing docker host: %w", err)
+	}
+
+	switch parsed.Scheme {
+	case "unix":
+		socket := parsed.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+
+		return &DockerClient{client: &http.Client{Transport: transport}, baseURL: "http://docker"}, nil
+	case "tcp", "http":
+		return &DockerClient{client: &http.Client{}, baseURL: "http://" + parsed.Host}, nil
+	case "https":
+		return &DockerClient{client: &http.Client{}, baseURL: "https://" + parsed.Host}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnsupportedDockerHost, host)
+	}
+}
+
+// CreateContainer creates a container from spec and returns its ID. A missing image is
+// pulled first, as docker run does.
+func (c *DockerClient) CreateContainer(ctx context.Context, spec ContainerSpec) (string, error) {
+	id, err := c.createContainer(ctx, spec)
+	if !isMissingImage(err) {
+		return id, err
+	}
+
+	if pullErr := c.pullImage(ctx, spec.Image); pullErr != nil {
+		return "", pullErr
+	}
+
+	return c.createContainer(ctx, spec)
+}
+
+func (c *DockerClient) createContainer(ctx context.Context, spec ContainerSpec) (string, error) {
+	body := map[string]interface{}{
+		"Image": spec.Image,
+		"Cmd":   spec.Cmd,
+		"Env":   spec.Env,
+		"HostConfig": map[string]interface{}{
+			"Binds":       spec.Binds,
+			"NetworkMode": spec.Network,
+		},
+	}
+
+	query := url.Values{}
+	if spec.Name != "" {
+		query.Set("name", spec.Name)
+	}
+
+	resp, err := c.request(ctx, http.MethodPost, "/containers/create", query, body)
+	if err != nil {
+		return "", fmt.Errorf("creating container %s: %w", spec.Name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("decoding created container %s: %w", spec.Name, err)
+	}
+
+	return created.ID, nil
+}
+
+// pullImage pulls image, reading the progress stream to the end to surface pull errors.
+func (c *DockerClient) pullImage(ctx context.Context, image string) error {
+	query := url.Values{}
+	query.Set("fromImage", image)
+
+	// The tag is the part after the last colon, unless that colon belongs to a registry port.
+	if !strings.Contains(image, "@") {
+		if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			query.Set("fromImage", image[:i])
+			query.Set("tag", image[i+1:])
+		} else {
+			query.Set("tag", "latest")
+		}
+	}
+
+	resp, err := c.request(ctx, http.MethodPost, "/images/create", query, nil)
+	if err != nil {
+		return fmt.Errorf("pulling image %s: %w", image, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	decoder := json.NewDecoder(resp.Body)
+
+	for {
+		var progress struct {
+			Error string `json:"error"`
+		}
+
+		if err := decoder.Decode(&progress); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading pull progress of %s: %w", image, err)
+		}
+
+		if progress.Error != "" {
+			return fmt.Errorf("pulling image %s: %w", image, &DockerError{StatusCode: http.StatusOK, Message: progress.Error})
+		}
+	}
+}
+
+// StartContainer starts a created container. Starting a running container is not an error.
+func (c *DockerClient) StartContainer(ctx context.Context, id string) error {
+	resp, err := c.request(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/start", nil, nil)
+	if err != nil {
+		return fmt.Errorf("starting container %s: %w", id, err)
+	}
+
+	return resp.Body.Close()
+}
+
+// KillContainer sends SIGKILL to a running container.
+func (c *DockerClient) KillContainer(ctx context.Context, id string) error {
+	resp, err := c.request(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/kill", nil, nil)
+	if err != nil {
+		return fmt.Errorf("killing container %s: %w", id, err)
+	}
+
+	return resp.Body.Close()
+}
+
+// RemoveContainer force-removes a container and its anonymous volumes.
+func (c *DockerClient) RemoveContainer(ctx context.Context, id string) error {
+	query := url.Values{}
+	query.Set("force", "1")
+	query.Set("v", "1")
+
+	resp, err := c.request(ctx, http.MethodDelete, "/containers/"+url.PathEscape(id), query, nil)
+	if IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("removing container %s: %w", id, err)
+	}
+
+	return resp.Body.Close()
+}
+
+// ContainerLogs writes the stdout and stderr of a container to w, in the order they were
+// produced. Containers are created without a TTY, so the stream is demultiplexed.
+func (c *DockerClient) ContainerLogs(ctx context.Context, id string, w io.Writer) error {
+	query := url.Values{}
+	query.Set("stdout", "1")
+	query.Set("stderr", "1")
+
+	resp, err := c.request(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/logs", query, nil)
+	if err != nil {
+		return fmt.Errorf("reading logs of container %s: %w", id, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := demuxLogs(resp.Body, w); err != nil {
+		return fmt.Errorf("reading logs of container %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// Close releases idle connections to the daemon.
+func (c *DockerClient) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// request sends an API request with an optional JSON body and returns the response of a
+// successful (2xx or 304) status. Other statuses are returned as a *DockerError.
+func (c *DockerClient) request(
+	ctx context.Context,
+	method, path string,
+	query url.Values,
+	body interface{},
+) (*http.Response, error) {
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	target := c.baseURL + "/" + dockerAPIVersion + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling docker daemon: %w", err)
+	}
+
+	if resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, dockerErrorBodyLimit))
+
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+
+	message := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+		message = apiErr.Message
+	}
+
+	return nil, &DockerError{StatusCode: resp.StatusCode, Message: message}
+}
+
+// demuxLogs copies the payloads of a multiplexed log stream to w. Each frame has an 8-byte
+// header: the stream type, three zero bytes and the big-endian payload size.
+func demuxLogs(r io.Reader, w io.Writer) error {
+	header := make([]byte, 8)
+
+	for {
+		if _, err := io.ReadFull(r, header); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%w: %w", errDockerLogFrame, err)
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return fmt.Errorf("%w: %w", errDockerLogFrame, err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func logFrame(stream byte, payload string) []byte {
+	frame := make([]byte, 8, 8+len(payload))
+	frame[0] = stream
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(payload))) //nolint:gosec // G115: Test payloads are small
+
+	return append(frame, payload...)
+}
+
+func TestDockerClient_ContainerLifecycle(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		calls   []string
+		pulled  bool
+		created map[string]interface{}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls = append(calls, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+
+		switch r.URL.Path {
+		case "/v1.41/containers/create":
+			if !pulled {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = io.WriteString(w, `{"message":"No such image: ethpandaops/cbt:debian-latest"}`)
+
+				return
+			}
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, `{"Id":"abc123"}`)
+		case "/v1.41/images/create":
+			pulled = true
+			_, _ = io.WriteString(w, `{"status":"Pulling"}`+"\n"+`{"status":"Done"}`+"\n")
+		case "/v1.41/containers/abc123/start", "/v1.41/containers/abc123/kill":
+			w.WriteHeader(http.StatusNoContent)
+		case "/v1.41/containers/abc123/logs":
+			_, _ = w.Write(logFrame(1, "starting cbt\n"))
+			_, _ = w.Write(logFrame(2, "level=error msg=boom\n"))
+		case "/v1.41/containers/abc123", "/v1.41/containers/gone":
+			if r.URL.Path == "/v1.41/containers/gone" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = io.WriteString(w, `{"message":"No such container: gone"}`)
+
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewDockerClient(server.URL)
+	require.NoError(t, err)
+
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+
+	id, err := client.CreateContainer(ctx, ContainerSpec{
+		Name:    "xatu-cbt-test-1",
+		Image:   "ethpandaops/cbt:debian-latest",
+		Cmd:     []string{"--config", "/config/config.yml"},
+		Env:     []string{"NETWORK=mainnet"},
+		Binds:   []string{"/tmp/config.yml:/config/config.yml"},
+		Network: "xatu_xatu-net",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "abc123", id)
+	require.Equal(t, "ethpandaops/cbt:debian-latest", created["Image"])
+	require.Equal(t, map[string]interface{}{
+		"Binds":       []interface{}{"/tmp/config.yml:/config/config.yml"},
+		"NetworkMode": "xatu_xatu-net",
+	}, created["HostConfig"])
+
+	require.NoError(t, client.StartContainer(ctx, id))
+	require.NoError(t, client.KillContainer(ctx, id))
+
+	var logs bytes.Buffer
+	require.NoError(t, client.ContainerLogs(ctx, id, &logs))
+	require.Equal(t, "starting cbt\nlevel=error msg=boom\n", logs.String())
+
+	require.NoError(t, client.RemoveContainer(ctx, id))
+	require.NoError(t, client.RemoveContainer(ctx, "gone"), "removing a missing container is not an error")
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Equal(t, []string{
+		"POST /v1.41/containers/create?name=xatu-cbt-test-1",
+		"POST /v1.41/images/create?fromImage=ethpandaops%2Fcbt&tag=debian-latest",
+		"POST /v1.41/containers/create?name=xatu-cbt-test-1",
+		"POST /v1.41/containers/abc123/start?",
+		"POST /v1.41/containers/abc123/kill?",
+		"GET /v1.41/containers/abc123/logs?stderr=1&stdout=1",
+		"DELETE /v1.41/containers/abc123?force=1&v=1",
+		"DELETE /v1.41/containers/gone?force=1&v=1",
+	}, calls)
+}
+
+func TestDockerClient_ErrorResponse(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = io.WriteString(w, `{"message":"container abc123 is not running"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewDockerClient(server.URL)
+	require.NoError(t, err)
+
+	err = client.KillContainer(context.Background(), "abc123")
+
+	var dockerErr *DockerError
+	require.ErrorAs(t, err, &dockerErr)
+	require.Equal(t, http.StatusConflict, dockerErr.StatusCode)
+	require.Equal(t, "container abc123 is not running", dockerErr.Message)
+	require.False(t, IsNotFound(err))
+}
+
+func TestDockerClient_CreateContainerMissingNetwork(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls = append(calls, r.Method+" "+r.URL.Path)
+
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"message":"network xatu_xatu-net not found"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewDockerClient(server.URL)
+	require.NoError(t, err)
+
+	_, err = client.CreateContainer(context.Background(), ContainerSpec{
+		Name:    "xatu-cbt-test-1",
+		Image:   "ethpandaops/cbt:debian-latest",
+		Network: "xatu_xatu-net",
+	})
+
+	var dockerErr *DockerError
+	require.ErrorAs(t, err, &dockerErr)
+	require.Equal(t, "network xatu_xatu-net not found", dockerErr.Message)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Equal(t, []string{"POST /v1.41/containers/create"}, calls, "only a missing image is pulled")
+}
+
+func TestNewDockerClient(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		host    string
+		baseURL string
+		wantErr bool
+	}{
+		{host: "unix:///var/run/docker.sock", baseURL: "http://docker"},
+		{host: "tcp://127.0.0.1:2375", baseURL: "http://127.0.0.1:2375"},
+		{host: "https://docker.example:2376", baseURL: "https://docker.example:2376"},
+		{host: "ssh://user@docker.example", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewDockerClient(tt.host)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errUnsupportedDockerHost)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.baseURL, client.baseURL)
+		})
+	}
+}
+
+func TestDemuxLogs_TruncatedFrame(t *testing.T) {
+	t.Parallel()
+
+	frame := logFrame(1, "complete line\n")
+
+	var out bytes.Buffer
+	err := demuxLogs(bytes.NewReader(frame[:len(frame)-3]), &out)
+	require.ErrorIs(t, err, errDockerLogFrame)
+}
@@ -0,0 +1,419 @@
+// Package runtime provides native clients for the services the test harness drives:
+// Redis, which holds CBT task state, and the Docker Engine API, which runs CBT containers.
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRedisPort = "6379"
+	redisDialTimeout = 5 * time.Second
+	// scanCount is the COUNT hint of each SCAN page in UnlinkMatching.
+	scanCount = 1000
+)
+
+var (
+	errUnsupportedRedisScheme = errors.New("unsupported redis url scheme (must be redis)")
+	errRedisProtocol          = errors.New("malformed redis reply")
+)
+
+// Redis is the subset of Redis commands the test harness uses.
+type Redis interface {
+	// Scan returns the next cursor and a page of keys matching pattern, as SCAN does.
+	Scan(ctx context.Context, cursor uint64, pattern string, count int) (uint64, []string, error)
+	// Unlink deletes keys without blocking Redis and returns how many existed.
+	Unlink(ctx context.Context, keys ...string) (int64, error)
+	// SetNX sets key to value with a ttl unless it exists, and reports whether it was set.
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// PExpire resets the ttl of key and reports whether it exists.
+	PExpire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	Close() error
+}
+
+// RedisError is an error reply from Redis.
+type RedisError struct {
+	Message string
+}
+
+func (e *RedisError) Error() string {
+	return "redis: " + e.Message
+}
+
+// UnlinkMatching deletes every key matching pattern with SCAN and UNLINK, so unrelated keys
+// are untouched and large key sets don't block Redis. It returns the number of deleted keys.
+func UnlinkMatching(ctx context.Context, r Redis, pattern string) (int64, error) {
+	var (
+		cursor  uint64
+		deleted int64
+	)
+
+	for {
+		next, keys, err := r.Scan(ctx, cursor, pattern, scanCount)
+		if err != nil {
+			return deleted, fmt.Errorf("scanning %s: %w", pattern, err)
+		}
+
+		if len(keys) > 0 {
+			n, err := r.Unlink(ctx, keys...)
+			if err != nil {
+				return deleted, fmt.Errorf("unlinking %s: %w", pattern, err)
+			}
+
+			deleted += n
+		}
+
+		if next == 0 {
+			return deleted, nil
+		}
+
+		cursor = next
+	}
+}
+
+// RedisClient is a minimal RESP client holding a single connection to one Redis DB.
+// It is safe for concurrent use; commands are serialised over the connection, which is
+// dialled on first use and again after a network error.
+type RedisClient struct {
+	addr     string
+	username string
+	password string
+	db       int
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+var _ Redis = (*RedisClient)(nil)
+
+// NewRedisClient creates a client for the server of a redis://[[user]:password@]host[:port]
+// URL, selecting db. Any DB in the URL path is ignored in favour of db.
+func NewRedisClient(rawURL string, db int) (*RedisClient, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing redis url: %w", err)
+	}
+
+	if parsed.Scheme != "redis" {
+		return nil, fmt.Errorf("%w: %q", errUnsupportedRedisScheme, parsed.Scheme)
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = defaultRedisPort
+	}
+
+	client := &RedisClient{
+		addr: net.JoinHostPort(parsed.Hostname(), port),
+		db:   db,
+	}
+
+	if parsed.User != nil {
+		client.username = parsed.User.Username()
+		client.password, _ = parsed.User.Password()
+	}
+
+	return client, nil
+}
+
+// Scan returns the next cursor and a page of keys matching pattern.
+func (c *RedisClient) Scan(ctx context.Context, cursor uint64, pattern string, count int) (uint64, []string, error) {
+	reply, err := c.do(ctx, "SCAN", strconv.FormatUint(cursor, 10), "MATCH", pattern, "COUNT", strconv.Itoa(count))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	page, ok := reply.([]interface{})
+	if !ok || len(page) != 2 {
+		return 0, nil, fmt.Errorf("%w: SCAN returned %T", errRedisProtocol, reply)
+	}
+
+	rawCursor, ok := page[0].(string)
+	if !ok {
+		return 0, nil, fmt.Errorf("%w: SCAN cursor is %T", errRedisProtocol, page[0])
+	}
+
+	next, err := strconv.ParseUint(rawCursor, 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: SCAN cursor %q", errRedisProtocol, rawCursor)
+	}
+
+	rawKeys, ok := page[1].([]interface{})
+	if !ok {
+		return 0, nil, fmt.Errorf("%w: SCAN keys are %T", errRedisProtocol, page[1])
+	}
+
+	keys := make([]string, 0, len(rawKeys))
+	for _, rawKey := range rawKeys {
+		key, ok := rawKey.(string)
+		if !ok {
+			return 0, nil, fmt.Errorf("%w: SCAN key is %T", errRedisProtocol, rawKey)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return next, keys, nil
+}
+
+// Unlink deletes keys and returns how many of them existed.
+func (c *RedisClient) Unlink(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	reply, err := c.do(ctx, append([]string{"UNLINK"}, keys...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("%w: UNLINK returned %T", errRedisProtocol, reply)
+	}
+
+	return deleted, nil
+}
+
+// SetNX sets key to value with a ttl unless it exists, and reports whether it was set.
+func (c *RedisClient) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	reply, err := c.do(ctx, "SET", key, value, "NX", "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+
+	switch reply {
+	case "OK":
+		return true, nil
+	case nil:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: SET returned %v", errRedisProtocol, reply)
+	}
+}
+
+// PExpire resets the ttl of key and reports whether it exists.
+func (c *RedisClient) PExpire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	reply, err := c.do(ctx, "PEXPIRE", key, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+
+	set, ok := reply.(int64)
+	if !ok {
+		return false, fmt.Errorf("%w: PEXPIRE returned %T", errRedisProtocol, reply)
+	}
+
+	return set == 1, nil
+}
+
+// Close closes the connection. The client dials again if it is used afterwards.
+func (c *RedisClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closeConn()
+}
+
+// do sends a command and reads its reply, dialling first if there is no connection.
+// The connection is dropped after any error other than an error reply.
+func (c *RedisClient) do(ctx context.Context, args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.roundTrip(ctx, args...)
+
+	var redisErr *RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		_ = c.closeConn()
+	}
+
+	return reply, err
+}
+
+// dial connects to Redis, then authenticates and selects the DB if needed.
+func (c *RedisClient) dial(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: redisDialTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("connecting to redis at %s: %w", c.addr, err)
+	}
+
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+
+	if c.password != "" {
+		auth := []string{"AUTH", c.password}
+		if c.username != "" {
+			auth = []string{"AUTH", c.username, c.password}
+		}
+
+		if _, err := c.roundTrip(ctx, auth...); err != nil {
+			_ = c.closeConn()
+			return fmt.Errorf("authenticating to redis: %w", err)
+		}
+	}
+
+	if c.db != 0 {
+		if _, err := c.roundTrip(ctx, "SELECT", strconv.Itoa(c.db)); err != nil {
+			_ = c.closeConn()
+			return fmt.Errorf("selecting redis db %d: %w", c.db, err)
+		}
+	}
+
+	return nil
+}
+
+// roundTrip writes a command and reads its reply, honouring ctx cancellation and deadline.
+func (c *RedisClient) roundTrip(ctx context.Context, args ...string) (interface{}, error) {
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("setting redis deadline: %w", err)
+	}
+
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	if _, err := c.conn.Write(encodeCommand(args)); err != nil {
+		return nil, redisIOError(ctx, "writing command", err)
+	}
+
+	reply, err := readReply(c.reader)
+	if err != nil {
+		var redisErr *RedisError
+		if errors.As(err, &redisErr) {
+			return nil, err
+		}
+
+		return nil, redisIOError(ctx, "reading reply", err)
+	}
+
+	return reply, nil
+}
+
+func (c *RedisClient) closeConn() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+	c.reader = nil
+
+	return err
+}
+
+// redisIOError prefers the context error when a command was interrupted by cancellation.
+func redisIOError(ctx context.Context, op string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%s: %w", op, ctxErr)
+	}
+
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// encodeCommand encodes a command as a RESP array of bulk strings.
+func encodeCommand(args []string) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+
+	return buf
+}
+
+// readReply reads one RESP2 reply: simple and bulk strings as string, integers as int64,
+// arrays as []interface{}, nulls as nil and error replies as *RedisError.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("%w: %q", errRedisProtocol, line)
+	}
+
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, &RedisError{Message: payload}
+	case ':':
+		n, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: integer %q", errRedisProtocol, payload)
+		}
+
+		return n, nil
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bulk length %q", errRedisProtocol, payload)
+		}
+
+		if size < 0 {
+			return nil, nil
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		return string(data[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: array length %q", errRedisProtocol, payload)
+		}
+
+		if size < 0 {
+			return nil, nil
+		}
+
+		items := make([]interface{}, 0, size)
+		for range size {
+			item, err := readReply(r)
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, item)
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errRedisProtocol, line)
+	}
+}
@@ -0,0 +1,214 @@
+package assertion
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+)
+
+var (
+	errCheckListValue   = errors.New("check requires a list value")
+	errCheckRangeValue  = errors.New("between requires a [min, max] value")
+	errCheckOrdered     = errors.New("check requires numeric or timestamp values")
+	errCheckNoTolerance = errors.New("approx requires tolerance or relative_tolerance")
+	errCheckNoLength    = errors.New("length requires an array, map or string column")
+)
+
+// evaluateCheck evaluates a typed check against a column value. expected is the check value
+// with column references resolved. The original operators are handled by evaluateComparison.
+//
+//nolint:gocyclo // switch statement throwing it off.
+func (r *runner) evaluateCheck(check *testdef.TypedCheck, actual, expected interface{}) (bool, error) {
+	switch check.Type {
+	case "is_null":
+		return isNull(actual), nil
+
+	case "not_null":
+		return !isNull(actual), nil
+
+	case "approx":
+		return r.evaluateApprox(check, actual, expected)
+
+	case "between":
+		bounds, ok := expected.([]interface{})
+		if !ok || len(bounds) != 2 {
+			return false, errCheckRangeValue
+		}
+
+		if isNull(actual) {
+			return false, nil
+		}
+
+		aboveMin, err := r.evaluateOrdered("greater_than_or_equal", actual, bounds[0])
+		if err != nil || !aboveMin {
+			return false, err
+		}
+
+		return r.evaluateOrdered("less_than_or_equal", actual, bounds[1])
+
+	case "in", "not_in":
+		values, ok := expected.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%w: %s", errCheckListValue, check.Type)
+		}
+
+		found := false
+
+		for _, value := range values {
+			equal, err := r.evaluateComparison("equals", actual, value)
+			if err != nil {
+				return false, err
+			}
+
+			if equal {
+				found = true
+
+				break
+			}
+		}
+
+		return found == (check.Type == "in"), nil
+
+	case "matches":
+		pattern, err := regexp.Compile(fmt.Sprintf("%v", check.Value))
+		if err != nil {
+			return false, fmt.Errorf("compiling pattern: %w", err)
+		}
+
+		if isNull(actual) {
+			return false, nil
+		}
+
+		return pattern.MatchString(checkString(actual)), nil
+
+	case "length":
+		length, ok := valueLength(actual)
+		if !ok {
+			return false, fmt.Errorf("%w, got %T", errCheckNoLength, actual)
+		}
+
+		want, ok := toFloat64(expected)
+		if !ok {
+			return false, fmt.Errorf("length requires a numeric value, got %T", expected) //nolint:err113 // Dynamic error with type info
+		}
+
+		return float64(length) == want, nil
+
+	default:
+		return r.evaluateComparison(check.Type, actual, expected)
+	}
+}
+
+// evaluateApprox compares numbers within an absolute or relative tolerance. Timestamps are
+// compared within an absolute tolerance in seconds.
+func (r *runner) evaluateApprox(check *testdef.TypedCheck, actual, expected interface{}) (bool, error) {
+	actualTime, actualIsTime := r.parseTimestamp(actual)
+	expectedTime, expectedIsTime := r.parseTimestamp(expected)
+
+	if actualIsTime && expectedIsTime {
+		if check.Tolerance == nil {
+			return false, fmt.Errorf("%w (in seconds) for timestamps", errCheckNoTolerance)
+		}
+
+		delta := actualTime.Sub(expectedTime).Abs()
+
+		return delta <= time.Duration(*check.Tolerance*float64(time.Second)), nil
+	}
+
+	actualFloat, actualIsNumeric := toFloat64(actual)
+	expectedFloat, expectedIsNumeric := toFloat64(expected)
+
+	if !actualIsNumeric || !expectedIsNumeric {
+		return false, fmt.Errorf("approx requires numeric or timestamp values, got actual=%T expected=%T", actual, expected) //nolint:err113 // Dynamic error with type info
+	}
+
+	delta := math.Abs(actualFloat - expectedFloat)
+
+	switch {
+	case check.Tolerance != nil:
+		return delta <= *check.Tolerance, nil
+	case check.RelativeTolerance != nil:
+		return delta <= *check.RelativeTolerance*math.Abs(expectedFloat), nil
+	default:
+		return false, errCheckNoTolerance
+	}
+}
+
+// evaluateOrdered applies an ordering operator, requiring numeric or timestamp values.
+func (r *runner) evaluateOrdered(comparisonType string, actual, expected interface{}) (bool, error) {
+	_, actualIsTime := r.parseTimestamp(actual)
+	_, expectedIsTime := r.parseTimestamp(expected)
+	_, actualIsNumeric := toFloat64(actual)
+	_, expectedIsNumeric := toFloat64(expected)
+
+	if !(actualIsTime && expectedIsTime) && !(actualIsNumeric && expectedIsNumeric) {
+		return false, fmt.Errorf("%w, got actual=%T expected=%T", errCheckOrdered, actual, expected)
+	}
+
+	return r.evaluateComparison(comparisonType, actual, expected)
+}
+
+// describeCheck renders a check for failure messages, including any tolerance.
+func describeCheck(check *testdef.TypedCheck) string {
+	switch {
+	case check.Type == "is_null" || check.Type == "not_null":
+		return fmt.Sprintf("%s %s", check.Column, check.Type)
+	case check.Tolerance != nil:
+		return fmt.Sprintf("%s %s %v ±%v", check.Column, check.Type, check.Value, *check.Tolerance)
+	case check.RelativeTolerance != nil:
+		return fmt.Sprintf("%s %s %v ±%v%%", check.Column, check.Type, check.Value, *check.RelativeTolerance*100)
+	default:
+		return fmt.Sprintf("%s %s %v", check.Column, check.Type, check.Value)
+	}
+}
+
+// isNull reports whether a scanned value is NULL. Nullable columns scan into pointers.
+func isNull(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(val)
+
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// checkString renders a value for pattern matching; timestamps use RFC3339.
+func checkString(val interface{}) string {
+	if rv := reflect.ValueOf(val); rv.Kind() == reflect.Ptr {
+		val = rv.Elem().Interface()
+	}
+
+	if t, ok := val.(time.Time); ok {
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	return fmt.Sprintf("%v", val)
+}
+
+// valueLength returns the number of characters in a string or elements in an array or map.
+func valueLength(val interface{}) (int, bool) {
+	if isNull(val) {
+		return 0, false
+	}
+
+	rv := reflect.ValueOf(val)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() { //nolint:exhaustive // Only sized kinds have a length
+	case reflect.String:
+		return utf8.RuneCountInString(rv.String()), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len(), true
+	default:
+		return 0, false
+	}
+}
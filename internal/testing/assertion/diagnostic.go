@@ -0,0 +1,121 @@
+package assertion
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+)
+
+// maxSampleRows caps the offending rows attached to a failed assertion.
+const maxSampleRows = 10
+
+var (
+	// countQueryPattern matches a query selecting only COUNT(*) (or count()) from a source.
+	countQueryPattern = regexp.MustCompile(`(?is)^\s*SELECT\s+count\(\s*\*?\s*\)(?:\s+AS\s+\w+)?\s+FROM\s+(.+?)[\s;]*$`)
+
+	// unsupportedCountClause matches top-level clauses that change what COUNT(*) counts.
+	unsupportedCountClause = regexp.MustCompile(`(?i)\b(GROUP\s+BY|HAVING|UNION|INTERSECT|EXCEPT|ORDER\s+BY|LIMIT|SETTINGS|FORMAT)\b`)
+
+	// lineComment matches SQL line comments.
+	lineComment = regexp.MustCompile(`--[^\n]*`)
+)
+
+// diagnosticSQL returns the query that samples offending rows for a failed assertion:
+// the assertion's diagnostic_sql, or a SELECT * rewrite of a simple COUNT(*) query.
+func diagnosticSQL(assertion *testdef.Assertion) (string, bool) {
+	if assertion.DiagnosticSQL != "" {
+		return assertion.DiagnosticSQL, true
+	}
+
+	// Result set forms already report the offending rows.
+	if assertion.Snapshot != "" || isResultSetAssertion(assertion) {
+		return "", false
+	}
+
+	return rewriteCountQuery(assertion.SQL, maxSampleRows)
+}
+
+// rewriteCountQuery rewrites "SELECT COUNT(*) AS x FROM <source> [WHERE ...]" into
+// "SELECT * FROM <source> [WHERE ...] LIMIT n". Queries with other select expressions or
+// top-level clauses that change the count (GROUP BY, HAVING, UNION, ...) are not rewritten.
+func rewriteCountQuery(sql string, limit int) (string, bool) {
+	match := countQueryPattern.FindStringSubmatch(strings.TrimSpace(sql))
+	if match == nil {
+		return "", false
+	}
+
+	source := match[1]
+
+	if unsupportedCountClause.MatchString(maskNested(lineComment.ReplaceAllString(source, ""))) {
+		return "", false
+	}
+
+	return fmt.Sprintf("SELECT * FROM %s\nLIMIT %d", source, limit), true
+}
+
+// maskNested blanks out parenthesised expressions and quoted strings so only the
+// top level of a query remains.
+func maskNested(sql string) string {
+	var (
+		builder strings.Builder
+		depth   int
+		quote   rune
+	)
+
+	for _, ch := range sql {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+
+			ch = ' '
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+			ch = ' '
+		case ch == '(':
+			depth++
+		case ch == ')':
+			if depth > 0 {
+				depth--
+			}
+		case depth > 0:
+			ch = ' '
+		}
+
+		builder.WriteRune(ch)
+	}
+
+	return builder.String()
+}
+
+// attachSampleRows runs the diagnostic query of a failed assertion and attaches up to
+// maxSampleRows rows to the result. Diagnostic failures are logged, not reported.
+func (r *runner) attachSampleRows(
+	ctx context.Context,
+	log logrus.FieldLogger,
+	dbName string,
+	assertion *testdef.Assertion,
+	result *Result,
+) {
+	sql, ok := diagnosticSQL(assertion)
+	if !ok {
+		return
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	_, rows, err := r.queryRows(queryCtx, dbName, substituteQuery(sql, dbName), maxSampleRows)
+	if err != nil {
+		log.WithError(err).WithField("assertion", assertion.Name).Warn("diagnostic query failed")
+
+		return
+	}
+
+	result.SampleRows = rows
+}
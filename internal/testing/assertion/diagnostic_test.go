@@ -0,0 +1,71 @@
+package assertion
+
+import (
+	"testing"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteCountQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{
+			name:     "bad rows",
+			sql:      "SELECT COUNT(*) AS bad_rows FROM fct_block FINAL\nWHERE slot < 0 OR empty(block_root)\n",
+			expected: "SELECT * FROM fct_block FINAL\nWHERE slot < 0 OR empty(block_root)\nLIMIT 10",
+		},
+		{
+			name:     "count() without alias",
+			sql:      "select count() from fct_block where block_root = '';",
+			expected: "SELECT * FROM fct_block where block_root = ''\nLIMIT 10",
+		},
+		{
+			name: "subquery with grouping",
+			sql: "SELECT COUNT(*) AS duplicate_keys FROM (\n    SELECT slot\n    FROM fct_block FINAL\n" +
+				"    GROUP BY slot\n    HAVING COUNT(*) > 1\n)\n",
+			expected: "SELECT * FROM (\n    SELECT slot\n    FROM fct_block FINAL\n    GROUP BY slot\n    HAVING COUNT(*) > 1\n)\nLIMIT 10",
+		},
+		{
+			name:     "keyword inside string literal",
+			sql:      "SELECT COUNT(*) AS bad_rows FROM fct_block WHERE status = 'GROUP BY'",
+			expected: "SELECT * FROM fct_block WHERE status = 'GROUP BY'\nLIMIT 10",
+		},
+		{name: "top-level group by", sql: "SELECT COUNT(*) AS c FROM fct_block GROUP BY slot"},
+		{name: "top-level limit", sql: "SELECT COUNT(*) AS c FROM fct_block LIMIT 1"},
+		{name: "union", sql: "SELECT COUNT(*) AS c FROM a UNION ALL SELECT COUNT(*) AS c FROM b"},
+		{name: "other columns", sql: "SELECT COUNT(*) AS c, max(slot) AS s FROM fct_block"},
+		{name: "countIf", sql: "SELECT countIf(slot = 0) AS c FROM fct_block"},
+		{name: "not a count", sql: "SELECT slot FROM fct_block"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sql, ok := rewriteCountQuery(tt.sql, maxSampleRows)
+			require.Equal(t, tt.expected != "", ok)
+			require.Equal(t, tt.expected, sql)
+		})
+	}
+}
+
+func TestDiagnosticSQL(t *testing.T) {
+	t.Parallel()
+
+	sql, ok := diagnosticSQL(&testdef.Assertion{
+		SQL:           "SELECT COUNT(*) AS bad_rows FROM fct_block",
+		DiagnosticSQL: "SELECT slot FROM fct_block WHERE slot < 0 LIMIT 5",
+	})
+	require.True(t, ok)
+	require.Equal(t, "SELECT slot FROM fct_block WHERE slot < 0 LIMIT 5", sql)
+
+	// Result set forms report offending rows themselves.
+	_, ok = diagnosticSQL(&testdef.Assertion{SQL: "SELECT COUNT(*) AS c FROM fct_block", ExpectEmpty: true})
+	require.False(t, ok)
+}
@@ -0,0 +1,279 @@
+package assertion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errRowsMismatch     = errors.New("rows do not match expected_rows")
+	errRowCountMismatch = errors.New("row count mismatch")
+	errExpectedEmpty    = errors.New("expected no rows")
+)
+
+// isResultSetAssertion reports whether an assertion evaluates every row of its result
+// rather than the first one. Empty results are valid for these forms.
+func isResultSetAssertion(assertion *testdef.Assertion) bool {
+	return len(assertion.ExpectedRows) > 0 || assertion.RowCount != nil || assertion.ExpectEmpty
+}
+
+// withRetries calls attempt until it reports done, waiting with exponential backoff
+// between attempts, up to maxRetries retries. The last attempt is flagged as final.
+func (r *runner) withRetries(ctx context.Context, log logrus.FieldLogger, attempt func(final bool) bool) error {
+	currentRetryDelay := r.retryDelay
+
+	for i := 0; i <= r.maxRetries; i++ {
+		if i > 0 {
+			log.WithFields(logrus.Fields{
+				"attempt": i,
+				"max":     r.maxRetries,
+			}).Debug("retrying assertion")
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(currentRetryDelay):
+				// Exponential backoff.
+				currentRetryDelay *= 2
+			}
+		}
+
+		if attempt(i == r.maxRetries) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// executeRowsAssertion evaluates expected_rows, row_count and expect_empty assertions
+// against the full result set, retrying mismatches while transformations settle.
+func (r *runner) executeRowsAssertion(
+	ctx context.Context,
+	log logrus.FieldLogger,
+	dbName string,
+	assertion *testdef.Assertion,
+	result *Result,
+	start time.Time,
+) *Result {
+	var (
+		query  = substituteQuery(assertion.SQL, dbName)
+		logCtx = log.WithField("assertion", assertion.Name)
+	)
+
+	if err := r.withRetries(ctx, logCtx, func(_ bool) bool {
+		queryCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		_, rows, err := r.queryRows(queryCtx, dbName, query, 0)
+		cancel()
+
+		if err != nil {
+			result.Error = fmt.Errorf("executing query: %w", err)
+
+			return true
+		}
+
+		result.Actual = map[string]interface{}{"row_count": len(rows)}
+		result.Error = r.evaluateRows(assertion, rows)
+		result.Passed = result.Error == nil
+
+		return result.Passed
+	}); err != nil {
+		result.Error = err
+	}
+
+	if !result.Passed && result.Actual != nil {
+		r.attachSampleRows(ctx, logCtx, dbName, assertion, result)
+	}
+
+	result.Duration = time.Since(start)
+
+	logCtx.WithFields(logrus.Fields{
+		"passed":   result.Passed,
+		"duration": result.Duration,
+	}).Debug("assertion executed")
+
+	return result
+}
+
+// queryRows executes SQL and returns the column names and rows as maps, reading at most
+// maxRows rows when maxRows is positive.
+func (r *runner) queryRows(
+	ctx context.Context,
+	dbName, sqlQuery string,
+	maxRows int,
+) ([]string, []map[string]interface{}, error) {
+	conn, err := r.conn.Conn(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting connection from pool: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Set database on this specific connection.
+	if _, execErr := conn.ExecContext(ctx, fmt.Sprintf("USE %s", dbName)); execErr != nil {
+		return nil, nil, fmt.Errorf("setting database: %w", execErr)
+	}
+
+	rows, err := conn.QueryContext(ctx, sqlQuery)
+	if err != nil {
+		return nil, nil, fmt.Errorf("executing query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting columns: %w", err)
+	}
+
+	result := make([]map[string]interface{}, 0)
+
+	for (maxRows <= 0 || len(result) < maxRows) && rows.Next() {
+		var (
+			values    = make([]interface{}, len(columns))
+			valuePtrs = make([]interface{}, len(columns))
+		)
+
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, nil, fmt.Errorf("scanning row: %w", err)
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			// Convert []byte to string for comparison.
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+
+		result = append(result, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("reading rows: %w", err)
+	}
+
+	return columns, result, nil
+}
+
+// evaluateRows checks a full result set against an expected_rows, row_count or
+// expect_empty assertion, returning an error describing any mismatch.
+func (r *runner) evaluateRows(assertion *testdef.Assertion, actual []map[string]interface{}) error {
+	switch {
+	case assertion.ExpectEmpty:
+		if len(actual) == 0 {
+			return nil
+		}
+
+		return fmt.Errorf("%w, got %d:\n%s", errExpectedEmpty, len(actual), r.formatRows("extra", actual))
+
+	case assertion.RowCount != nil:
+		if assertion.RowCount.Matches(len(actual)) {
+			return nil
+		}
+
+		return fmt.Errorf("%w: expected %s, got %d", errRowCountMismatch, assertion.RowCount, len(actual))
+
+	default:
+		missing, extra := r.diffRows(assertion.ExpectedRows, actual, assertion.Unordered)
+		if len(missing) == 0 && len(extra) == 0 {
+			return nil
+		}
+
+		var builder strings.Builder
+
+		fmt.Fprintf(&builder, "%d expected rows, %d actual rows", len(assertion.ExpectedRows), len(actual))
+
+		if len(missing) > 0 {
+			builder.WriteString("\n" + r.formatRows("missing", missing))
+		}
+
+		if len(extra) > 0 {
+			builder.WriteString("\n" + r.formatRows("extra", extra))
+		}
+
+		return fmt.Errorf("%w: %s", errRowsMismatch, builder.String())
+	}
+}
+
+// diffRows returns the expected rows not found in actual and the actual rows not
+// expected. Ordered comparison matches rows by position; unordered comparison matches
+// each expected row to any equal, not yet matched, actual row.
+func (r *runner) diffRows(
+	expected, actual []map[string]interface{},
+	unordered bool,
+) (missing, extra []map[string]interface{}) {
+	if !unordered {
+		for i, row := range expected {
+			if i >= len(actual) || !r.compareResults(row, actual[i]) {
+				missing = append(missing, row)
+
+				if i < len(actual) {
+					extra = append(extra, actual[i])
+				}
+			}
+		}
+
+		if len(actual) > len(expected) {
+			extra = append(extra, actual[len(expected):]...)
+		}
+
+		return missing, extra
+	}
+
+	matched := make([]bool, len(actual))
+
+	for _, row := range expected {
+		found := false
+
+		for i, candidate := range actual {
+			if !matched[i] && r.compareResults(row, candidate) {
+				matched[i] = true
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, row)
+		}
+	}
+
+	for i, row := range actual {
+		if !matched[i] {
+			extra = append(extra, row)
+		}
+	}
+
+	return missing, extra
+}
+
+// formatRows renders labelled rows, one per line, capped at maxSnapshotDiffLines.
+func (r *runner) formatRows(label string, rows []map[string]interface{}) string {
+	shown := rows
+	if len(shown) > maxSnapshotDiffLines {
+		shown = shown[:maxSnapshotDiffLines]
+	}
+
+	lines := make([]string, 0, len(shown)+1)
+	for _, row := range shown {
+		lines = append(lines, fmt.Sprintf("%s: %v", label, r.normalizeTimestampsInMap(row)))
+	}
+
+	if len(rows) > len(shown) {
+		lines = append(lines, fmt.Sprintf("... and %d more %s rows", len(rows)-len(shown), label))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,163 @@
+package assertion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestEvaluateRows(t *testing.T) {
+	t.Parallel()
+
+	actual := []map[string]interface{}{
+		{"slot": uint32(1), "proposer": "a"},
+		{"slot": uint32(2), "proposer": "b"},
+	}
+
+	tests := []struct {
+		name      string
+		assertion *testdef.Assertion
+		rows      []map[string]interface{}
+		err       error
+		contains  []string
+	}{
+		{
+			name: "ordered match",
+			assertion: &testdef.Assertion{ExpectedRows: []map[string]interface{}{
+				{"slot": 1, "proposer": "a"},
+				{"slot": 2, "proposer": "b"},
+			}},
+			rows: actual,
+		},
+		{
+			name: "ordered mismatch",
+			assertion: &testdef.Assertion{ExpectedRows: []map[string]interface{}{
+				{"slot": 2, "proposer": "b"},
+				{"slot": 1, "proposer": "a"},
+			}},
+			rows:     actual,
+			err:      errRowsMismatch,
+			contains: []string{"missing: map[proposer:b slot:2]", "extra: map[proposer:a slot:1]"},
+		},
+		{
+			name: "unordered match",
+			assertion: &testdef.Assertion{Unordered: true, ExpectedRows: []map[string]interface{}{
+				{"slot": 2, "proposer": "b"},
+				{"slot": 1, "proposer": "a"},
+			}},
+			rows: actual,
+		},
+		{
+			name: "unordered missing and extra",
+			assertion: &testdef.Assertion{Unordered: true, ExpectedRows: []map[string]interface{}{
+				{"slot": 2, "proposer": "b"},
+				{"slot": 3, "proposer": "c"},
+			}},
+			rows:     actual,
+			err:      errRowsMismatch,
+			contains: []string{"2 expected rows, 2 actual rows", "missing: map[proposer:c slot:3]", "extra: map[proposer:a slot:1]"},
+		},
+		{
+			name: "unordered duplicates are counted",
+			assertion: &testdef.Assertion{Unordered: true, ExpectedRows: []map[string]interface{}{
+				{"slot": 1, "proposer": "a"},
+				{"slot": 1, "proposer": "a"},
+			}},
+			rows:     actual,
+			err:      errRowsMismatch,
+			contains: []string{"missing: map[proposer:a slot:1]"},
+		},
+		{
+			name:      "row count exact",
+			assertion: &testdef.Assertion{RowCount: &testdef.RowCount{Exact: intPtr(2)}},
+			rows:      actual,
+		},
+		{
+			name:      "row count out of range",
+			assertion: &testdef.Assertion{RowCount: &testdef.RowCount{Min: intPtr(3), Max: intPtr(5)}},
+			rows:      actual,
+			err:       errRowCountMismatch,
+			contains:  []string{"expected between 3 and 5, got 2"},
+		},
+		{
+			name:      "row count of empty result",
+			assertion: &testdef.Assertion{RowCount: &testdef.RowCount{Max: intPtr(0)}},
+			rows:      []map[string]interface{}{},
+		},
+		{
+			name:      "expect empty",
+			assertion: &testdef.Assertion{ExpectEmpty: true},
+			rows:      []map[string]interface{}{},
+		},
+		{
+			name:      "expect empty with rows",
+			assertion: &testdef.Assertion{ExpectEmpty: true},
+			rows:      actual,
+			err:       errExpectedEmpty,
+			contains:  []string{"extra: map[proposer:a slot:1]", "extra: map[proposer:b slot:2]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := (&runner{}).evaluateRows(tt.assertion, tt.rows)
+			if tt.err == nil {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, tt.err)
+
+			for _, fragment := range tt.contains {
+				require.Contains(t, err.Error(), fragment)
+			}
+		})
+	}
+}
+
+func TestWithRetries(t *testing.T) {
+	t.Parallel()
+
+	r := &runner{maxRetries: 2, retryDelay: time.Millisecond}
+
+	var finals []bool
+
+	require.NoError(t, r.withRetries(context.Background(), logrus.New(), func(final bool) bool {
+		finals = append(finals, final)
+
+		return false
+	}))
+	require.Equal(t, []bool{false, false, true}, finals, "only the last attempt is final")
+
+	attempts := 0
+
+	require.NoError(t, r.withRetries(context.Background(), logrus.New(), func(bool) bool {
+		attempts++
+
+		return attempts == 2
+	}))
+	require.Equal(t, 2, attempts, "attempts stop once one is done")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts = 0
+
+	require.ErrorIs(t, r.withRetries(ctx, logrus.New(), func(bool) bool {
+		attempts++
+
+		return false
+	}), context.Canceled)
+	require.Equal(t, 1, attempts, "no retry once the context is done")
+}
@@ -61,6 +61,9 @@ type Result struct {
 	Duration time.Duration
 	Expected map[string]interface{}
 	Actual   map[string]interface{}
+
+	// SampleRows holds offending rows from the diagnostic query of a failed assertion.
+	SampleRows []map[string]interface{}
 }
 
 type runner struct {
@@ -71,11 +74,15 @@ type runner struct {
 	retryDelay time.Duration
 	log        logrus.FieldLogger
 
+	// updateSnapshots rewrites snapshot files from query results instead of comparing.
+	updateSnapshots bool
+
 	conn *sql.DB
 }
 
-// NewRunner creates a new assertion runner.
-func NewRunner(log logrus.FieldLogger, connStr string, workers int, timeout time.Duration, maxRetries int, retryDelay time.Duration) Runner {
+// NewRunner creates a new assertion runner. With updateSnapshots set, snapshot assertions
+// write their result sets to the snapshot files and pass.
+func NewRunner(log logrus.FieldLogger, connStr string, workers int, timeout time.Duration, maxRetries int, retryDelay time.Duration, updateSnapshots bool) Runner {
 	if workers <= 0 {
 		workers = defaultWorkers
 	}
@@ -99,6 +106,8 @@ func NewRunner(log logrus.FieldLogger, connStr string, workers int, timeout time
 		maxRetries: maxRetries,
 		retryDelay: retryDelay,
 		log:        log.WithField("component", "assertion_runner"),
+
+		updateSnapshots: updateSnapshots,
 	}
 }
 
@@ -188,7 +197,7 @@ func (r *runner) RunAssertions(ctx context.Context, model, dbName string, assert
 }
 
 // executeAssertion runs a single assertion with retry logic.
-func (r *runner) executeAssertion(ctx context.Context, log logrus.FieldLogger, dbName string, assertion *testdef.Assertion) *Result { //nolint:gocyclo // test assertion runner with retry logic
+func (r *runner) executeAssertion(ctx context.Context, log logrus.FieldLogger, dbName string, assertion *testdef.Assertion) *Result {
 	var (
 		start  = time.Now()
 		result = &Result{
@@ -197,52 +206,24 @@ func (r *runner) executeAssertion(ctx context.Context, log logrus.FieldLogger, d
 		}
 	)
 
-	// Retry logic for data not ready scenarios
-	// Handles race condition where table exists but INSERT hasn't completed
-	currentRetryDelay := r.retryDelay
-	for attempt := 0; attempt <= r.maxRetries; attempt++ {
-		if attempt > 0 {
-			log.WithFields(logrus.Fields{
-				"assertion": assertion.Name,
-				"attempt":   attempt,
-				"max":       r.maxRetries,
-			}).Debug("retrying assertion")
+	if assertion.Snapshot != "" {
+		return r.executeSnapshotAssertion(ctx, log, dbName, assertion, result, start)
+	}
 
-			select {
-			case <-ctx.Done():
-				result.Error = ctx.Err()
-				result.Duration = time.Since(start)
-				return result
-			case <-time.After(currentRetryDelay):
-				// Exponential backoff.
-				currentRetryDelay *= 2
-			}
-		}
+	if isResultSetAssertion(assertion) {
+		return r.executeRowsAssertion(ctx, log, dbName, assertion, result, start)
+	}
 
-		// Substitute variables in SQL:
-		// - {database} -> actual test database name (e.g., ext_xxx or cbt_xxx)
-		// - cluster('{raw}', default.TABLE) -> TABLE (since we USE dbName)
-		// - default. -> empty (since we USE dbName, tables are in current db context)
-		query := strings.ReplaceAll(assertion.SQL, "{database}", dbName)
-
-		// Handle cluster() wrapper - must remove BOTH the prefix AND trailing ) together
-		// Pattern: cluster('{raw}', default.TABLE) -> TABLE
-		const clusterPrefix = "cluster('{raw}', default."
-		for strings.Contains(query, clusterPrefix) {
-			startIdx := strings.Index(query, clusterPrefix)
-			// Find the matching closing ) after the table name
-			afterPrefix := startIdx + len(clusterPrefix)
-			closeIdx := strings.Index(query[afterPrefix:], ")")
-			if closeIdx >= 0 {
-				// Remove the cluster() wrapper entirely (prefix and closing paren)
-				query = query[:startIdx] + query[afterPrefix:afterPrefix+closeIdx] + query[afterPrefix+closeIdx+1:]
-			} else {
-				break // Malformed, stop processing
-			}
-		}
+	var (
+		query    = substituteQuery(assertion.SQL, dbName)
+		logCtx   = log.WithField("assertion", assertion.Name)
+		attempts int
+	)
 
-		query = strings.ReplaceAll(query, "{raw}", dbName) // Fallback for other uses
-		query = strings.ReplaceAll(query, "default.", "")             // Remove default. prefix
+	// Retry logic for data not ready scenarios
+	// Handles race condition where table exists but INSERT hasn't completed
+	if err := r.withRetries(ctx, logCtx, func(final bool) bool {
+		attempts++
 
 		// Execute query with timeout.
 		queryCtx, cancel := context.WithTimeout(ctx, r.timeout)
@@ -251,19 +232,19 @@ func (r *runner) executeAssertion(ctx context.Context, log logrus.FieldLogger, d
 
 		if err != nil {
 			// Only retry on "no rows" errors (data not ready yet).
-			if err.Error() == "no rows returned" && attempt < r.maxRetries {
-				log.WithField("assertion", assertion.Name).Debug("no rows returned, retrying")
+			if errors.Is(err, errNoRowsReturned) && !final {
+				logCtx.Debug("no rows returned, retrying")
 
-				continue
+				return false
 			}
 
 			result.Error = fmt.Errorf("executing query: %w", err)
-			result.Duration = time.Since(start)
 
-			return result
+			return true
 		}
 
 		result.Actual = actual
+		result.Error = nil
 
 		// Evaluate assertion based on format (exact match vs typed checks)
 		if len(assertion.Expected) > 0 {
@@ -278,53 +259,81 @@ func (r *runner) executeAssertion(ctx context.Context, log logrus.FieldLogger, d
 			}
 		}
 
-		// If passed, return immediately.
-		if result.Passed {
-			result.Duration = time.Since(start)
-
-			log.WithFields(logrus.Fields{
-				"assertion": assertion.Name,
-				"passed":    true,
-				"attempts":  attempt + 1,
-				"duration":  result.Duration,
-			}).Debug("assertion executed")
-
-			return result
-		}
-
 		// If failed but not the last attempt, retry.
-		if attempt < r.maxRetries {
-			log.WithFields(logrus.Fields{
-				"assertion": assertion.Name,
-				"expected":  assertion.Expected,
-				"actual":    actual,
+		if !result.Passed && !final {
+			logCtx.WithFields(logrus.Fields{
+				"expected": assertion.Expected,
+				"actual":   actual,
 			}).Debug("assertion failed, will retry")
 
-			continue
+			return false
 		}
 
-		// Last attempt failed - format error message based on assertion type
+		return true
+	}); err != nil {
+		result.Error = err
+		result.Duration = time.Since(start)
+
+		return result
+	}
+
+	// Last attempt failed - format error message based on assertion type
+	if !result.Passed && result.Actual != nil {
 		if result.Error == nil {
 			// Only generate error if not already set by evaluateTypedChecks
 			normalizedExpected := r.normalizeTimestampsInMap(assertion.Expected)
-			normalizedActual := r.normalizeTimestampsInMap(actual)
+			normalizedActual := r.normalizeTimestampsInMap(result.Actual)
 			result.Error = fmt.Errorf("assertion failed: expected %v, got %v", normalizedExpected, normalizedActual) //nolint:err113 // Dynamic error with context needed for debugging
 		}
 
-		break
+		r.attachSampleRows(ctx, logCtx, dbName, assertion, result)
 	}
 
 	result.Duration = time.Since(start)
 
-	log.WithFields(logrus.Fields{
-		"assertion": assertion.Name,
-		"passed":    result.Passed,
-		"duration":  result.Duration,
+	logCtx.WithFields(logrus.Fields{
+		"passed":   result.Passed,
+		"attempts": attempts,
+		"duration": result.Duration,
 	}).Debug("assertion executed")
 
 	return result
 }
 
+// substituteQuery rewrites assertion SQL to run inside the test database:
+// - {database} -> actual test database name (e.g., ext_xxx or cbt_xxx)
+// - cluster('{raw}', default.TABLE) -> TABLE (since we USE dbName)
+// - default. -> empty (since we USE dbName, tables are in current db context)
+func substituteQuery(sqlQuery, dbName string) string {
+	query := strings.ReplaceAll(sqlQuery, "{database}", dbName)
+
+	// Handle cluster() wrapper - must remove BOTH the prefix AND trailing ) together
+	// Pattern: cluster('{raw}', default.TABLE) -> TABLE
+	const clusterPrefix = "cluster('{raw}', default."
+	for strings.Contains(query, clusterPrefix) {
+		startIdx := strings.Index(query, clusterPrefix)
+		// Find the matching closing ) after the table name
+		afterPrefix := startIdx + len(clusterPrefix)
+		closeIdx := strings.Index(query[afterPrefix:], ")")
+		if closeIdx >= 0 {
+			// Remove the cluster() wrapper entirely (prefix and closing paren)
+			query = query[:startIdx] + query[afterPrefix:afterPrefix+closeIdx] + query[afterPrefix+closeIdx+1:]
+		} else {
+			break // Malformed, stop processing
+		}
+	}
+
+	query = strings.ReplaceAll(query, "{raw}", dbName) // Fallback for other uses
+	query = strings.ReplaceAll(query, "default.", "")  // Remove default. prefix
+
+	return query
+}
+
+// RenderQuery returns assertion SQL as it runs inside the test database dbName.
+func RenderQuery(sqlQuery, dbName string) string {
+	return substituteQuery(sqlQuery, dbName)
+}
+
 // queryToMap executes SQL and returns first row as a map.
 func (r *runner) queryToMap(
 	ctx context.Context,
@@ -434,21 +443,21 @@ func (r *runner) evaluateTypedChecks(checks []*testdef.TypedCheck, actual map[st
 		}
 
 		// Resolve column references: if value is a string matching another column name,
-		// use that column's value instead of the literal string.
+		// use that column's value instead of the literal string. Patterns are never resolved.
 		expectedVal := check.Value
-		if colRef, ok := check.Value.(string); ok {
+		if colRef, ok := check.Value.(string); ok && check.Type != "matches" {
 			if resolvedVal, found := actual[colRef]; found {
 				expectedVal = resolvedVal
 			}
 		}
 
-		passed, err := r.evaluateComparison(check.Type, actualVal, expectedVal)
+		passed, err := r.evaluateCheck(check, actualVal, expectedVal)
 		if err != nil {
 			return false, fmt.Errorf("evaluating check for column %s: %w", check.Column, err)
 		}
 
 		if !passed {
-			failedChecks = append(failedChecks, fmt.Sprintf("%s %s %v (actual: %v)", check.Column, check.Type, check.Value, actualVal))
+			failedChecks = append(failedChecks, fmt.Sprintf("%s (actual: %v)", describeCheck(check), actualVal))
 		}
 	}
 
@@ -0,0 +1,94 @@
+package assertion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/stretchr/testify/require"
+)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestEvaluateTypedChecks(t *testing.T) {
+	t.Parallel()
+
+	var nullString *string
+
+	name := "lighthouse"
+
+	actual := map[string]interface{}{
+		"rate":        0.1234,
+		"count":       uint64(100),
+		"expected":    uint64(100),
+		"client":      "lighthouse",
+		"nullable":    nullString,
+		"present":     &name,
+		"validators":  []uint32{1, 2, 3},
+		"slot_time":   time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC),
+		"block_root":  "0xabc123",
+		"empty_array": []string{},
+	}
+
+	tests := []struct {
+		name   string
+		check  *testdef.TypedCheck
+		passed bool
+	}{
+		{name: "equals column reference", check: &testdef.TypedCheck{Type: "equals", Column: "count", Value: "expected"}, passed: true},
+		{name: "gte", check: &testdef.TypedCheck{Type: "gte", Column: "count", Value: 100}, passed: true},
+		{name: "approx absolute", check: &testdef.TypedCheck{Type: "approx", Column: "rate", Value: 0.12, Tolerance: floatPtr(0.01)}, passed: true},
+		{name: "approx absolute outside", check: &testdef.TypedCheck{Type: "approx", Column: "rate", Value: 0.1, Tolerance: floatPtr(0.01)}},
+		{name: "approx relative", check: &testdef.TypedCheck{Type: "approx", Column: "count", Value: 101, RelativeTolerance: floatPtr(0.01)}, passed: true},
+		{name: "approx relative outside", check: &testdef.TypedCheck{Type: "approx", Column: "count", Value: 110, RelativeTolerance: floatPtr(0.05)}},
+		{name: "approx timestamp", check: &testdef.TypedCheck{Type: "approx", Column: "slot_time", Value: "2024-01-01T00:00:00Z", Tolerance: floatPtr(60)}, passed: true},
+		{name: "between", check: &testdef.TypedCheck{Type: "between", Column: "count", Value: []interface{}{50, 100}}, passed: true},
+		{name: "between outside", check: &testdef.TypedCheck{Type: "between", Column: "count", Value: []interface{}{101, 200}}},
+		{name: "between timestamps", check: &testdef.TypedCheck{Type: "between", Column: "slot_time", Value: []interface{}{"2024-01-01 00:00:00", "2024-01-01 00:01:00"}}, passed: true},
+		{name: "in", check: &testdef.TypedCheck{Type: "in", Column: "client", Value: []interface{}{"prysm", "lighthouse"}}, passed: true},
+		{name: "in numeric", check: &testdef.TypedCheck{Type: "in", Column: "count", Value: []interface{}{99, 100}}, passed: true},
+		{name: "not_in", check: &testdef.TypedCheck{Type: "not_in", Column: "client", Value: []interface{}{"prysm", "teku"}}, passed: true},
+		{name: "not_in present", check: &testdef.TypedCheck{Type: "not_in", Column: "client", Value: []interface{}{"lighthouse"}}},
+		{name: "matches", check: &testdef.TypedCheck{Type: "matches", Column: "block_root", Value: "^0x[0-9a-f]+$"}, passed: true},
+		{name: "matches column name pattern", check: &testdef.TypedCheck{Type: "matches", Column: "client", Value: "client"}},
+		{name: "matches timestamp", check: &testdef.TypedCheck{Type: "matches", Column: "slot_time", Value: "^2024-01-01T"}, passed: true},
+		{name: "is_null", check: &testdef.TypedCheck{Type: "is_null", Column: "nullable"}, passed: true},
+		{name: "is_null present", check: &testdef.TypedCheck{Type: "is_null", Column: "present"}},
+		{name: "not_null", check: &testdef.TypedCheck{Type: "not_null", Column: "present"}, passed: true},
+		{name: "length array", check: &testdef.TypedCheck{Type: "length", Column: "validators", Value: 3}, passed: true},
+		{name: "length empty array", check: &testdef.TypedCheck{Type: "length", Column: "empty_array", Value: 0}, passed: true},
+		{name: "length string", check: &testdef.TypedCheck{Type: "length", Column: "client", Value: 10}, passed: true},
+		{name: "length pointer string", check: &testdef.TypedCheck{Type: "length", Column: "present", Value: 10}, passed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			passed, err := (&runner{}).evaluateTypedChecks([]*testdef.TypedCheck{tt.check}, actual)
+			require.Equal(t, tt.passed, passed)
+
+			if tt.passed {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestEvaluateTypedChecks_FailureMessage(t *testing.T) {
+	t.Parallel()
+
+	_, err := (&runner{}).evaluateTypedChecks([]*testdef.TypedCheck{
+		{Type: "approx", Column: "rate", Value: 0.5, RelativeTolerance: floatPtr(0.01)},
+	}, map[string]interface{}{"rate": 0.4})
+	require.EqualError(t, err, "failed checks: [rate approx 0.5 ±1% (actual: 0.4)]")
+
+	_, err = (&runner{}).evaluateTypedChecks([]*testdef.TypedCheck{
+		{Type: "length", Column: "count", Value: 1},
+	}, map[string]interface{}{"count": uint64(1)})
+	require.ErrorIs(t, err, errCheckNoLength)
+}
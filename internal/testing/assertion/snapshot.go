@@ -0,0 +1,527 @@
+package assertion
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// snapshotNull represents NULL in snapshot files, matching ClickHouse's TSV/CSV convention.
+	// JSONL snapshots store it as a JSON null.
+	snapshotNull = `\N`
+
+	// maxSnapshotDiffLines caps the number of differing rows shown in a failure.
+	maxSnapshotDiffLines = 20
+
+	// maxSnapshotDiffCells bounds the LCS table; larger result sets are diffed by position.
+	maxSnapshotDiffCells = 4_000_000
+)
+
+var (
+	errSnapshotMissing       = errors.New("snapshot file does not exist (run with --update-snapshots to create it)")
+	errSnapshotFormat        = errors.New("unsupported snapshot format")
+	errSnapshotMismatch      = errors.New("result set does not match snapshot")
+	errSnapshotColumnsChange = errors.New("snapshot columns changed")
+	errSnapshotRowNotObject  = errors.New("expected a JSON object")
+)
+
+// snapshot is a full, ordered query result set with values rendered as strings.
+type snapshot struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// executeSnapshotAssertion compares the full result set of an assertion's SQL against its
+// snapshot file, or rewrites the file when the runner updates snapshots. Mismatches are
+// retried like other assertions in case transformations are still settling.
+func (r *runner) executeSnapshotAssertion(
+	ctx context.Context,
+	log logrus.FieldLogger,
+	dbName string,
+	assertion *testdef.Assertion,
+	result *Result,
+	start time.Time,
+) *Result {
+	var (
+		query  = substituteQuery(assertion.SQL, dbName)
+		logCtx = log.WithFields(logrus.Fields{
+			"assertion": assertion.Name,
+			"snapshot":  assertion.SnapshotPath,
+		})
+	)
+
+	if err := r.withRetries(ctx, logCtx, func(final bool) bool {
+		queryCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		actual, err := r.queryToSnapshot(queryCtx, dbName, query)
+		cancel()
+
+		if err != nil {
+			result.Error = fmt.Errorf("executing query: %w", err)
+
+			return true
+		}
+
+		if r.updateSnapshots {
+			// An empty result may mean data is not ready yet.
+			if len(actual.Rows) == 0 && !final {
+				return false
+			}
+
+			if result.Error = writeSnapshot(assertion.SnapshotPath, actual); result.Error == nil {
+				result.Passed = true
+
+				logCtx.WithField("rows", len(actual.Rows)).Info("snapshot updated")
+			}
+
+			return true
+		}
+
+		expected, err := readSnapshot(assertion.SnapshotPath)
+		if err != nil {
+			result.Error = err
+
+			return true
+		}
+
+		result.Error = compareSnapshots(expected, actual)
+		result.Passed = result.Error == nil
+
+		return result.Passed
+	}); err != nil {
+		result.Error = err
+	}
+
+	result.Duration = time.Since(start)
+
+	logCtx.WithFields(logrus.Fields{
+		"passed":   result.Passed,
+		"duration": result.Duration,
+	}).Debug("assertion executed")
+
+	return result
+}
+
+// queryToSnapshot executes SQL and returns every row of the result, formatted for a snapshot.
+func (r *runner) queryToSnapshot(ctx context.Context, dbName, sqlQuery string) (*snapshot, error) {
+	columns, rows, err := r.queryRows(ctx, dbName, sqlQuery, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &snapshot{Columns: columns, Rows: make([][]string, 0, len(rows))}
+
+	for _, row := range rows {
+		values := make([]string, len(columns))
+		for i, col := range columns {
+			values[i] = formatSnapshotValue(row[col])
+		}
+
+		result.Rows = append(result.Rows, values)
+	}
+
+	return result, nil
+}
+
+// formatSnapshotValue renders a scanned value deterministically.
+// Timestamps are written in UTC RFC3339, arrays, maps and tuples as JSON.
+func formatSnapshotValue(val interface{}) string {
+	if val == nil {
+		return snapshotNull
+	}
+
+	// Nullable columns scan into pointers.
+	if rv := reflect.ValueOf(val); rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return snapshotNull
+		}
+
+		if _, isBigInt := val.(*big.Int); !isBigInt {
+			return formatSnapshotValue(rv.Elem().Interface())
+		}
+	}
+
+	switch v := val.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case *big.Int:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	switch reflect.ValueOf(val).Kind() { //nolint:exhaustive // Scalars fall through to fmt
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		if encoded, err := json.Marshal(val); err == nil {
+			return string(encoded)
+		}
+	}
+
+	return fmt.Sprintf("%v", val)
+}
+
+// readSnapshot reads a snapshot file. The format is chosen by extension (.csv or .jsonl).
+func readSnapshot(path string) (*snapshot, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Snapshot path from trusted test definition
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", errSnapshotMissing, path)
+		}
+
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return decodeCSVSnapshot(data)
+	case ".jsonl":
+		return decodeJSONLSnapshot(data)
+	default:
+		return nil, fmt.Errorf("%w: %s", errSnapshotFormat, path)
+	}
+}
+
+// writeSnapshot writes a snapshot file, creating its directory if needed.
+func writeSnapshot(path string, snap *snapshot) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		data, err = encodeCSVSnapshot(snap)
+	case ".jsonl":
+		data, err = encodeJSONLSnapshot(snap)
+	default:
+		return fmt.Errorf("%w: %s", errSnapshotFormat, path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:gosec // G301: Snapshot directory with standard permissions
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec // G306: Snapshot files are committed to the repo
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// encodeCSVSnapshot renders a snapshot as CSV with a header row.
+func encodeCSVSnapshot(snap *snapshot) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(snap.Columns); err != nil {
+		return nil, fmt.Errorf("encoding csv header: %w", err)
+	}
+
+	if err := w.WriteAll(snap.Rows); err != nil {
+		return nil, fmt.Errorf("encoding csv rows: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeCSVSnapshot parses a CSV snapshot with a header row.
+func decodeCSVSnapshot(data []byte) (*snapshot, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parsing csv snapshot: %w", err)
+	}
+
+	if len(records) == 0 {
+		return &snapshot{}, nil
+	}
+
+	return &snapshot{Columns: records[0], Rows: records[1:]}, nil
+}
+
+// encodeJSONLSnapshot renders a snapshot as one JSON object per row, keys in column order.
+// An empty result set is written as a single line holding the column names.
+func encodeJSONLSnapshot(snap *snapshot) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if len(snap.Rows) == 0 {
+		encoded, err := json.Marshal(snap.Columns)
+		if err != nil {
+			return nil, fmt.Errorf("encoding jsonl columns: %w", err)
+		}
+
+		buf.Write(encoded)
+		buf.WriteByte('\n')
+
+		return buf.Bytes(), nil
+	}
+
+	for _, row := range snap.Rows {
+		buf.WriteByte('{')
+
+		for i, col := range snap.Columns {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			key, err := json.Marshal(col)
+			if err != nil {
+				return nil, fmt.Errorf("encoding jsonl key: %w", err)
+			}
+
+			value := []byte("null")
+			if row[i] != snapshotNull {
+				if value, err = json.Marshal(row[i]); err != nil {
+					return nil, fmt.Errorf("encoding jsonl value: %w", err)
+				}
+			}
+
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+
+		buf.WriteString("}\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeJSONLSnapshot parses a JSONL snapshot, taking the column order from the first row.
+func decodeJSONLSnapshot(data []byte) (*snapshot, error) {
+	snap := &snapshot{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		// Column-only line written for empty result sets.
+		if strings.HasPrefix(text, "[") {
+			if err := json.Unmarshal([]byte(text), &snap.Columns); err != nil {
+				return nil, fmt.Errorf("parsing jsonl snapshot line %d: %w", line, err)
+			}
+
+			continue
+		}
+
+		keys, values, err := decodeJSONLRow(text)
+		if err != nil {
+			return nil, fmt.Errorf("parsing jsonl snapshot line %d: %w", line, err)
+		}
+
+		if snap.Columns == nil {
+			snap.Columns = keys
+		}
+
+		row := make([]string, len(snap.Columns))
+		for i, col := range snap.Columns {
+			val, ok := values[col]
+			if !ok {
+				return nil, fmt.Errorf("parsing jsonl snapshot line %d: missing column %q", line, col) //nolint:err113 // Include line for debugging
+			}
+
+			row[i] = val
+		}
+
+		snap.Rows = append(snap.Rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading jsonl snapshot: %w", err)
+	}
+
+	return snap, nil
+}
+
+// decodeJSONLRow parses one JSON object, preserving key order. Non-string values are kept
+// in their JSON form so hand-edited numbers and booleans compare as written.
+func decodeJSONLRow(text string) ([]string, map[string]string, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, errSnapshotRowNotObject
+	}
+
+	var (
+		keys   []string
+		values = make(map[string]string)
+	)
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		key, _ := tok.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, err
+		}
+
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, nil, err
+		}
+
+		switch v := value.(type) {
+		case nil:
+			values[key] = snapshotNull
+		case string:
+			values[key] = v
+		default:
+			values[key] = string(raw)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if _, err := dec.Token(); err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, err
+	}
+
+	return keys, values, nil
+}
+
+// compareSnapshots returns nil when actual matches expected row for row, otherwise an
+// error holding a row-level diff.
+func compareSnapshots(expected, actual *snapshot) error {
+	if !reflect.DeepEqual(expected.Columns, actual.Columns) {
+		return fmt.Errorf("%w: expected %v, got %v", errSnapshotColumnsChange, expected.Columns, actual.Columns)
+	}
+
+	diff := diffSnapshotRows(expected.Columns, expected.Rows, actual.Rows)
+	if len(diff) == 0 {
+		return nil
+	}
+
+	shown := diff
+	if len(shown) > maxSnapshotDiffLines {
+		shown = shown[:maxSnapshotDiffLines]
+	}
+
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "%d expected rows, %d actual rows\n", len(expected.Rows), len(actual.Rows))
+
+	for _, line := range shown {
+		builder.WriteString(line)
+		builder.WriteByte('\n')
+	}
+
+	if len(diff) > len(shown) {
+		fmt.Fprintf(&builder, "... and %d more differences\n", len(diff)-len(shown))
+	}
+
+	return fmt.Errorf("%w:\n%s", errSnapshotMismatch, strings.TrimSuffix(builder.String(), "\n"))
+}
+
+// diffSnapshotRows produces "- row N: ..." lines for expected rows missing from the result
+// and "+ row N: ..." lines for unexpected result rows, aligned on the longest common
+// subsequence so a single inserted row does not mark every following row as changed.
+func diffSnapshotRows(columns []string, expected, actual [][]string) []string {
+	var (
+		n     = len(expected)
+		m     = len(actual)
+		lines []string
+	)
+
+	if n*m > maxSnapshotDiffCells {
+		for i := 0; i < n || i < m; i++ {
+			switch {
+			case i >= m:
+				lines = append(lines, formatDiffRow('-', i, columns, expected[i]))
+			case i >= n:
+				lines = append(lines, formatDiffRow('+', i, columns, actual[i]))
+			case !reflect.DeepEqual(expected[i], actual[i]):
+				lines = append(lines,
+					formatDiffRow('-', i, columns, expected[i]),
+					formatDiffRow('+', i, columns, actual[i]))
+			}
+		}
+
+		return lines
+	}
+
+	// lcs[i][j] is the LCS length of expected[i:] and actual[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if reflect.DeepEqual(expected[i], actual[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && reflect.DeepEqual(expected[i], actual[j]):
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, formatDiffRow('-', i, columns, expected[i]))
+			i++
+		default:
+			lines = append(lines, formatDiffRow('+', j, columns, actual[j]))
+			j++
+		}
+	}
+
+	return lines
+}
+
+// formatDiffRow renders a row as "<sign> row <n>: col=value, ..." with 1-based row numbers.
+func formatDiffRow(sign byte, index int, columns, row []string) string {
+	parts := make([]string, len(row))
+	for i, val := range row {
+		name := strconv.Itoa(i)
+		if i < len(columns) {
+			name = columns[i]
+		}
+
+		parts[i] = name + "=" + val
+	}
+
+	return fmt.Sprintf("%c row %d: %s", sign, index+1, strings.Join(parts, ", "))
+}
@@ -0,0 +1,124 @@
+package assertion
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	snap := &snapshot{
+		Columns: []string{"slot", "block_root", "proposer"},
+		Rows: [][]string{
+			{"100", "0xabc", snapshotNull},
+			{"101", "has,comma \"quoted\"", "7"},
+		},
+	}
+
+	for _, name := range []string{"fct_block.csv", "fct_block.jsonl"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "snapshots", name)
+			require.NoError(t, writeSnapshot(path, snap))
+
+			loaded, err := readSnapshot(path)
+			require.NoError(t, err)
+			require.Equal(t, snap, loaded)
+
+			// Empty result sets keep their columns.
+			empty := &snapshot{Columns: snap.Columns}
+			require.NoError(t, writeSnapshot(path, empty))
+
+			loaded, err = readSnapshot(path)
+			require.NoError(t, err)
+			require.Equal(t, snap.Columns, loaded.Columns)
+			require.Empty(t, loaded.Rows)
+		})
+	}
+
+	_, err := readSnapshot(filepath.Join(t.TempDir(), "missing.csv"))
+	require.ErrorIs(t, err, errSnapshotMissing)
+
+	require.ErrorIs(t, writeSnapshot(filepath.Join(t.TempDir(), "out.txt"), snap), errSnapshotFormat)
+}
+
+func TestDecodeJSONLSnapshot_HandEditedValues(t *testing.T) {
+	t.Parallel()
+
+	snap, err := decodeJSONLSnapshot([]byte("{\"slot\":100,\"ok\":true,\"root\":\"0xabc\",\"x\":null}\n\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"slot", "ok", "root", "x"}, snap.Columns)
+	require.Equal(t, [][]string{{"100", "true", "0xabc", snapshotNull}}, snap.Rows)
+}
+
+func TestFormatSnapshotValue(t *testing.T) {
+	t.Parallel()
+
+	str := "value"
+
+	var nilStr *string
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{name: "nil", value: nil, expected: snapshotNull},
+		{name: "nil pointer", value: nilStr, expected: snapshotNull},
+		{name: "pointer", value: &str, expected: "value"},
+		{name: "bytes", value: []byte("abc"), expected: "abc"},
+		{name: "uint", value: uint64(42), expected: "42"},
+		{name: "float", value: 0.1, expected: "0.1"},
+		{name: "time", value: time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("X", 3600)), expected: "2024-01-02T02:04:05Z"},
+		{name: "array", value: []string{"a", "b"}, expected: `["a","b"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.expected, formatSnapshotValue(tt.value))
+		})
+	}
+}
+
+func TestCompareSnapshots(t *testing.T) {
+	t.Parallel()
+
+	expected := &snapshot{
+		Columns: []string{"slot", "value"},
+		Rows:    [][]string{{"1", "a"}, {"2", "b"}, {"3", "c"}, {"4", "d"}},
+	}
+
+	require.NoError(t, compareSnapshots(expected, expected))
+
+	// A changed and an inserted row are reported without flagging the aligned rows.
+	actual := &snapshot{
+		Columns: []string{"slot", "value"},
+		Rows:    [][]string{{"1", "a"}, {"2", "x"}, {"3", "c"}, {"3", "z"}, {"4", "d"}},
+	}
+
+	err := compareSnapshots(expected, actual)
+	require.ErrorIs(t, err, errSnapshotMismatch)
+	require.Equal(t, "result set does not match snapshot:\n"+
+		"4 expected rows, 5 actual rows\n"+
+		"- row 2: slot=2, value=b\n"+
+		"+ row 2: slot=2, value=x\n"+
+		"+ row 4: slot=3, value=z", err.Error())
+
+	err = compareSnapshots(expected, &snapshot{Columns: []string{"slot"}})
+	require.ErrorIs(t, err, errSnapshotColumnsChange)
+}
+
+func TestSubstituteQuery(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t,
+		"SELECT * FROM fct_block FINAL JOIN cbt_1.dim_node USING (slot)",
+		substituteQuery("SELECT * FROM cluster('{raw}', default.fct_block) FINAL JOIN {database}.dim_node USING (slot)", "cbt_1"),
+	)
+}
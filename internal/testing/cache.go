@@ -6,11 +6,14 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,6 +33,9 @@ type ParquetCache struct {
 	mu       sync.RWMutex
 	manifest *cacheManifest
 
+	// inUse holds the keys of entries used by this process; they are never evicted.
+	inUse map[string]bool
+
 	// Concurrent download protection
 	downloading sync.Map // URL → chan struct{}
 }
@@ -44,6 +50,25 @@ type cacheEntry struct {
 	Table      string    `json:"table"`
 }
 
+// CachedFile describes a cached parquet file as recorded in the manifest.
+type CachedFile struct {
+	Key        string
+	Path       string
+	URL        string
+	Table      string
+	SHA256     string
+	Size       int64
+	Downloaded time.Time
+	LastUsed   time.Time
+}
+
+// VerifyResult is the integrity check result for a single cached file.
+type VerifyResult struct {
+	File CachedFile
+	// Err is nil when the file exists and matches its recorded SHA256.
+	Err error
+}
+
 // cacheManifest tracks all cached files.
 type cacheManifest struct {
 	Entries map[string]*cacheEntry `json:"entries"` // Key: SHA256
@@ -51,6 +76,12 @@ type cacheManifest struct {
 
 const (
 	manifestFilename = "manifest.json"
+	tmpFileSuffix    = ".tmp"
+)
+
+var (
+	errCacheFileMissing = errors.New("cached file is missing")
+	errChecksumMismatch = errors.New("sha256 mismatch")
 )
 
 // NewParquetCache creates a new parquet cache manager.
@@ -75,6 +106,7 @@ func NewParquetCache(
 		metrics:  metricsCollector,
 		config:   cfg,
 		manifest: &cacheManifest{Entries: make(map[string]*cacheEntry)},
+		inUse:    make(map[string]bool),
 	}
 }
 
@@ -97,12 +129,14 @@ func (c *ParquetCache) Start(_ context.Context) error {
 	return nil
 }
 
-// Stop saves the manifest and cleans up resources.
+// Stop enforces the cache size limit, saves the manifest and cleans up resources.
 func (c *ParquetCache) Stop() error {
 	c.log.Debug("stopping parquet cache")
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evict(c.maxSizeBytes)
 
 	if err := c.saveManifest(); err != nil {
 		return fmt.Errorf("saving manifest: %w", err)
@@ -112,18 +146,22 @@ func (c *ParquetCache) Stop() error {
 }
 
 // Get retrieves a cached file or downloads it if not present.
+// Cached files are verified against their recorded SHA256 and re-downloaded on mismatch.
 func (c *ParquetCache) Get(ctx context.Context, url, tableName string) (string, error) {
 	startTime := time.Now()
 
 	urlHash := c.hashURL(url)
 
-	c.mu.RLock()
-	_, exists := c.manifest.Entries[urlHash]
-	c.mu.RUnlock()
+	c.mu.Lock()
+	entry, exists := c.manifest.Entries[urlHash]
+	c.inUse[urlHash] = true
+	c.mu.Unlock()
 
 	if exists {
 		filePath := filepath.Join(c.cacheDir, urlHash)
-		if fileInfo, err := os.Stat(filePath); err == nil {
+
+		err := c.verifyFile(filePath, entry.SHA256)
+		if err == nil {
 			if err := c.updateLastUsed(urlHash); err != nil {
 				c.log.WithError(err).Warn("failed to update last used time")
 			}
@@ -136,7 +174,7 @@ func (c *ParquetCache) Get(ctx context.Context, url, tableName string) (string,
 			c.metrics.RecordParquetLoad(ParquetLoadMetric{
 				Table:     tableName,
 				Source:    SourceCache,
-				SizeBytes: fileInfo.Size(),
+				SizeBytes: entry.Size,
 				Duration:  time.Since(startTime),
 				Timestamp: time.Now(),
 			})
@@ -144,9 +182,16 @@ func (c *ParquetCache) Get(ctx context.Context, url, tableName string) (string,
 			return filePath, nil
 		}
 
-		// File missing, remove from manifest
+		if !errors.Is(err, errCacheFileMissing) {
+			c.log.WithError(err).WithFields(logrus.Fields{
+				"url":   url,
+				"table": tableName,
+			}).Warn("cached parquet file failed integrity check, re-downloading")
+		}
+
+		// File missing or corrupt, remove from cache
 		c.mu.Lock()
-		delete(c.manifest.Entries, urlHash)
+		c.removeEntry(urlHash)
 		c.mu.Unlock()
 	}
 
@@ -197,7 +242,7 @@ func (c *ParquetCache) download(ctx context.Context, url, urlHash, tableName str
 		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode) //nolint:err113 // Include status code for debugging
 	}
 
-	tmpPath := filepath.Join(c.cacheDir, urlHash+".tmp")
+	tmpPath := filepath.Join(c.cacheDir, urlHash+tmpFileSuffix)
 	tmpFile, err := os.Create(tmpPath) //nolint:gosec // G304: Path constructed from safe hash
 	if err != nil {
 		return "", fmt.Errorf("creating temp file: %w", err)
@@ -236,6 +281,8 @@ func (c *ParquetCache) download(ctx context.Context, url, urlHash, tableName str
 		Table:      tableName,
 	}
 
+	c.evict(c.maxSizeBytes)
+
 	if err := c.saveManifest(); err != nil {
 		c.log.WithError(err).Warn("failed to save manifest after download")
 	}
@@ -261,6 +308,232 @@ func (c *ParquetCache) download(ctx context.Context, url, urlHash, tableName str
 	return finalPath, nil
 }
 
+// Entries returns the cached files recorded in the manifest, most recently used first.
+func (c *ParquetCache) Entries() []CachedFile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	files := make([]CachedFile, 0, len(c.manifest.Entries))
+	for key, entry := range c.manifest.Entries {
+		files = append(files, c.cachedFile(key, entry))
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].LastUsed.After(files[j].LastUsed)
+	})
+
+	return files
+}
+
+// Prune evicts least recently used entries until the cache fits within maxSizeBytes
+// and removes files in the cache directory that are not tracked by the manifest.
+// Entries used by this process are never evicted. A non-positive size disables eviction.
+func (c *ParquetCache) Prune(maxSizeBytes int64) ([]CachedFile, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	evicted := c.evict(maxSizeBytes)
+
+	if err := c.removeOrphans(); err != nil {
+		return evicted, err
+	}
+
+	if err := c.saveManifest(); err != nil {
+		return evicted, fmt.Errorf("saving manifest: %w", err)
+	}
+
+	return evicted, nil
+}
+
+// Verify checks every cached file against its recorded SHA256. When remove is set,
+// missing and corrupt entries are dropped from the cache.
+func (c *ParquetCache) Verify(remove bool) ([]VerifyResult, error) {
+	files := c.Entries()
+	results := make([]VerifyResult, 0, len(files))
+
+	for _, file := range files {
+		results = append(results, VerifyResult{
+			File: file,
+			Err:  c.verifyFile(file.Path, file.SHA256),
+		})
+	}
+
+	if !remove {
+		return results, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, result := range results {
+		if result.Err != nil {
+			c.removeEntry(result.File.Key)
+		}
+	}
+
+	if err := c.saveManifest(); err != nil {
+		return results, fmt.Errorf("saving manifest: %w", err)
+	}
+
+	return results, nil
+}
+
+// Clear removes every cached file and resets the manifest.
+func (c *ParquetCache) Clear() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := len(c.manifest.Entries)
+
+	for key := range c.manifest.Entries {
+		c.removeEntry(key)
+	}
+
+	if err := c.removeOrphans(); err != nil {
+		return removed, err
+	}
+
+	if err := c.saveManifest(); err != nil {
+		return removed, fmt.Errorf("saving manifest: %w", err)
+	}
+
+	return removed, nil
+}
+
+// evict removes least recently used entries not used by this process until the
+// total cache size is within maxSizeBytes. Caller must hold c.mu.Lock().
+func (c *ParquetCache) evict(maxSizeBytes int64) []CachedFile {
+	if maxSizeBytes <= 0 {
+		return nil
+	}
+
+	var total int64
+
+	candidates := make([]CachedFile, 0, len(c.manifest.Entries))
+
+	for key, entry := range c.manifest.Entries {
+		total += entry.Size
+
+		if !c.inUse[key] {
+			candidates = append(candidates, c.cachedFile(key, entry))
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].LastUsed.Before(candidates[j].LastUsed)
+	})
+
+	evicted := make([]CachedFile, 0)
+
+	for _, candidate := range candidates {
+		if total <= maxSizeBytes {
+			break
+		}
+
+		c.removeEntry(candidate.Key)
+
+		total -= candidate.Size
+		evicted = append(evicted, candidate)
+
+		c.log.WithFields(logrus.Fields{
+			"url":       candidate.URL,
+			"size":      candidate.Size,
+			"last_used": candidate.LastUsed,
+		}).Debug("evicted parquet file from cache")
+	}
+
+	if total > maxSizeBytes {
+		c.log.WithFields(logrus.Fields{
+			"size":     total,
+			"max_size": maxSizeBytes,
+		}).Warn("parquet cache exceeds max size with files used by the current run")
+	}
+
+	return evicted
+}
+
+// removeEntry deletes a cached file and its manifest entry. Caller must hold c.mu.Lock().
+func (c *ParquetCache) removeEntry(key string) {
+	if err := os.Remove(filepath.Join(c.cacheDir, key)); err != nil && !os.IsNotExist(err) {
+		c.log.WithError(err).WithField("key", key).Warn("failed to remove cached file")
+	}
+
+	delete(c.manifest.Entries, key)
+}
+
+// removeOrphans deletes files in the cache directory that are not tracked by the
+// manifest, including partial downloads. Caller must hold c.mu.Lock().
+func (c *ParquetCache) removeOrphans() error {
+	dirEntries, err := os.ReadDir(c.cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return fmt.Errorf("reading cache directory: %w", err)
+	}
+
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || name == manifestFilename {
+			continue
+		}
+
+		if _, tracked := c.manifest.Entries[name]; tracked {
+			continue
+		}
+
+		// In-flight downloads of this process are not orphans.
+		if strings.HasSuffix(name, tmpFileSuffix) && c.inUse[strings.TrimSuffix(name, tmpFileSuffix)] {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(c.cacheDir, name)); err != nil {
+			return fmt.Errorf("removing orphaned cache file %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// verifyFile checks that a cached file exists and matches the expected SHA256.
+func (c *ParquetCache) verifyFile(path, expected string) error {
+	file, err := os.Open(path) //nolint:gosec // G304: Path constructed from safe hash
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errCacheFileMissing
+		}
+
+		return fmt.Errorf("opening cached file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return fmt.Errorf("hashing cached file: %w", err)
+	}
+
+	if actual := hex.EncodeToString(hasher.Sum(nil)); actual != expected {
+		return fmt.Errorf("%w: expected %s, got %s", errChecksumMismatch, expected, actual)
+	}
+
+	return nil
+}
+
+// cachedFile converts a manifest entry into its exported representation.
+func (c *ParquetCache) cachedFile(key string, entry *cacheEntry) CachedFile {
+	return CachedFile{
+		Key:        key,
+		Path:       filepath.Join(c.cacheDir, key),
+		URL:        entry.URL,
+		Table:      entry.Table,
+		SHA256:     entry.SHA256,
+		Size:       entry.Size,
+		Downloaded: entry.Downloaded,
+		LastUsed:   entry.LastUsed,
+	}
+}
+
 // updateLastUsed updates the last used timestamp for a cache entry
 func (c *ParquetCache) updateLastUsed(urlHash string) error {
 	c.mu.Lock()
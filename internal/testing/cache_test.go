@@ -0,0 +1,110 @@
+package testing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestParquetCache(t *testing.T, maxSizeBytes int64) *ParquetCache {
+	t.Helper()
+
+	log := logrus.New()
+	cache := NewParquetCache(log, nil, t.TempDir(), maxSizeBytes, NewCollector(log))
+	require.NoError(t, cache.Start(context.Background()))
+
+	return cache
+}
+
+func newParquetServer(t *testing.T, downloads *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		_, _ = w.Write([]byte(strings.Repeat("x", 100) + r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestParquetCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	var downloads atomic.Int32
+
+	server := newParquetServer(t, &downloads)
+	ctx := context.Background()
+
+	// Simulate files left by a previous run: loaded without marking them in use.
+	previous := newTestParquetCache(t, 0)
+
+	_, err := previous.Get(ctx, server.URL+"/old", "old")
+	require.NoError(t, err)
+	_, err = previous.Get(ctx, server.URL+"/older", "older")
+	require.NoError(t, err)
+	require.NoError(t, previous.Stop())
+
+	// New run with room for two files only.
+	cache := NewParquetCache(logrus.New(), nil, previous.cacheDir, 220, NewCollector(logrus.New()))
+	require.NoError(t, cache.Start(ctx))
+
+	cache.manifest.Entries[cache.hashURL(server.URL+"/older")].LastUsed =
+		cache.manifest.Entries[cache.hashURL(server.URL+"/old")].LastUsed.Add(-1)
+
+	_, err = cache.Get(ctx, server.URL+"/new", "new")
+	require.NoError(t, err)
+
+	tables := make([]string, 0)
+	for _, file := range cache.Entries() {
+		tables = append(tables, file.Table)
+	}
+
+	require.ElementsMatch(t, []string{"new", "old"}, tables)
+
+	// Files used by the current run are never evicted, even over the limit.
+	evicted, err := cache.Prune(1)
+	require.NoError(t, err)
+	require.Len(t, evicted, 1)
+	require.Equal(t, "old", evicted[0].Table)
+	require.Len(t, cache.Entries(), 1)
+}
+
+func TestParquetCache_RedownloadsOnChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	var downloads atomic.Int32
+
+	server := newParquetServer(t, &downloads)
+	cache := newTestParquetCache(t, 0)
+	ctx := context.Background()
+
+	path, err := cache.Get(ctx, server.URL+"/file", "file")
+	require.NoError(t, err)
+
+	_, err = cache.Get(ctx, server.URL+"/file", "file")
+	require.NoError(t, err)
+	require.Equal(t, int32(1), downloads.Load())
+
+	require.NoError(t, os.WriteFile(path, []byte("corrupt"), 0o600))
+
+	results, err := cache.Verify(false)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.ErrorIs(t, results[0].Err, errChecksumMismatch)
+
+	_, err = cache.Get(ctx, server.URL+"/file", "file")
+	require.NoError(t, err)
+	require.Equal(t, int32(2), downloads.Load())
+
+	results, err = cache.Verify(false)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+}
@@ -0,0 +1,246 @@
+package testing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/runtime"
+)
+
+var (
+	errUnknownCBTRunner  = errors.New("unknown cbt runner (must be docker or binary)")
+	errCBTBinaryRequired = errors.New("the binary cbt runner requires a cbt binary path")
+)
+
+// cbtProcess is a CBT instance started for one run, as a container or a local subprocess.
+type cbtProcess interface {
+	// name identifies the instance in logs.
+	name() string
+	// logs returns the stdout and stderr the instance has produced so far.
+	logs(ctx context.Context) ([]byte, error)
+	// stop kills the instance and releases its resources.
+	stop(ctx context.Context)
+}
+
+// startCBT starts CBT with the config at configPath, using the configured runner, and
+// tracks it until stopCBT.
+func (e *CBTEngine) startCBT(ctx context.Context, network, externalDB, configPath string) (cbtProcess, error) {
+	absConfigPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute config path: %w", err)
+	}
+
+	// Use UnixNano to avoid name collisions when tests start within the same second
+	name := fmt.Sprintf("xatu-cbt-test-%d", time.Now().UnixNano())
+	env := []string{
+		fmt.Sprintf("NETWORK=%s", network),
+		fmt.Sprintf("EXTERNAL_DATABASE=%s", externalDB),
+	}
+
+	var proc cbtProcess
+
+	if e.config.CBTRunner == CBTRunnerBinary {
+		proc, err = e.startBinaryCBT(name, env, absConfigPath)
+	} else {
+		proc, err = e.startDockerCBT(ctx, name, env, absConfigPath)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	e.runningMu.Lock()
+	e.running = append(e.running, proc)
+	e.runningMu.Unlock()
+
+	return proc, nil
+}
+
+// stopCBT stops a CBT instance and stops tracking it.
+func (e *CBTEngine) stopCBT(proc cbtProcess) {
+	ctx, cancel := context.WithTimeout(context.Background(), containerCleanupTimeout)
+	defer cancel()
+
+	proc.stop(ctx)
+
+	e.runningMu.Lock()
+	defer e.runningMu.Unlock()
+
+	for i, running := range e.running {
+		if running == proc {
+			e.running = append(e.running[:i], e.running[i+1:]...)
+			break
+		}
+	}
+}
+
+// startDockerCBT runs CBT in a container on the platform Docker network, with the config
+// and the models directory mounted.
+func (e *CBTEngine) startDockerCBT(ctx context.Context, name string, env []string, absConfigPath string) (cbtProcess, error) {
+	if e.docker == nil {
+		return nil, errCBTEngineNotStarted
+	}
+
+	absModelsDir, err := filepath.Abs(e.modelsDir)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute models path: %w", err)
+	}
+
+	id, err := e.docker.CreateContainer(ctx, runtime.ContainerSpec{
+		Name:  name,
+		Image: e.config.DockerImage,
+		Cmd:   []string{"--config", "/config/config.yml"},
+		Env:   env,
+		Binds: []string{
+			fmt.Sprintf("%s:/config/config.yml", absConfigPath),
+			fmt.Sprintf("%s:/models", absModelsDir),
+		},
+		Network: e.config.DockerNetwork,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating CBT container: %w", err)
+	}
+
+	proc := &dockerCBT{engine: e, id: id, containerName: name}
+
+	if err := e.docker.StartContainer(ctx, id); err != nil {
+		e.stopCBT(proc)
+
+		return nil, fmt.Errorf("starting CBT container %s: %w", name, err)
+	}
+
+	return proc, nil
+}
+
+// dockerCBT is CBT running in a container.
+type dockerCBT struct {
+	engine        *CBTEngine
+	id            string
+	containerName string
+}
+
+func (c *dockerCBT) name() string {
+	return c.containerName
+}
+
+func (c *dockerCBT) logs(ctx context.Context) ([]byte, error) {
+	var logs bytes.Buffer
+	if err := c.engine.docker.ContainerLogs(ctx, c.id, &logs); err != nil {
+		return nil, err
+	}
+
+	return logs.Bytes(), nil
+}
+
+// stop kills and removes the container. Kill errors are ignored since the container may
+// already have exited.
+func (c *dockerCBT) stop(ctx context.Context) {
+	if err := c.engine.docker.KillContainer(ctx, c.id); err != nil {
+		c.engine.log.WithError(err).WithField("container", c.containerName).Debug("failed to kill container")
+	}
+
+	if err := c.engine.docker.RemoveContainer(ctx, c.id); err != nil {
+		c.engine.log.WithError(err).WithField("container", c.containerName).Warn("failed to remove container")
+	}
+}
+
+// startBinaryCBT runs the local CBT binary as a subprocess. The config points it at the
+// host models directory and the platform's host ports.
+func (e *CBTEngine) startBinaryCBT(name string, env []string, absConfigPath string) (cbtProcess, error) {
+	output := &lockedBuffer{}
+
+	// The process outlives the test context until it is stopped, like a container.
+	cmd := exec.Command(e.config.CBTBinary, "--config", absConfigPath) //nolint:gosec,noctx // G204: CBT binary path from our own flags
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting CBT binary %s: %w", e.config.CBTBinary, err)
+	}
+
+	proc := &binaryCBT{
+		engine:      e,
+		processName: fmt.Sprintf("%s (pid %d)", name, cmd.Process.Pid),
+		cmd:         cmd,
+		output:      output,
+		done:        make(chan struct{}),
+	}
+
+	go func() {
+		proc.exitErr = cmd.Wait()
+		close(proc.done)
+	}()
+
+	return proc, nil
+}
+
+// binaryCBT is CBT running as a local subprocess.
+type binaryCBT struct {
+	engine      *CBTEngine
+	processName string
+	cmd         *exec.Cmd
+	output      *lockedBuffer
+	done        chan struct{}
+	exitErr     error
+	stopOnce    sync.Once
+}
+
+func (c *binaryCBT) name() string {
+	return c.processName
+}
+
+func (c *binaryCBT) logs(_ context.Context) ([]byte, error) {
+	return c.output.Bytes(), nil
+}
+
+// stop kills the process and waits for it to exit.
+func (c *binaryCBT) stop(ctx context.Context) {
+	c.stopOnce.Do(func() {
+		select {
+		case <-c.done:
+			c.engine.log.WithError(c.exitErr).WithField("process", c.processName).Debug("CBT process already exited")
+
+			return
+		default:
+		}
+
+		if err := c.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			c.engine.log.WithError(err).WithField("process", c.processName).Debug("failed to kill CBT process")
+		}
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			c.engine.log.WithField("process", c.processName).Warn("CBT process did not exit after kill")
+		}
+	})
+}
+
+// lockedBuffer is a bytes.Buffer safe for a subprocess writing while logs are read.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+// Bytes returns a copy of the buffered output.
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return bytes.Clone(b.buf.Bytes())
+}
@@ -0,0 +1,434 @@
+// Package testing provides end-to-end test orchestration and execution.
+package testing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// changeKind classifies a changed file by how it affects model tests.
+type changeKind int
+
+const (
+	// changeIgnored files never affect model output (docs, CI config, generated protos).
+	changeIgnored changeKind = iota
+	// changeModel is a transformation or external model definition.
+	changeModel
+	// changeMigration is a xatu-cbt migration; affected models are found by content.
+	changeMigration
+	// changeTestDefinition is a test YAML for the network under test.
+	changeTestDefinition
+	// changeSnapshot is another file in the network's test models directory, such as a
+	// snapshot; it affects only the tests whose assertions reference it.
+	changeSnapshot
+	// changeTemplate is an assertion template; it affects the tests that use it.
+	changeTemplate
+	// changeBaseDefinition is a shared test YAML in the common layer; it affects the
+	// tests that extend it.
+	changeBaseDefinition
+	// changeAll is a change that can affect any test (harness code, test overrides).
+	changeAll
+	// changeUnknown is a file outside the paths the classifier knows, such as scripts or
+	// dotfiles. It is logged and otherwise ignored.
+	changeUnknown
+)
+
+// ChangeSet is the result of mapping a git diff onto model tests.
+type ChangeSet struct {
+	// All is set when a change can affect every test and the full suite should run.
+	All bool
+	// Models is the sorted set of affected models that have a test definition.
+	Models []string
+	// Files is the list of changed files considered.
+	Files []string
+}
+
+// ChangeDetector maps the files changed relative to a git ref onto the set of
+// model tests they affect, following the reverse dependency graph of the model cache.
+type ChangeDetector struct {
+	repoDir      string
+	modelCache   *ModelCache
+	configLoader testdef.Loader
+	log          logrus.FieldLogger
+}
+
+// NewChangeDetector creates a detector for the repository rooted at repoDir.
+func NewChangeDetector(
+	log logrus.FieldLogger,
+	repoDir string,
+	modelCache *ModelCache,
+	configLoader testdef.Loader,
+) *ChangeDetector {
+	return &ChangeDetector{
+		repoDir:      repoDir,
+		modelCache:   modelCache,
+		configLoader: configLoader,
+		log:          log.WithField("component", "change_detector"),
+	}
+}
+
+// Detect diffs the working tree against the merge base of base and HEAD and
+// returns the models with test definitions for the network that are affected.
+func (d *ChangeDetector) Detect(ctx context.Context, base, network string) (*ChangeSet, error) {
+	mergeBase, err := d.git(ctx, "merge-base", base, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("finding merge base with %s: %w", base, err)
+	}
+
+	mergeBase = strings.TrimSpace(mergeBase)
+
+	// Working tree vs merge base covers committed and uncommitted changes;
+	// untracked files are listed separately. Renames are listed as a deletion and an
+	// addition, so models still depending on a renamed model's old name are affected.
+	diffOut, err := d.git(ctx, "diff", "--name-only", "--no-renames", mergeBase)
+	if err != nil {
+		return nil, fmt.Errorf("diffing against %s: %w", base, err)
+	}
+
+	untrackedOut, err := d.git(ctx, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, fmt.Errorf("listing untracked files: %w", err)
+	}
+
+	files := uniqueLines(diffOut + "\n" + untrackedOut)
+	changes := &ChangeSet{Files: files, Models: make([]string, 0)}
+
+	var (
+		seeds     = make(map[string]bool)
+		snapshots = make(map[string]bool)
+		templates = make(map[string]bool)
+		bases     = make(map[string]bool)
+	)
+
+	for _, file := range files {
+		kind, name := classifyChangedPath(file, network)
+
+		switch kind {
+		case changeIgnored:
+			continue
+		case changeUnknown:
+			d.log.WithField("file", file).Debug("ignoring change outside known paths")
+
+			continue
+		case changeAll:
+			d.log.WithField("file", file).Info("change affects all tests")
+
+			changes.All = true
+
+			return changes, nil
+		case changeModel:
+			if modelName, ok := d.modelCache.ModelNameForFile(file); ok {
+				name = modelName
+			}
+
+			seeds[name] = true
+		case changeMigration:
+			for _, model := range d.modelsInMigration(ctx, mergeBase, file) {
+				seeds[model] = true
+			}
+		case changeTestDefinition:
+			seeds[name] = true
+
+			// Fixtures that changed for a table affect every model built on it.
+			for _, table := range d.changedExternalData(ctx, mergeBase, file) {
+				seeds[table] = true
+			}
+		case changeSnapshot:
+			snapshots[filepath.Join(d.repoDir, filepath.FromSlash(file))] = true
+		case changeTemplate:
+			templates[name] = true
+		case changeBaseDefinition:
+			bases[filepath.Join(d.repoDir, filepath.FromSlash(file))] = true
+		}
+	}
+
+	if len(seeds) == 0 && len(snapshots) == 0 && len(templates) == 0 && len(bases) == 0 {
+		return changes, nil
+	}
+
+	affected := make(map[string]bool, len(seeds))
+
+	seedNames := make([]string, 0, len(seeds))
+	for name := range seeds {
+		affected[name] = true
+		seedNames = append(seedNames, name)
+	}
+
+	for _, name := range d.modelCache.Dependents(seedNames...) {
+		affected[name] = true
+	}
+
+	// Only models with a test definition for this network can be run.
+	definitions, err := d.configLoader.LoadAll(network)
+	if err != nil {
+		return nil, fmt.Errorf("loading test definitions: %w", err)
+	}
+
+	for name, definition := range definitions {
+		if affected[name] || referencesSnapshot(definition, snapshots) ||
+			usesTemplate(definition, templates) || extendsFile(definition, bases) {
+			changes.Models = append(changes.Models, name)
+		}
+	}
+
+	sort.Strings(changes.Models)
+
+	d.log.WithFields(logrus.Fields{
+		"files":    len(files),
+		"seeds":    len(seeds),
+		"affected": len(affected),
+		"testable": len(changes.Models),
+	}).Info("detected changed models")
+
+	return changes, nil
+}
+
+// classifyChangedPath classifies a repo-relative path and returns the model name
+// it refers to, if any.
+func classifyChangedPath(file, network string) (changeKind, string) {
+	file = filepath.ToSlash(file)
+	stem := strings.TrimSuffix(path.Base(file), path.Ext(file))
+
+	switch {
+	case strings.HasPrefix(file, "models/external/"), strings.HasPrefix(file, "models/transformations/"):
+		return changeModel, stem
+	case strings.HasPrefix(file, "migrations/"):
+		return changeMigration, ""
+	case strings.HasPrefix(file, "tests/"):
+		parts := strings.Split(file, "/")
+		if len(parts) == 3 && parts[1] == testdef.TemplatesDir && strings.HasSuffix(file, ".yaml") {
+			return changeTemplate, stem
+		}
+
+		if len(parts) >= 4 && parts[1] == network && parts[2] == "models" {
+			if len(parts) == 4 && strings.HasSuffix(file, ".yaml") {
+				return changeTestDefinition, stem
+			}
+
+			return changeSnapshot, ""
+		}
+
+		if len(parts) >= 4 && parts[1] == testdef.CommonLayer && parts[2] == "models" {
+			if len(parts) == 4 && strings.HasSuffix(file, ".yaml") {
+				return changeBaseDefinition, stem
+			}
+
+			return changeSnapshot, ""
+		}
+
+		return changeIgnored, ""
+	case strings.HasPrefix(file, "overrides") && strings.HasSuffix(file, ".yaml"),
+		strings.HasPrefix(file, "internal/"), strings.HasPrefix(file, "cmd/"), file == "go.mod", file == "go.sum":
+		return changeAll, ""
+	case strings.HasPrefix(file, "pkg/proto/"), strings.HasPrefix(file, ".github/"),
+		strings.HasSuffix(file, ".md"), file == "buf.lock", file == "Makefile":
+		return changeIgnored, ""
+	default:
+		return changeUnknown, ""
+	}
+}
+
+// referencesSnapshot reports whether any assertion of a definition, in any scenario,
+// uses one of the snapshot paths.
+func referencesSnapshot(definition *testdef.TestDefinition, snapshots map[string]bool) bool {
+	for _, test := range definition.Tests() {
+		for _, assertion := range test.Assertions {
+			if assertion.SnapshotPath != "" && snapshots[assertion.SnapshotPath] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// usesTemplate reports whether a definition, in any scenario, uses any of the assertion templates.
+func usesTemplate(definition *testdef.TestDefinition, templates map[string]bool) bool {
+	for _, test := range definition.Tests() {
+		for _, use := range test.Uses {
+			if templates[use.Template] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// extendsFile reports whether a definition was merged from any of the base definition files.
+func extendsFile(definition *testdef.TestDefinition, bases map[string]bool) bool {
+	for _, source := range definition.Sources {
+		if bases[source] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// modelsInMigration returns the models whose tables are referenced by a migration.
+// Deleted migrations are read from the merge base.
+func (d *ChangeDetector) modelsInMigration(ctx context.Context, mergeBase, file string) []string {
+	content, err := os.ReadFile(filepath.Join(d.repoDir, file)) //nolint:gosec // G304: Migration path from git diff of trusted repo
+	if err != nil {
+		baseContent, gitErr := d.git(ctx, "show", mergeBase+":"+file)
+		if gitErr != nil {
+			d.log.WithError(gitErr).WithField("file", file).Warn("failed to read changed migration")
+
+			return nil
+		}
+
+		content = []byte(baseContent)
+	}
+
+	return referencedModels(string(content), d.modelCache.ListAllModels())
+}
+
+// referencedModels returns the models whose table (or _local table) appears in sql.
+func referencedModels(sql string, models []string) []string {
+	referenced := make([]string, 0)
+
+	for _, model := range models {
+		re := regexp.MustCompile(`\b` + regexp.QuoteMeta(model) + `(_local)?\b`)
+		if re.MatchString(sql) {
+			referenced = append(referenced, model)
+		}
+	}
+
+	sort.Strings(referenced)
+
+	return referenced
+}
+
+// changedExternalData returns the external tables whose fixture URL or network
+// column differ between the merge base and the working tree version of a test definition.
+func (d *ChangeDetector) changedExternalData(ctx context.Context, mergeBase, file string) []string {
+	current := &testdef.TestDefinition{}
+
+	if data, err := os.ReadFile(filepath.Join(d.repoDir, file)); err == nil { //nolint:gosec // G304: Test definition path from git diff of trusted repo
+		if err := yaml.Unmarshal(data, current); err != nil {
+			d.log.WithError(err).WithField("file", file).Warn("failed to parse changed test definition")
+		}
+	}
+
+	previous := &testdef.TestDefinition{}
+
+	if data, err := d.git(ctx, "show", mergeBase+":"+file); err == nil {
+		if err := yaml.Unmarshal([]byte(data), previous); err != nil {
+			d.log.WithError(err).WithField("file", file).Debug("failed to parse base test definition")
+		}
+	}
+
+	// Scenarios carry their own fixtures; a change in any of them counts.
+	previousSets, currentSets := externalDataSets(previous), externalDataSets(current)
+	changed := make(map[string]bool)
+
+	for scenario := range currentSets {
+		if _, ok := previousSets[scenario]; !ok {
+			previousSets[scenario] = nil
+		}
+	}
+
+	for scenario, previousData := range previousSets {
+		for _, table := range diffExternalData(previousData, currentSets[scenario]) {
+			changed[table] = true
+		}
+	}
+
+	tables := make([]string, 0, len(changed))
+	for table := range changed {
+		tables = append(tables, table)
+	}
+
+	sort.Strings(tables)
+
+	return tables
+}
+
+// externalDataSets returns the external_data sections of a raw definition keyed by
+// scenario name, with the top-level section under "".
+func externalDataSets(definition *testdef.TestDefinition) map[string]map[string]*testdef.ExternalTable {
+	sets := map[string]map[string]*testdef.ExternalTable{"": definition.ExternalData}
+
+	for _, scenario := range definition.Scenarios {
+		if scenario != nil {
+			sets[scenario.Name] = scenario.ExternalData
+		}
+	}
+
+	return sets
+}
+
+// diffExternalData returns the sorted set of tables added, removed or changed between two external_data sections.
+func diffExternalData(previous, current map[string]*testdef.ExternalTable) []string {
+	changed := make(map[string]bool)
+
+	for table, ext := range current {
+		prev, ok := previous[table]
+		if !ok || prev == nil || ext == nil || prev.URL != ext.URL || prev.NetworkColumn != ext.NetworkColumn {
+			changed[table] = true
+		}
+	}
+
+	for table := range previous {
+		if _, ok := current[table]; !ok {
+			changed[table] = true
+		}
+	}
+
+	tables := make([]string, 0, len(changed))
+	for table := range changed {
+		tables = append(tables, table)
+	}
+
+	sort.Strings(tables)
+
+	return tables
+}
+
+// git runs a git command in the repository and returns its stdout.
+func (d *ChangeDetector) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", d.repoDir}, args...)...) //nolint:gosec // G204: Git command with controlled arguments
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w (stderr: %s)", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// uniqueLines splits output into non-empty, de-duplicated, sorted lines.
+func uniqueLines(output string) []string {
+	seen := make(map[string]bool)
+	lines := make([]string, 0)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] {
+			continue
+		}
+
+		seen[line] = true
+		lines = append(lines, line)
+	}
+
+	sort.Strings(lines)
+
+	return lines
+}
@@ -0,0 +1,175 @@
+package testing
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyChangedPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		file     string
+		wantKind changeKind
+		wantName string
+	}{
+		{file: "models/transformations/fct_block.sql", wantKind: changeModel, wantName: "fct_block"},
+		{file: "models/external/canonical_beacon_block.sql", wantKind: changeModel, wantName: "canonical_beacon_block"},
+		{file: "migrations/042_fct_block.up.sql", wantKind: changeMigration},
+		{file: "tests/mainnet/models/fct_block.yaml", wantKind: changeTestDefinition, wantName: "fct_block"},
+		{file: "tests/sepolia/models/fct_block.yaml", wantKind: changeIgnored},
+		{file: "tests/mainnet/models/snapshots/fct_block.csv", wantKind: changeSnapshot},
+		{file: "tests/assertions/not_empty.yaml", wantKind: changeTemplate, wantName: "not_empty"},
+		{file: "tests/_common/models/fct_block.yaml", wantKind: changeBaseDefinition, wantName: "fct_block"},
+		{file: "tests/_common/models/snapshots/fct_block.csv", wantKind: changeSnapshot},
+		{file: "overrides.tests.yaml", wantKind: changeAll},
+		{file: "pkg/proto/clickhouse/fct_block.proto", wantKind: changeIgnored},
+		{file: "README.md", wantKind: changeIgnored},
+		{file: "internal/testing/engine.go", wantKind: changeAll},
+		{file: "cmd/test.go", wantKind: changeAll},
+		{file: "go.sum", wantKind: changeAll},
+		{file: ".gitignore", wantKind: changeUnknown},
+		{file: "scripts/release.sh", wantKind: changeUnknown},
+		{file: "notes.txt", wantKind: changeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			t.Parallel()
+
+			kind, name := classifyChangedPath(tt.file, "mainnet")
+			require.Equal(t, tt.wantKind, kind)
+			require.Equal(t, tt.wantName, name)
+		})
+	}
+}
+
+func TestReferencedModels(t *testing.T) {
+	t.Parallel()
+
+	sql := `CREATE TABLE fct_block_local ON CLUSTER '{cluster}' (slot UInt32);
+CREATE TABLE fct_block ON CLUSTER '{cluster}' AS fct_block_local;`
+
+	got := referencedModels(sql, []string{"fct_block", "fct_block_head", "canonical_beacon_block"})
+	require.Equal(t, []string{"fct_block"}, got)
+}
+
+func TestDiffExternalData(t *testing.T) {
+	t.Parallel()
+
+	previous := map[string]*testdef.ExternalTable{
+		"canonical_beacon_block":        {URL: "https://example.com/a.parquet"},
+		"beacon_api_eth_v1_events_head": {URL: "https://example.com/b.parquet"},
+		"removed_table":                 {URL: "https://example.com/c.parquet"},
+	}
+	current := map[string]*testdef.ExternalTable{
+		"canonical_beacon_block":        {URL: "https://example.com/a.parquet"},
+		"beacon_api_eth_v1_events_head": {URL: "https://example.com/b2.parquet"},
+		"added_table":                   {URL: "https://example.com/d.parquet"},
+	}
+
+	got := diffExternalData(previous, current)
+	require.Equal(t, []string{"added_table", "beacon_api_eth_v1_events_head", "removed_table"}, got)
+}
+
+func TestModelCacheDependents(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	externalDir := filepath.Join(root, "external")
+	transformationDir := filepath.Join(root, "transformations")
+
+	require.NoError(t, os.MkdirAll(externalDir, 0o750))
+	require.NoError(t, os.MkdirAll(transformationDir, 0o750))
+
+	files := map[string]string{
+		filepath.Join(externalDir, "canonical_beacon_block.sql"): "---\ntable: canonical_beacon_block\n---\nSELECT 1\n",
+		filepath.Join(transformationDir, "int_block.sql"): "---\ntable: int_block\n" +
+			"dependencies:\n  - \"{{external}}.canonical_beacon_block\"\n---\nSELECT 1\n",
+		filepath.Join(transformationDir, "fct_block.sql"): "---\ntable: fct_block\n" +
+			"dependencies:\n  - \"{{transformation}}.int_block\"\n---\nSELECT 1\n",
+		filepath.Join(transformationDir, "fct_other.sql"): "---\ntable: fct_other\n---\nSELECT 1\n",
+	}
+
+	for path, content := range files {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	cache := NewModelCache(logrus.New())
+	require.NoError(t, cache.LoadAll(context.Background(), externalDir, transformationDir))
+
+	require.Equal(t, []string{"fct_block", "int_block"}, cache.Dependents("canonical_beacon_block"))
+	require.Equal(t, []string{"fct_block"}, cache.Dependents("int_block"))
+	require.Empty(t, cache.Dependents("fct_other"))
+
+	name, ok := cache.ModelNameForFile("models/transformations/int_block.sql")
+	require.True(t, ok)
+	require.Equal(t, "int_block", name)
+}
+
+func TestChangeDetectorDetect_Rename(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := t.TempDir()
+
+	git := func(args ...string) {
+		t.Helper()
+
+		cmd := exec.CommandContext(ctx, "git", append([]string{
+			"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	write := func(file, content string) {
+		t.Helper()
+
+		path := filepath.Join(repo, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	// Long enough for git to see the rename below as one.
+	body := "SELECT\n" + strings.Repeat("    slot,\n", 20) + "    1 AS one\nFROM {{ .self.helpers.from }}\n"
+
+	write("models/external/canonical_beacon_block.sql", "---\ntable: canonical_beacon_block\n---\nSELECT 1\n")
+	write("models/transformations/int_block.sql", "---\ntable: int_block\n"+
+		"dependencies:\n  - \"{{external}}.canonical_beacon_block\"\n---\n"+body)
+	write("models/transformations/fct_block.sql", "---\ntable: fct_block\n"+
+		"dependencies:\n  - \"{{transformation}}.int_block\"\n---\nSELECT 1\n")
+	write("tests/mainnet/models/fct_block.yaml", "model: fct_block\nnetwork: mainnet\n"+
+		"external_data:\n  canonical_beacon_block:\n    url: https://example.com/block.parquet\n    network_column: meta_network_name\n"+
+		"assertions:\n  - name: count\n    sql: SELECT 1 AS one\n    expected:\n      one: 1\n")
+
+	git("init", "-q", "-b", "main")
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+	git("checkout", "-q", "-b", "rename")
+	git("mv", "models/transformations/int_block.sql", "models/transformations/int_block_v2.sql")
+	write("models/transformations/int_block_v2.sql", "---\ntable: int_block_v2\n"+
+		"dependencies:\n  - \"{{external}}.canonical_beacon_block\"\n---\n"+body)
+	git("commit", "-q", "-am", "rename int_block")
+
+	cache := NewModelCache(logrus.New())
+	require.NoError(t, cache.LoadAll(ctx, filepath.Join(repo, "models", "external"), filepath.Join(repo, "models", "transformations")))
+
+	detector := NewChangeDetector(logrus.New(), repo, cache, testdef.NewLoader(logrus.New(), filepath.Join(repo, "tests")))
+
+	changes, err := detector.Detect(ctx, "main", "mainnet")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"models/transformations/int_block.sql",
+		"models/transformations/int_block_v2.sql",
+	}, changes.Files)
+	require.Equal(t, []string{"fct_block"}, changes.Models, "dependents of the old name are affected")
+}
@@ -0,0 +1,167 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errChunkedMismatch = errors.New("chunked run produced different rows")
+	errChunkedCoverage = errors.New("chunked run covered a different range")
+)
+
+// slotChunkIntervalMax is the default interval.max of slot models in chunked runs. Slot
+// positions are unix timestamps, so a single slot spans 12 positions.
+const slotChunkIntervalMax uint64 = 12
+
+// chunkIntervalMax returns the interval.max of a model with the given interval type in
+// chunked runs: override when set, otherwise a single slot, or a single position of any
+// other interval type such as a block.
+func chunkIntervalMax(intervalType string, override uint64) uint64 {
+	if override > 0 {
+		return override
+	}
+
+	if intervalType == "slot" {
+		return slotChunkIntervalMax
+	}
+
+	return 1
+}
+
+// CountRowsMissing counts rows of database.table that are not in otherDatabase.table,
+// comparing only the given columns under FINAL.
+func (m *DatabaseManager) CountRowsMissing(
+	ctx context.Context,
+	database, otherDatabase, table string,
+	columns []string,
+) (uint64, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	selected := quoteIdentifiers(columns)
+	query := fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled identifiers
+		"SELECT count() FROM (SELECT %s FROM `%s`.`%s` FINAL EXCEPT SELECT %s FROM `%s`.`%s` FINAL)",
+		selected, database, table, selected, otherDatabase, table,
+	)
+
+	var missing uint64
+	if err := m.cbtConn.QueryRowContext(queryCtx, query).Scan(&missing); err != nil {
+		return 0, fmt.Errorf("comparing %s.%s with %s.%s: %w", database, table, otherDatabase, table, err)
+	}
+
+	return missing, nil
+}
+
+// checkChunking runs a passing incremental model again with a tiny interval.max and fails
+// if its rows differ from the normal run, exposing bugs at interval boundaries.
+// The chunked run uses a fresh clone of the CBT database and the same external database,
+// and waits until the model covers the same positions as the normal run.
+// Scheduled models and external model tests are skipped.
+// The clone is returned once created, even on failure; it is kept or dropped with the
+// test's other databases.
+func (o *Orchestrator) checkChunking(
+	ctx context.Context,
+	network, cbtDB, extDB, model string,
+	deps *Dependencies,
+) (string, error) {
+	tm := o.modelCache.GetTransformationModel(model)
+	if tm == nil || tm.ExecutionType == "scheduled" {
+		o.log.WithField("model", model).Debug("skipping chunking check for non-incremental model")
+
+		return "", nil
+	}
+
+	intervalMax := chunkIntervalMax(tm.IntervalType, o.chunkIntervalMax)
+
+	logCtx := o.log.WithFields(logrus.Fields{
+		"model":        model,
+		"cbt_db":       cbtDB,
+		"interval_max": intervalMax,
+	})
+
+	coverage, err := o.dbManager.IncrementalCoverage(ctx, cbtDB, model)
+	if err != nil {
+		return "", err
+	}
+
+	chunkedDB, err := o.dbManager.CloneCBTDatabase(ctx, o.generateTestID(), extractCloneTableNames(deps.TransformationModels))
+	if err != nil {
+		return "", fmt.Errorf("cloning cbt DB for chunked run: %w", err)
+	}
+
+	logCtx.WithFields(logrus.Fields{
+		"coverage":   coverage,
+		"chunked_db": chunkedDB,
+	}).Info("running model in small intervals to check chunking")
+
+	return chunkedDB, o.compareChunkedRun(ctx, network, cbtDB, chunkedDB, extDB, model, deps, intervalMax, coverage)
+}
+
+// compareChunkedRun runs the chunked transformations into chunkedDB and compares the
+// model's output with the normal run in cbtDB.
+func (o *Orchestrator) compareChunkedRun(
+	ctx context.Context,
+	network, cbtDB, chunkedDB, extDB, model string,
+	deps *Dependencies,
+	intervalMax, coverage uint64,
+) error {
+	allModels, transformationNames := transformationRunModels(deps)
+
+	if err := o.cbtEngine.RunChunkedTransformations(
+		ctx, network, chunkedDB, extDB, allModels, transformationNames, model, intervalMax, coverage,
+	); err != nil {
+		return fmt.Errorf("running chunked transformations: %w", err)
+	}
+
+	chunkedCoverage, err := o.dbManager.IncrementalCoverage(ctx, chunkedDB, model)
+	if err != nil {
+		return err
+	}
+
+	normal, err := o.dbManager.FingerprintTable(ctx, cbtDB, model)
+	if err != nil {
+		return fmt.Errorf("fingerprinting normal run: %w", err)
+	}
+
+	chunked, err := o.dbManager.FingerprintTable(ctx, chunkedDB, model)
+	if err != nil {
+		return fmt.Errorf("fingerprinting chunked run: %w", err)
+	}
+
+	match, err := compareChunked(coverage, chunkedCoverage, normal, chunked)
+	if err != nil || match {
+		return err
+	}
+
+	columns, err := o.dbManager.comparableColumns(ctx, cbtDB, model)
+	if err != nil {
+		return err
+	}
+
+	onlyNormal, err := o.dbManager.CountRowsMissing(ctx, cbtDB, chunkedDB, model, columns)
+	if err != nil {
+		return err
+	}
+
+	onlyChunked, err := o.dbManager.CountRowsMissing(ctx, chunkedDB, cbtDB, model, columns)
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w with interval max %d: %d rows only in the normal run, %d only in the chunked run (%s normal, %s chunked)",
+		errChunkedMismatch, intervalMax, onlyNormal, onlyChunked, normal, chunked)
+}
+
+// compareChunked decides a chunking check: the chunked run must cover the positions of
+// the normal run. It reports whether both runs left the same fingerprint.
+func compareChunked(coverage, chunkedCoverage uint64, normal, chunked *TableFingerprint) (bool, error) {
+	if chunkedCoverage != coverage {
+		return false, fmt.Errorf("%w: %d positions chunked, %d in the normal run", errChunkedCoverage, chunkedCoverage, coverage)
+	}
+
+	return *normal == *chunked, nil
+}
@@ -0,0 +1,65 @@
+package testing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkIntervalMax(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		intervalType string
+		override     uint64
+		want         uint64
+	}{
+		{name: "one slot", intervalType: "slot", want: 12},
+		{name: "one block", intervalType: "block", want: 1},
+		{name: "other interval type", intervalType: "entity", want: 1},
+		{name: "override for slots", intervalType: "slot", override: 60, want: 60},
+		{name: "override for blocks", intervalType: "block", override: 5, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, chunkIntervalMax(tt.intervalType, tt.override))
+		})
+	}
+}
+
+func TestCompareChunked(t *testing.T) {
+	t.Parallel()
+
+	normal := &TableFingerprint{Rows: 10, Checksum: 0xabc}
+
+	tests := []struct {
+		name            string
+		chunkedCoverage uint64
+		chunked         *TableFingerprint
+		wantMatch       bool
+		wantErr         error
+	}{
+		{name: "same rows", chunkedCoverage: 120, chunked: &TableFingerprint{Rows: 10, Checksum: 0xabc}, wantMatch: true},
+		{name: "rows split at a boundary", chunkedCoverage: 120, chunked: &TableFingerprint{Rows: 12, Checksum: 0xabc}},
+		{name: "changed values", chunkedCoverage: 120, chunked: &TableFingerprint{Rows: 10, Checksum: 0xdef}},
+		{name: "covered less", chunkedCoverage: 60, chunked: &TableFingerprint{Rows: 5, Checksum: 0x1}, wantErr: errChunkedCoverage},
+		{name: "covered more", chunkedCoverage: 180, chunked: &TableFingerprint{Rows: 10, Checksum: 0xabc}, wantErr: errChunkedCoverage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			match, err := compareChunked(120, tt.chunkedCoverage, normal, tt.chunked)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.wantMatch, match)
+		})
+	}
+
+	_, err := compareChunked(120, 60, normal, normal)
+	require.EqualError(t, err, "chunked run covered a different range: 60 positions chunked, 120 in the normal run")
+}
@@ -19,6 +19,11 @@ type TestConfig struct {
 	DockerImage   string
 	DockerNetwork string
 
+	// CBT runner configuration
+	CBTRunner            string // CBTRunnerDocker or CBTRunnerBinary
+	CBTBinary            string // Path of the CBT binary run by CBTRunnerBinary
+	CBTClickHouseHTTPURL string // Host HTTP URL of the CBT cluster, used by CBTRunnerBinary
+
 	// Execution timeouts
 	ExecutionTimeout          time.Duration
 	TransformationWaitTimeout time.Duration
@@ -28,7 +33,8 @@ type TestConfig struct {
 	QueryTimeout          time.Duration
 
 	// CBT container configuration
-	CBTConcurrency int // Max concurrent CBT Docker containers
+	CBTConcurrency  int // Max concurrent CBT Docker containers
+	CBTLogTailLines int // CBT log lines included in transformation timeout errors
 
 	// Cache configuration
 	MaxConcurrentDownloads int
@@ -46,6 +52,9 @@ type TestConfig struct {
 	AssertionMaxRetries int
 	AssertionRetryDelay time.Duration
 
+	// UpdateSnapshots rewrites snapshot assertion files instead of comparing against them.
+	UpdateSnapshots bool
+
 	// Safety configuration
 	// SafeHostnames is a whitelist of ClickHouse hostnames that are allowed for destructive operations.
 	// If a connection is made to a ClickHouse instance whose hostname is not in this list,
@@ -53,6 +62,13 @@ type TestConfig struct {
 	SafeHostnames []string
 }
 
+const (
+	// CBTRunnerDocker runs CBT in a container per test run, on the platform Docker network.
+	CBTRunnerDocker = "docker"
+	// CBTRunnerBinary runs a local CBT binary per test run, against the platform's host ports.
+	CBTRunnerBinary = "binary"
+)
+
 // DefaultTestConfig returns a TestConfig with default values for all test execution parameters.
 func DefaultTestConfig() *TestConfig {
 	// Read safe hostnames from environment variable, fall back to defaults
@@ -63,6 +79,10 @@ func DefaultTestConfig() *TestConfig {
 		DockerImage:   "ethpandaops/cbt:debian-latest",
 		DockerNetwork: appconfig.GetDockerNetworkName(),
 
+		// CBT runner
+		CBTRunner:            CBTRunnerDocker,
+		CBTClickHouseHTTPURL: appconfig.GetCBTClickHouseHTTPURL(),
+
 		// Execution timeouts
 		ExecutionTimeout:          30 * time.Minute,
 		TransformationWaitTimeout: 10 * time.Minute,
@@ -71,7 +91,8 @@ func DefaultTestConfig() *TestConfig {
 		QueryTimeout:          5 * time.Minute,
 
 		// CBT containers
-		CBTConcurrency: 15, // Reduced to limit ClickHouse contention under concurrent load
+		CBTConcurrency:  15, // Reduced to limit ClickHouse contention under concurrent load
+		CBTLogTailLines: 20,
 
 		// Cache
 		MaxConcurrentDownloads: 10,
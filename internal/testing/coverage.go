@@ -0,0 +1,442 @@
+package testing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/ethpandaops/xatu-cbt/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errDependencyCycle      = errors.New("dependency cycle")
+	errUnknownDependency    = errors.New("unknown dependency")
+	errNoInterval           = errors.New("dependency has no interval")
+	errNoIntervalDependency = errors.New("no dependency with interval type")
+	errDisjointDependencies = errors.New("dependencies have no data in common")
+	errNoExternalData       = errors.New("no external data")
+)
+
+// PositionRange is a half-open range [Start, End) of interval positions, such as slot
+// start timestamps or block numbers.
+type PositionRange struct {
+	Start uint64
+	End   uint64
+}
+
+func (r PositionRange) String() string {
+	return fmt.Sprintf("[%d, %d)", r.Start, r.End)
+}
+
+// ModelCoverage compares the intervals an incremental model processed with the range its
+// dependencies' data spans.
+type ModelCoverage struct {
+	Model     string
+	Expected  PositionRange
+	Covered   []PositionRange // Processed intervals, merged
+	Gaps      []PositionRange // Parts of Expected not covered
+	Unchecked error           // Why the model has no expected range, leaving its coverage unchecked
+}
+
+// Complete reports whether the processed intervals cover the whole expected range.
+func (c ModelCoverage) Complete() bool {
+	return len(c.Gaps) == 0
+}
+
+func (c ModelCoverage) String() string {
+	if c.Unchecked != nil {
+		return fmt.Sprintf("%s: not checked: %v", c.Model, c.Unchecked)
+	}
+
+	return fmt.Sprintf("%s: expected %s, covered %s, gaps %s",
+		c.Model, c.Expected, formatRanges(c.Covered), formatRanges(c.Gaps))
+}
+
+func formatRanges(ranges []PositionRange) string {
+	if len(ranges) == 0 {
+		return "none"
+	}
+
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		parts = append(parts, r.String())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// newModelCoverage merges the processed intervals of a model and finds the parts of
+// expected they leave uncovered.
+func newModelCoverage(model string, expected PositionRange, intervals []PositionRange) ModelCoverage {
+	coverage := ModelCoverage{Model: model, Expected: expected, Covered: mergeRanges(intervals)}
+
+	next := expected.Start
+
+	for _, r := range coverage.Covered {
+		if r.End <= next {
+			continue
+		}
+
+		if r.Start >= expected.End {
+			break
+		}
+
+		if r.Start > next {
+			coverage.Gaps = append(coverage.Gaps, PositionRange{Start: next, End: r.Start})
+		}
+
+		next = r.End
+	}
+
+	if next < expected.End {
+		coverage.Gaps = append(coverage.Gaps, PositionRange{Start: next, End: expected.End})
+	}
+
+	return coverage
+}
+
+// mergeRanges sorts ranges and merges those that overlap or touch.
+func mergeRanges(ranges []PositionRange) []PositionRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sorted := make([]PositionRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	merged := []PositionRange{sorted[0]}
+
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start > last.End {
+			merged = append(merged, r)
+			continue
+		}
+
+		if r.End > last.End {
+			last.End = r.End
+		}
+	}
+
+	return merged
+}
+
+// getIntervals returns, per incremental model, the intervals recorded in
+// admin_cbt_incremental.
+func (e *CBTEngine) getIntervals(ctx context.Context, conn *sql.DB, dbName string) (map[string][]PositionRange, error) {
+	query := fmt.Sprintf(`SELECT table, position, interval FROM %s.admin_cbt_incremental FINAL`, dbName) //nolint:gosec // G201: Safe SQL with controlled identifiers
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	intervals := make(map[string][]PositionRange)
+
+	for rows.Next() {
+		var (
+			tableName          string
+			position, interval uint64
+		)
+
+		if err := rows.Scan(&tableName, &position, &interval); err != nil {
+			continue
+		}
+
+		intervals[tableName] = append(intervals[tableName], PositionRange{Start: position, End: position + interval})
+	}
+
+	return intervals, nil
+}
+
+// expectedRanges returns the range each incremental model in models must cover, and why
+// the others have none, as their coverage then goes unchecked. A model's range is the
+// intersection of its dependency groups with the same interval type. An OR group spans
+// the ranges of its options, since the model processes what any of them has data for. An
+// external dependency's range spans its min and max bounds, as returned by bounds; a
+// transformation dependency's is its own expected range.
+func (e *CBTEngine) expectedRanges(
+	models map[string]bool,
+	bounds func(model *ModelMetadata) (PositionRange, error),
+) (map[string]PositionRange, map[string]error) {
+	var (
+		ranges   = make(map[string]PositionRange)
+		errs     = make(map[string]error)
+		expected func(model string) (PositionRange, error)
+	)
+
+	expected = func(model string) (PositionRange, error) {
+		if r, ok := ranges[model]; ok {
+			return r, nil
+		}
+
+		if err, ok := errs[model]; ok {
+			return PositionRange{}, err
+		}
+
+		// Guard against cycles (should not happen in a valid DAG).
+		errs[model] = fmt.Errorf("%w: %s", errDependencyCycle, model)
+
+		var (
+			r   PositionRange
+			err error
+		)
+
+		if em := e.modelCache.GetExternalModel(model); em != nil {
+			r, err = bounds(em)
+		} else {
+			r, err = e.dependencyRange(model, expected)
+		}
+
+		if err != nil {
+			errs[model] = err
+
+			return PositionRange{}, err
+		}
+
+		delete(errs, model)
+		ranges[model] = r
+
+		return r, nil
+	}
+
+	var (
+		result    = make(map[string]PositionRange)
+		unchecked = make(map[string]error)
+	)
+
+	for model := range models {
+		tm := e.modelCache.GetTransformationModel(model)
+		if tm == nil || tm.ExecutionType != "incremental" {
+			continue
+		}
+
+		r, err := expected(model)
+		if err != nil {
+			unchecked[model] = err
+
+			continue
+		}
+
+		result[model] = r
+	}
+
+	return result, unchecked
+}
+
+// dependencyRange intersects the ranges of the dependency groups of a transformation
+// that have its interval type, using expected for the range of each option.
+func (e *CBTEngine) dependencyRange(model string, expected func(model string) (PositionRange, error)) (PositionRange, error) {
+	tm := e.modelCache.GetTransformationModel(model)
+	if tm == nil {
+		return PositionRange{}, fmt.Errorf("%w: %s", errUnknownDependency, model)
+	}
+
+	if tm.ExecutionType == "scheduled" || tm.IntervalType == "" {
+		return PositionRange{}, fmt.Errorf("%w: %s", errNoInterval, model)
+	}
+
+	var (
+		r     PositionRange
+		found bool
+	)
+
+	for _, group := range tm.DependencyGroups {
+		groupRange, ok, err := e.groupRange(group, tm.IntervalType, expected)
+		if err != nil {
+			return PositionRange{}, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		if !found {
+			r, found = groupRange, true
+
+			continue
+		}
+
+		r.Start = max(r.Start, groupRange.Start)
+		r.End = min(r.End, groupRange.End)
+	}
+
+	if !found {
+		return PositionRange{}, fmt.Errorf("%w: %s", errNoIntervalDependency, tm.IntervalType)
+	}
+
+	// Dependencies without overlapping data leave nothing to check.
+	if r.Start >= r.End {
+		return PositionRange{}, fmt.Errorf("%w: %s", errDisjointDependencies, model)
+	}
+
+	return r, nil
+}
+
+// groupRange returns the span of the ranges of the options of a dependency group with
+// the given interval type, skipping options without one as long as any has one. It
+// reports false when no option has the interval type.
+func (e *CBTEngine) groupRange(
+	group []string,
+	intervalType string,
+	expected func(model string) (PositionRange, error),
+) (PositionRange, bool, error) {
+	var (
+		r     PositionRange
+		found bool
+		errs  []error
+	)
+
+	for _, dep := range group {
+		dep = e.modelCache.ResolveDependency(dep)
+
+		depType, ok := e.intervalType(dep)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", errUnknownDependency, dep))
+
+			continue
+		}
+
+		if depType != intervalType {
+			continue
+		}
+
+		depRange, err := expected(dep)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("dependency %s: %w", dep, err))
+
+			continue
+		}
+
+		if !found {
+			r, found = depRange, true
+
+			continue
+		}
+
+		r.Start = min(r.Start, depRange.Start)
+		r.End = max(r.End, depRange.End)
+	}
+
+	if found {
+		return r, true, nil
+	}
+
+	if len(errs) > 0 {
+		return PositionRange{}, false, errors.Join(errs...)
+	}
+
+	return PositionRange{}, false, nil
+}
+
+// intervalType returns the interval type of an external or transformation model, and
+// false if there is no such model.
+func (e *CBTEngine) intervalType(model string) (string, bool) {
+	if em := e.modelCache.GetExternalModel(model); em != nil {
+		return em.IntervalType, true
+	}
+
+	if tm := e.modelCache.GetTransformationModel(model); tm != nil {
+		return tm.IntervalType, true
+	}
+
+	return "", false
+}
+
+// externalBounds runs the bounds query of an external model as a full scan against the
+// test's external data. It fails when the query can't be rendered or run, or the table
+// has no data.
+func (e *CBTEngine) externalBounds(
+	ctx context.Context,
+	conn *sql.DB,
+	network, externalDB string,
+	model *ModelMetadata,
+) (PositionRange, error) {
+	boundsSQL, err := e.renderBoundsQuery(network, externalDB, model)
+	if err != nil {
+		return PositionRange{}, fmt.Errorf("rendering bounds of %s: %w", model.Name, err)
+	}
+
+	query := fmt.Sprintf("SELECT toUInt64(`min`), toUInt64(`max`) FROM (\n%s\n)", boundsSQL)
+
+	var bounds PositionRange
+	if err := conn.QueryRowContext(ctx, query).Scan(&bounds.Start, &bounds.End); err != nil {
+		return PositionRange{}, fmt.Errorf("querying bounds of %s: %w", model.Name, err)
+	}
+
+	if bounds.End == 0 || bounds.Start >= bounds.End {
+		return PositionRange{}, fmt.Errorf("%w: %s", errNoExternalData, model.Name)
+	}
+
+	e.log.WithFields(logrus.Fields{
+		"model": model.Name,
+		"min":   bounds.Start,
+		"max":   bounds.End,
+	}).Debug("queried external bounds")
+
+	return bounds, nil
+}
+
+// renderBoundsQuery renders the SQL of an external model as CBT does for a full bounds
+// scan, reading from the test's copy of the table on the xatu cluster.
+func (e *CBTEngine) renderBoundsQuery(network, externalDB string, model *ModelMetadata) (string, error) {
+	content, err := os.ReadFile(model.FilePath)
+	if err != nil {
+		return "", fmt.Errorf("reading model: %w", err)
+	}
+
+	_, body, err := e.modelCache.extractFrontmatter(string(content))
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New(model.Name).
+		Option("missingkey=zero").
+		Funcs(template.FuncMap{"default": templateDefault}).
+		Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("parsing bounds query: %w", err)
+	}
+
+	database, table := externalDB, model.Name
+	if model.SourceDB != "" {
+		database, table = model.SourceDB, model.SourceTable
+	}
+
+	data := map[string]interface{}{
+		"self": map[string]interface{}{
+			"database": database,
+			"table":    table,
+			"helpers": map[string]interface{}{
+				"from": fmt.Sprintf("cluster('%s', `%s`.`%s`)", config.XatuClusterName, database, table),
+			},
+		},
+		"env":   cbtModelEnv(network, externalDB),
+		"cache": map[string]interface{}{"is_incremental_scan": false},
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering bounds query: %w", err)
+	}
+
+	return b.String(), nil
+}
+
+// templateDefault is the default template function of CBT model templates: given, when
+// set and not empty, otherwise def.
+func templateDefault(def interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || given[0] == nil || given[0] == "" {
+		return def
+	}
+
+	return given[0]
+}
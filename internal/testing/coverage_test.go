@@ -0,0 +1,213 @@
+package testing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewModelCoverage(t *testing.T) {
+	t.Parallel()
+
+	expected := PositionRange{Start: 100, End: 200}
+
+	tests := []struct {
+		name        string
+		intervals   []PositionRange
+		wantCovered []PositionRange
+		wantGaps    []PositionRange
+	}{
+		{
+			name:     "nothing processed",
+			wantGaps: []PositionRange{{Start: 100, End: 200}},
+		},
+		{
+			name:        "first chunk only",
+			intervals:   []PositionRange{{Start: 100, End: 150}},
+			wantCovered: []PositionRange{{Start: 100, End: 150}},
+			wantGaps:    []PositionRange{{Start: 150, End: 200}},
+		},
+		{
+			name:        "touching intervals out of order",
+			intervals:   []PositionRange{{Start: 150, End: 200}, {Start: 100, End: 150}},
+			wantCovered: []PositionRange{{Start: 100, End: 200}},
+		},
+		{
+			name:        "gap in the middle",
+			intervals:   []PositionRange{{Start: 100, End: 120}, {Start: 180, End: 200}, {Start: 110, End: 130}},
+			wantCovered: []PositionRange{{Start: 100, End: 130}, {Start: 180, End: 200}},
+			wantGaps:    []PositionRange{{Start: 130, End: 180}},
+		},
+		{
+			name:        "covered beyond the expected range",
+			intervals:   []PositionRange{{Start: 50, End: 250}},
+			wantCovered: []PositionRange{{Start: 50, End: 250}},
+		},
+		{
+			name:        "missing the start",
+			intervals:   []PositionRange{{Start: 120, End: 300}},
+			wantCovered: []PositionRange{{Start: 120, End: 300}},
+			wantGaps:    []PositionRange{{Start: 100, End: 120}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			coverage := newModelCoverage("fct_block", expected, tt.intervals)
+			require.Equal(t, tt.wantCovered, coverage.Covered)
+			require.Equal(t, tt.wantGaps, coverage.Gaps)
+			require.Equal(t, len(tt.wantGaps) == 0, coverage.Complete())
+		})
+	}
+}
+
+func TestModelCoverage_String(t *testing.T) {
+	t.Parallel()
+
+	coverage := newModelCoverage("fct_block", PositionRange{Start: 100, End: 200}, []PositionRange{{Start: 100, End: 150}})
+	require.Equal(t, "fct_block: expected [100, 200), covered [100, 150), gaps [150, 200)", coverage.String())
+
+	coverage = newModelCoverage("fct_block", PositionRange{Start: 100, End: 200}, nil)
+	require.Equal(t, "fct_block: expected [100, 200), covered none, gaps [100, 200)", coverage.String())
+}
+
+func TestExpectedRanges(t *testing.T) {
+	t.Parallel()
+
+	cache := NewModelCache(logrus.New())
+
+	for name, intervalType := range map[string]string{
+		"beacon_api_eth_v1_events_blob_sidecar": "slot",
+		"libp2p_gossipsub_blob_sidecar":         "slot",
+		"canonical_beacon_block":                "slot",
+		"canonical_beacon_validators":           "slot",
+		"empty_table":                           "slot",
+		"dim_node_entity":                       "entity",
+	} {
+		cache.externalModels[name] = &ModelMetadata{Name: name, IntervalType: intervalType}
+	}
+
+	cache.externalModels["observoor_cpu_utilization"] = &ModelMetadata{
+		Name:         "observoor_cpu_utilization",
+		SourceDB:     "observoor",
+		SourceTable:  "cpu_utilization",
+		IntervalType: "slot",
+	}
+
+	transformations := map[string][][]string{
+		"fct_blob_count":   {{"beacon_api_eth_v1_events_blob_sidecar", "libp2p_gossipsub_blob_sidecar"}},
+		"fct_block":        {{"canonical_beacon_block"}, {"canonical_beacon_validators"}},
+		"fct_block_daily":  {{"fct_block"}},
+		"fct_cpu":          {{"observoor.cpu_utilization"}},
+		"fct_or_empty":     {{"empty_table", "canonical_beacon_block"}},
+		"fct_empty":        {{"empty_table"}, {"canonical_beacon_block"}},
+		"fct_entity":       {{"dim_node_entity"}},
+		"fct_unknown":      {{"missing_table"}},
+		"fct_disjoint":     {{"canonical_beacon_validators"}, {"observoor.cpu_utilization"}},
+		"fct_mixed_entity": {{"dim_node_entity"}, {"canonical_beacon_block"}},
+	}
+
+	for name, groups := range transformations {
+		cache.transformationModels[name] = &ModelMetadata{
+			Name:             name,
+			ExecutionType:    "incremental",
+			IntervalType:     "slot",
+			DependencyGroups: groups,
+		}
+	}
+
+	cache.transformationModels["dim_scheduled"] = &ModelMetadata{Name: "dim_scheduled", ExecutionType: "scheduled"}
+
+	bounds := map[string]PositionRange{
+		"beacon_api_eth_v1_events_blob_sidecar": {Start: 100, End: 200},
+		"libp2p_gossipsub_blob_sidecar":         {Start: 150, End: 300},
+		"canonical_beacon_block":                {Start: 100, End: 300},
+		"canonical_beacon_validators":           {Start: 120, End: 180},
+		"observoor_cpu_utilization":             {Start: 500, End: 600},
+	}
+
+	engine := NewCBTEngine(logrus.New(), nil, cache, "", "", t.TempDir())
+
+	models := make(map[string]bool, len(transformations)+1)
+	for name := range cache.transformationModels {
+		models[name] = true
+	}
+
+	expected, unchecked := engine.expectedRanges(models, func(model *ModelMetadata) (PositionRange, error) {
+		if r, ok := bounds[model.Name]; ok {
+			return r, nil
+		}
+
+		return PositionRange{}, fmt.Errorf("%w: %s", errNoExternalData, model.Name)
+	})
+
+	require.Equal(t, map[string]PositionRange{
+		"fct_blob_count":   {Start: 100, End: 300}, // OR options are united
+		"fct_block":        {Start: 120, End: 180}, // AND dependencies are intersected
+		"fct_block_daily":  {Start: 120, End: 180},
+		"fct_cpu":          {Start: 500, End: 600}, // cross-database dependency
+		"fct_or_empty":     {Start: 100, End: 300}, // an OR option without data is skipped
+		"fct_mixed_entity": {Start: 100, End: 300}, // other interval types are ignored
+	}, expected)
+
+	require.ErrorIs(t, unchecked["fct_empty"], errNoExternalData)
+	require.ErrorIs(t, unchecked["fct_entity"], errNoIntervalDependency)
+	require.ErrorIs(t, unchecked["fct_unknown"], errUnknownDependency)
+	require.ErrorIs(t, unchecked["fct_disjoint"], errDisjointDependencies)
+	require.Len(t, unchecked, 4, "scheduled models have no coverage to check")
+
+	coverage := ModelCoverage{Model: "fct_empty", Unchecked: unchecked["fct_empty"]}
+	require.True(t, coverage.Complete())
+	require.Equal(t, "fct_empty: not checked: dependency empty_table: no external data: empty_table", coverage.String())
+}
+
+func TestRenderBoundsQuery(t *testing.T) {
+	t.Parallel()
+
+	modelPath := filepath.Join(t.TempDir(), "beacon_api_eth_v1_events_block.sql")
+	require.NoError(t, os.WriteFile(modelPath, []byte(`---
+table: beacon_api_eth_v1_events_block
+interval:
+  type: slot
+---
+SELECT
+    {{ if .cache.is_incremental_scan }}
+      '{{ .cache.previous_min }}' as min,
+    {{ else }}
+      toUnixTimestamp(min(slot_start_date_time)) as min,
+    {{ end }}
+    toUnixTimestamp(max(slot_start_date_time)) as max
+FROM {{ .self.helpers.from }}
+WHERE meta_network_name = '{{ .env.NETWORK }}'
+    {{- $ts := default (default "0" .env.EXTERNAL_MODEL_MIN_TIMESTAMP) .env.UNSET_MIN_TIMESTAMP }}
+    AND slot_start_date_time >= fromUnixTimestamp({{ $ts }})
+`), 0o600))
+
+	engine := NewCBTEngine(logrus.New(), nil, NewModelCache(logrus.New()), "", "", t.TempDir())
+
+	query, err := engine.renderBoundsQuery("mainnet", "ext_1", &ModelMetadata{
+		Name:     "beacon_api_eth_v1_events_block",
+		FilePath: modelPath,
+	})
+	require.NoError(t, err)
+	require.Contains(t, query, "toUnixTimestamp(min(slot_start_date_time)) as min")
+	require.NotContains(t, query, "previous_min")
+	require.Contains(t, query, "FROM cluster('xatu_cluster', `ext_1`.`beacon_api_eth_v1_events_block`)")
+	require.Contains(t, query, "meta_network_name = 'mainnet'")
+	require.Contains(t, query, "slot_start_date_time >= fromUnixTimestamp(0)")
+
+	query, err = engine.renderBoundsQuery("mainnet", "ext_1", &ModelMetadata{
+		Name:        "observoor_cpu_utilization",
+		SourceDB:    "observoor",
+		SourceTable: "cpu_utilization",
+		FilePath:    modelPath,
+	})
+	require.NoError(t, err)
+	require.Contains(t, query, "FROM cluster('xatu_cluster', `observoor`.`cpu_utilization`)")
+}
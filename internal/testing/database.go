@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,6 +22,7 @@ import (
 	"github.com/ethpandaops/xatu-cbt/internal/config"
 	"github.com/ethpandaops/xatu-cbt/internal/infra"
 	"github.com/ethpandaops/xatu-cbt/internal/testing/memfs"
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
 	"github.com/fatih/color"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/clickhouse"
@@ -35,6 +37,7 @@ type DatabaseManager struct {
 	xatuConnStr      string
 	cbtConnStr       string
 	xatuMigrationDir string
+	cbtTemplateDB    string
 	forceRebuild     bool
 	log              logrus.FieldLogger
 	config           *TestConfig
@@ -61,6 +64,7 @@ func NewDatabaseManager(
 		xatuConnStr:      xatuConnStr,
 		cbtConnStr:       cbtConnStr,
 		xatuMigrationDir: xatuMigrationDir,
+		cbtTemplateDB:    config.CBTTemplateDatabase,
 		forceRebuild:     forceRebuild,
 		log:              log.WithField("component", "database_manager"),
 		config:           cfg,
@@ -68,6 +72,18 @@ func NewDatabaseManager(
 	}
 }
 
+// WithCBTTemplate returns a manager sharing this manager's connections that builds and
+// clones CBT databases from templateDB instead. The template is always rebuilt, since it
+// holds the migrations of another ref. Only the original manager should be stopped.
+func (m *DatabaseManager) WithCBTTemplate(templateDB string) *DatabaseManager {
+	clone := *m
+	clone.cbtTemplateDB = templateDB
+	clone.forceRebuild = true
+	clone.log = m.log.WithField("template", templateDB)
+
+	return &clone
+}
+
 // Start initializes database connections.
 func (m *DatabaseManager) Start(ctx context.Context) error {
 	m.log.Debug("starting database manager")
@@ -113,6 +129,25 @@ func (m *DatabaseManager) Start(ctx context.Context) error {
 	return nil
 }
 
+// EphemeralClusters describes where per-test databases live, for garbage collection:
+// ext_ databases on the xatu cluster and cbt_ and legacy test_ databases on the CBT cluster.
+func (m *DatabaseManager) EphemeralClusters() []infra.EphemeralCluster {
+	return []infra.EphemeralCluster{
+		{
+			Name:       config.XatuClusterName,
+			Conn:       m.xatuConn,
+			Prefixes:   []string{config.ExternalDBPrefix},
+			TemplateDB: config.DefaultDatabase,
+		},
+		{
+			Name:       config.CBTClusterName,
+			Conn:       m.cbtConn,
+			Prefixes:   []string{config.CBTDBPrefix, "test_"},
+			TemplateDB: config.CBTTemplateDatabase,
+		},
+	}
+}
+
 // Stop closes database connections.
 func (m *DatabaseManager) Stop() error {
 	m.log.Debug("stopping database manager")
@@ -210,7 +245,7 @@ func (m *DatabaseManager) PrepareNetworkDatabase(ctx context.Context, _ string)
 // CreateCBTTemplate creates the CBT template database with migrations (run once at startup).
 // Per-test databases will be cloned from this template to avoid re-running migrations.
 func (m *DatabaseManager) CreateCBTTemplate(ctx context.Context, migrationDir string) error {
-	templateDB := config.CBTTemplateDatabase
+	templateDB := m.cbtTemplateDB
 
 	logCtx := m.log.WithFields(logrus.Fields{
 		"cluster":  "xatu-cbt",
@@ -271,7 +306,7 @@ func (m *DatabaseManager) isCBTTemplateReady(ctx context.Context) bool {
 
 	dbSQL := fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled identifiers
 		"SELECT COUNT(*) FROM system.databases WHERE name = '%s'",
-		config.CBTTemplateDatabase)
+		m.cbtTemplateDB)
 	if err := m.cbtConn.QueryRowContext(dbCtx, dbSQL).Scan(&dbCount); err != nil || dbCount == 0 {
 		return false
 	}
@@ -287,7 +322,7 @@ func (m *DatabaseManager) isCBTTemplateReady(ctx context.Context) bool {
 		FROM system.tables
 		WHERE database = 'default'
 		AND name = '%s%s'`,
-		config.SchemaMigrationsPrefix, config.CBTTemplateDatabase)
+		config.SchemaMigrationsPrefix, m.cbtTemplateDB)
 	if err := m.cbtConn.QueryRowContext(queryCtx, checkSQL).Scan(&count); err != nil || count == 0 {
 		return false
 	}
@@ -301,7 +336,7 @@ func (m *DatabaseManager) isCBTTemplateReady(ctx context.Context) bool {
 	countSQL := fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled identifiers
 		`SELECT COUNT(*)
 		FROM default.%s%s`,
-		config.SchemaMigrationsPrefix, config.CBTTemplateDatabase)
+		config.SchemaMigrationsPrefix, m.cbtTemplateDB)
 	if err := m.cbtConn.QueryRowContext(queryCtx2, countSQL).Scan(&migrationCount); err != nil {
 		return false
 	}
@@ -315,7 +350,7 @@ func (m *DatabaseManager) clearCBTTemplateTables(ctx context.Context) error {
 	defer cancel()
 
 	rows, err := m.cbtConn.QueryContext(queryCtx,
-		fmt.Sprintf("SELECT name, engine FROM system.tables WHERE database = '%s'", config.CBTTemplateDatabase))
+		fmt.Sprintf("SELECT name, engine FROM system.tables WHERE database = '%s'", m.cbtTemplateDB))
 	if err != nil {
 		return fmt.Errorf("querying template tables: %w", err)
 	}
@@ -340,10 +375,10 @@ func (m *DatabaseManager) clearCBTTemplateTables(ctx context.Context) error {
 		var dropSQL string
 		if obj.engine == "MaterializedView" {
 			dropSQL = fmt.Sprintf("DROP VIEW IF EXISTS `%s`.`%s` ON CLUSTER %s SYNC",
-				config.CBTTemplateDatabase, obj.name, config.CBTClusterName)
+				m.cbtTemplateDB, obj.name, config.CBTClusterName)
 		} else {
 			dropSQL = fmt.Sprintf("DROP TABLE IF EXISTS `%s`.`%s` ON CLUSTER %s SYNC",
-				config.CBTTemplateDatabase, obj.name, config.CBTClusterName)
+				m.cbtTemplateDB, obj.name, config.CBTClusterName)
 		}
 
 		queryCtx2, cancel2 := context.WithTimeout(ctx, m.config.QueryTimeout)
@@ -357,7 +392,7 @@ func (m *DatabaseManager) clearCBTTemplateTables(ctx context.Context) error {
 
 	// Also drop migrations table
 	dropMigrationsSQL := fmt.Sprintf("DROP TABLE IF EXISTS `default`.`%s%s`",
-		config.SchemaMigrationsPrefix, config.CBTTemplateDatabase)
+		config.SchemaMigrationsPrefix, m.cbtTemplateDB)
 
 	queryCtx3, cancel3 := context.WithTimeout(ctx, m.config.QueryTimeout)
 	defer cancel3()
@@ -827,7 +862,7 @@ func (m *DatabaseManager) CloneCBTDatabase(ctx context.Context, testID string, t
 	}
 
 	// Always include admin_* tables - CBT engine needs these for tracking bounds
-	adminTables, err := m.listAdminTables(ctx, m.cbtConn, config.CBTTemplateDatabase)
+	adminTables, err := m.listAdminTables(ctx, m.cbtConn, m.cbtTemplateDB)
 	if err != nil {
 		return "", fmt.Errorf("listing admin tables: %w", err)
 	}
@@ -854,7 +889,7 @@ func (m *DatabaseManager) CloneCBTDatabase(ctx context.Context, testID string, t
 			defer func() { <-cloneSem }()
 
 			if cloneErr := m.cloneTableWithUniqueReplicaPath(ctx, m.cbtConn,
-				config.CBTTemplateDatabase, cbtDBName, t.name, t.name, config.CBTClusterName); cloneErr != nil {
+				m.cbtTemplateDB, cbtDBName, t.name, t.name, config.CBTClusterName); cloneErr != nil {
 				cloneErrs <- fmt.Errorf("cloning table %s: %w", t.name, cloneErr)
 			}
 		}(table)
@@ -1055,39 +1090,146 @@ func (m *DatabaseManager) ValidateExternalData(ctx context.Context, database str
 	return nil
 }
 
+// ParquetFile is a local parquet file to load into an external table.
+type ParquetFile struct {
+	Path string
+	// NetworkColumn is the column holding the network name (e.g. meta_network_name).
+	NetworkColumn string
+	// NetworkMode controls how rows from other networks are handled: filter (default) drops
+	// them, rewrite sets the network column to the test network.
+	NetworkMode string
+}
+
+// parquetNetworkSummary is the pre-load network breakdown of a parquet file.
+type parquetNetworkSummary struct {
+	table    string
+	mode     string
+	total    uint64
+	matching uint64
+	networks []string
+}
+
+// tableSchema is the sorting key and columns of a table.
+type tableSchema struct {
+	Database   string
+	Table      string
+	SortingKey []string
+	Columns    []schemaColumn
+}
+
+// DescribeModelTable reads the schema of a model's local table: transformations from the
+// CBT template database, external models from the xatu default database.
+func (m *DatabaseManager) DescribeModelTable(ctx context.Context, model string, external bool) (*tableSchema, error) {
+	conn, database := m.cbtConn, m.cbtTemplateDB
+	if external {
+		conn, database = m.xatuConn, config.DefaultDatabase
+	}
+
+	schema := &tableSchema{Database: database, Table: model + config.ClickHouseLocalSuffix}
+
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	var sortingKey string
+
+	err := conn.QueryRowContext(queryCtx,
+		"SELECT sorting_key FROM system.tables WHERE database = ? AND name = ?",
+		schema.Database, schema.Table,
+	).Scan(&sortingKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("table %s.%s not found (run a test or migrations first): %w", schema.Database, schema.Table, err)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading sorting key of %s.%s: %w", schema.Database, schema.Table, err)
+	}
+
+	schema.SortingKey = splitSortingKey(sortingKey)
+
+	rows, err := conn.QueryContext(queryCtx,
+		"SELECT name, type FROM system.columns WHERE database = ? AND table = ? ORDER BY position",
+		schema.Database, schema.Table,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading columns of %s.%s: %w", schema.Database, schema.Table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var col schemaColumn
+		if err := rows.Scan(&col.Name, &col.Type); err != nil {
+			return nil, fmt.Errorf("scanning column: %w", err)
+		}
+
+		schema.Columns = append(schema.Columns, col)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading columns of %s.%s: %w", schema.Database, schema.Table, err)
+	}
+
+	return schema, nil
+}
+
+// splitSortingKey splits a sorting key expression on top-level commas.
+func splitSortingKey(key string) []string {
+	if strings.TrimSpace(key) == "" {
+		return nil
+	}
+
+	_, parts := splitType("key(" + key + ")")
+
+	return parts
+}
+
 // LoadParquetData loads parquet files into the specified database in xatu cluster.
-func (m *DatabaseManager) LoadParquetData(ctx context.Context, database string, dataFiles map[string]string) error {
+// Rows are filtered on (or rewritten to) the test network via each file's network column.
+func (m *DatabaseManager) LoadParquetData(
+	ctx context.Context,
+	database, network string,
+	dataFiles map[string]*ParquetFile,
+) error {
 	logCtx := m.log.WithFields(logrus.Fields{
 		"cluster":  "xatu",
 		"database": database,
+		"network":  network,
 	})
 
 	start := time.Now()
 
 	type job struct {
 		tableName string
-		filePath  string
+		file      *ParquetFile
 	}
 
-	jobs := make(chan job, len(dataFiles))
-	errChan := make(chan error, len(dataFiles))
-	var wg sync.WaitGroup
+	var (
+		jobs      = make(chan job, len(dataFiles))
+		errChan   = make(chan error, len(dataFiles))
+		summaries = make([]*parquetNetworkSummary, 0, len(dataFiles))
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+	)
 
 	for i := 0; i < m.config.MaxParquetLoadWorkers; i++ {
 		wg.Add(1)
 		go func(db string) {
 			defer wg.Done()
 			for j := range jobs {
-				if err := m.loadParquetFile(ctx, db, j.tableName, j.filePath); err != nil {
+				summary, err := m.loadParquetFile(ctx, db, network, j.tableName, j.file)
+				if err != nil {
 					errChan <- fmt.Errorf("loading %s: %w", j.tableName, err)
 					return
 				}
+
+				mu.Lock()
+				summaries = append(summaries, summary)
+				mu.Unlock()
 			}
 		}(database)
 	}
 
-	for tableName, filePath := range dataFiles {
-		jobs <- job{tableName: tableName, filePath: filePath}
+	for tableName, file := range dataFiles {
+		jobs <- job{tableName: tableName, file: file}
 	}
 	close(jobs)
 
@@ -1098,6 +1240,8 @@ func (m *DatabaseManager) LoadParquetData(ctx context.Context, database string,
 		return err
 	}
 
+	m.logNetworkSummary(logCtx, network, summaries)
+
 	logCtx.WithFields(logrus.Fields{
 		"files":    len(dataFiles),
 		"duration": time.Since(start),
@@ -1107,8 +1251,23 @@ func (m *DatabaseManager) LoadParquetData(ctx context.Context, database string,
 }
 
 // loadParquetFile loads a single parquet file into a table
-func (m *DatabaseManager) loadParquetFile(ctx context.Context, database, tableName, filePath string) error {
+func (m *DatabaseManager) loadParquetFile(
+	ctx context.Context,
+	database, network, tableName string,
+	file *ParquetFile,
+) (*parquetNetworkSummary, error) {
+	summary, err := m.summarizeParquetNetworks(ctx, network, tableName, file)
+	if err != nil {
+		return nil, err
+	}
+
 	localTableName := tableName + "_local"
+
+	plan, err := m.planParquetLoad(ctx, database, localTableName, tableName, file)
+	if err != nil {
+		return nil, err
+	}
+
 	// canonical_execution_traces carries large blob columns (action_init/action_input); a
 	// single read block briefly needs ~2GB on the read stage, which tripped the old 2GB
 	// max_memory_usage (code 241). Raising the ceiling to 3GB fixes that on its own. Keep the
@@ -1119,24 +1278,202 @@ func (m *DatabaseManager) loadParquetFile(ctx context.Context, database, tableNa
 	// - input_format_parquet_max_block_size: read parquet in 8k row chunks
 	// - max_memory_usage: 3GB ceiling (was 2GB)
 	insertSQL := fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled identifiers and file path
-		`INSERT INTO "%s"."%s"
-		 SELECT * FROM file('%s', Parquet)
+		`INSERT INTO "%s"."%s" (%s)
+		 %s
 		 SETTINGS
 		   max_insert_block_size = 10000,
 		   min_insert_block_size_bytes = 10485760,
 		   input_format_parquet_max_block_size = 8192,
 		   max_memory_usage = 3000000000`,
-		database, localTableName, filePath,
+		database, localTableName, quoteIdentifiers(plan.Columns), buildParquetSelect(network, file, plan.Columns),
 	)
 
 	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
 	defer cancel()
 
 	if _, err := m.xatuConn.ExecContext(queryCtx, insertSQL); err != nil {
-		return fmt.Errorf("inserting from parquet: %w", err)
+		return nil, fmt.Errorf("inserting from parquet: %w", err)
 	}
 
-	return nil
+	return summary, nil
+}
+
+// planParquetLoad compares the parquet schema with the target table so columns are
+// loaded by name: missing columns get table defaults and extra columns are dropped.
+func (m *DatabaseManager) planParquetLoad(
+	ctx context.Context,
+	database, localTableName, tableName string,
+	file *ParquetFile,
+) (*parquetInsertPlan, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	fileColumns, err := m.querySchemaColumns(queryCtx, fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled file path
+		"SELECT name, type FROM (DESCRIBE TABLE file('%s', Parquet))", file.Path,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("reading parquet schema: %w", err)
+	}
+
+	// MATERIALIZED, ALIAS and EPHEMERAL columns cannot be inserted into.
+	tableColumns, err := m.querySchemaColumns(queryCtx, fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled identifiers
+		`SELECT name, type FROM system.columns
+		 WHERE database = '%s' AND table = '%s' AND default_kind NOT IN ('MATERIALIZED', 'ALIAS', 'EPHEMERAL')
+		 ORDER BY position`,
+		database, localTableName,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("reading table schema: %w", err)
+	}
+
+	plan, err := planParquetInsert(tableName, fileColumns, tableColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plan.Extra) > 0 {
+		m.log.WithFields(logrus.Fields{
+			"table":   tableName,
+			"columns": strings.Join(plan.Extra, ","),
+		}).Warn("dropping parquet columns that do not exist in the table")
+	}
+
+	if len(plan.Missing) > 0 {
+		m.log.WithFields(logrus.Fields{
+			"table":   tableName,
+			"columns": strings.Join(plan.Missing, ","),
+		}).Debug("table columns missing from parquet, using defaults")
+	}
+
+	return plan, nil
+}
+
+// querySchemaColumns runs a query returning (name, type) rows on the xatu cluster.
+func (m *DatabaseManager) querySchemaColumns(ctx context.Context, query string) ([]schemaColumn, error) {
+	rows, err := m.xatuConn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns := make([]schemaColumn, 0)
+
+	for rows.Next() {
+		var col schemaColumn
+		if err := rows.Scan(&col.Name, &col.Type); err != nil {
+			return nil, fmt.Errorf("scanning column: %w", err)
+		}
+
+		columns = append(columns, col)
+	}
+
+	return columns, rows.Err()
+}
+
+// summarizeParquetNetworks counts the rows in a parquet file that belong to the test network
+// before it is loaded, so fixtures exported from the wrong or a mixed network are visible early.
+func (m *DatabaseManager) summarizeParquetNetworks(
+	ctx context.Context,
+	network, tableName string,
+	file *ParquetFile,
+) (*parquetNetworkSummary, error) {
+	summary := &parquetNetworkSummary{table: tableName, mode: file.NetworkMode}
+
+	query := fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled identifiers and file path
+		`SELECT count(), countIf(toString("%s") = '%s'), groupUniqArray(10)(toString("%s"))
+		 FROM file('%s', Parquet)`,
+		file.NetworkColumn, escapeSQLString(network), file.NetworkColumn, file.Path,
+	)
+
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	if err := m.xatuConn.QueryRowContext(queryCtx, query).Scan(
+		&summary.total, &summary.matching, &summary.networks,
+	); err != nil {
+		return nil, fmt.Errorf("reading network column %q from parquet: %w", file.NetworkColumn, err)
+	}
+
+	return summary, nil
+}
+
+// logNetworkSummary reports rows dropped or rewritten by the network filter.
+func (m *DatabaseManager) logNetworkSummary(
+	logCtx logrus.FieldLogger,
+	network string,
+	summaries []*parquetNetworkSummary,
+) {
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].table < summaries[j].table
+	})
+
+	for _, summary := range summaries {
+		other := summary.total - summary.matching
+
+		fields := logrus.Fields{
+			"table":    summary.table,
+			"mode":     summary.mode,
+			"rows":     summary.total,
+			"matching": summary.matching,
+		}
+
+		if other == 0 {
+			logCtx.WithFields(fields).Debug("parquet rows match test network")
+
+			continue
+		}
+
+		fields["networks"] = strings.Join(summary.networks, ",")
+
+		if summary.mode == testdef.NetworkModeRewrite {
+			fields["rewritten"] = other
+			logCtx.WithFields(fields).Info("rewrote parquet rows from other networks to " + network)
+
+			continue
+		}
+
+		fields["dropped"] = other
+		logCtx.WithFields(fields).Warn("dropped parquet rows from other networks")
+	}
+}
+
+// buildParquetSelect builds the SELECT that reads the given parquet columns for the test network.
+func buildParquetSelect(network string, file *ParquetFile, columns []string) string {
+	if file.NetworkMode == testdef.NetworkModeRewrite {
+		selected := make([]string, 0, len(columns))
+
+		for _, col := range columns {
+			if col == file.NetworkColumn {
+				selected = append(selected, fmt.Sprintf(`'%s' AS "%s"`, escapeSQLString(network), col))
+
+				continue
+			}
+
+			selected = append(selected, quoteIdentifiers([]string{col}))
+		}
+
+		return fmt.Sprintf(`SELECT %s FROM file('%s', Parquet)`, strings.Join(selected, ", "), file.Path)
+	}
+
+	return fmt.Sprintf(
+		`SELECT %s FROM file('%s', Parquet) WHERE toString("%s") = '%s'`,
+		quoteIdentifiers(columns), file.Path, file.NetworkColumn, escapeSQLString(network),
+	)
+}
+
+// quoteIdentifiers renders column names as a comma-separated list of quoted identifiers.
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, `"`+strings.ReplaceAll(name, `"`, `\"`)+`"`)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// escapeSQLString escapes a value for use in a single-quoted ClickHouse string literal.
+func escapeSQLString(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
 }
 
 // generateDatabaseName creates a unique database name for a test
@@ -61,3 +61,32 @@ func TestScopedDSN(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildParquetSelect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		file *ParquetFile
+		want string
+	}{
+		{
+			name: "filter",
+			file: &ParquetFile{Path: "/tmp/a", NetworkColumn: "meta_network_name", NetworkMode: "filter"},
+			want: `SELECT "slot", "meta_network_name" FROM file('/tmp/a', Parquet) WHERE toString("meta_network_name") = 'main\'net'`,
+		},
+		{
+			name: "rewrite",
+			file: &ParquetFile{Path: "/tmp/a", NetworkColumn: "meta_network_name", NetworkMode: "rewrite"},
+			want: `SELECT "slot", 'main\'net' AS "meta_network_name" FROM file('/tmp/a', Parquet)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, buildParquetSelect("main'net", tt.file, []string{"slot", "meta_network_name"}))
+		})
+	}
+}
@@ -0,0 +1,631 @@
+package testing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/config"
+	"github.com/ethpandaops/xatu-cbt/internal/testing/output"
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+var errInvalidAllowColumn = errors.New("allowed column must be <column> or <table>.<column>")
+
+// DiffBase is the other side of a differential run: a checkout of a base git ref.
+type DiffBase struct {
+	Ref          string
+	ModelCache   *ModelCache // Models of the base ref
+	ModelsDir    string      // models/ of the base ref
+	MigrationDir string      // migrations/ of the base ref
+}
+
+// ColumnAllowList holds columns whose differences are expected between two refs.
+// Entries are a bare column name, allowed in every table, or table.column.
+type ColumnAllowList struct {
+	columns map[string]bool
+}
+
+// ParseAllowColumns parses a comma-separated list of columns, e.g.
+// "updated_date_time,fct_block.block_total_bytes".
+func ParseAllowColumns(spec string) (*ColumnAllowList, error) {
+	allow := &ColumnAllowList{columns: make(map[string]bool)}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ".")
+		if len(parts) > 2 || slices.Contains(parts, "") {
+			return nil, fmt.Errorf("%w: %q", errInvalidAllowColumn, entry)
+		}
+
+		allow.columns[entry] = true
+	}
+
+	return allow, nil
+}
+
+// Allowed reports whether differences in table.column are expected.
+func (a *ColumnAllowList) Allowed(table, column string) bool {
+	if a == nil {
+		return false
+	}
+
+	return a.columns[column] || a.columns[table+"."+column]
+}
+
+// TableDiff is the comparison of one table between the base and head versions.
+type TableDiff struct {
+	Table          string
+	BaseRows       uint64
+	HeadRows       uint64
+	OnlyBase       uint64   // Rows produced only by the base version
+	OnlyHead       uint64   // Rows produced only by the head version
+	SchemaChanges  []string // Added, removed and retyped columns, or a table only in one version
+	ChangedColumns []string // Columns present in both versions whose values differ
+}
+
+// Identical reports whether both versions produced the same rows, ignoring allowed columns.
+func (d *TableDiff) Identical() bool {
+	return len(d.SchemaChanges) == 0 && len(d.ChangedColumns) == 0 &&
+		d.OnlyBase == 0 && d.OnlyHead == 0 && d.BaseRows == d.HeadRows
+}
+
+// Details describes the schema and column differences for output.
+func (d *TableDiff) Details() []string {
+	details := slices.Clone(d.SchemaChanges)
+	if len(d.ChangedColumns) > 0 {
+		details = append(details, "changed: "+strings.Join(d.ChangedColumns, ", "))
+	}
+
+	return details
+}
+
+// DiffResult contains the table comparisons for a single model or model scenario.
+type DiffResult struct {
+	Model    string
+	Scenario string
+	Tables   []*TableDiff
+	Error    error
+}
+
+// Name identifies the test in output: the model, or model[scenario] for a scenario.
+func (r *DiffResult) Name() string {
+	return testdef.TestID(r.Model, r.Scenario)
+}
+
+// Identical reports whether every table is identical in both versions.
+func (r *DiffResult) Identical() bool {
+	if r.Error != nil {
+		return false
+	}
+
+	for _, table := range r.Tables {
+		if !table.Identical() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// diffSchemas compares the columns of a table in both versions. It returns the columns
+// to compare values of, in head order, and descriptions of the schema changes. Allowed
+// columns and fingerprintIgnoredColumns are skipped.
+func diffSchemas(table string, base, head []schemaColumn, allow *ColumnAllowList) (compared, changes []string) {
+	skip := func(column string) bool {
+		return allow.Allowed(table, column) || slices.Contains(fingerprintIgnoredColumns, column)
+	}
+
+	baseTypes := make(map[string]string, len(base))
+	for _, col := range base {
+		baseTypes[col.Name] = col.Type
+	}
+
+	headNames := make(map[string]bool, len(head))
+
+	for _, col := range head {
+		headNames[col.Name] = true
+
+		if skip(col.Name) {
+			continue
+		}
+
+		baseType, ok := baseTypes[col.Name]
+
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("added column %s %s", col.Name, col.Type))
+		case baseType != col.Type:
+			changes = append(changes, fmt.Sprintf("column %s %s → %s", col.Name, baseType, col.Type))
+		default:
+			compared = append(compared, col.Name)
+		}
+	}
+
+	for _, col := range base {
+		if !headNames[col.Name] && !skip(col.Name) {
+			changes = append(changes, fmt.Sprintf("removed column %s %s", col.Name, col.Type))
+		}
+	}
+
+	return compared, changes
+}
+
+// DescribeColumns returns the columns of a CBT cluster table in order.
+func (m *DatabaseManager) DescribeColumns(ctx context.Context, database, table string) ([]schemaColumn, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.cbtConn.QueryContext(queryCtx,
+		"SELECT name, type FROM system.columns WHERE database = ? AND table = ? ORDER BY position",
+		database, table,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading columns of %s.%s: %w", database, table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns := make([]schemaColumn, 0)
+
+	for rows.Next() {
+		var col schemaColumn
+		if err := rows.Scan(&col.Name, &col.Type); err != nil {
+			return nil, fmt.Errorf("scanning column: %w", err)
+		}
+
+		columns = append(columns, col)
+	}
+
+	return columns, rows.Err()
+}
+
+// ColumnChecksums counts a table's rows under FINAL and returns an order-independent
+// checksum per column, so differing columns can be named without a join key.
+func (m *DatabaseManager) ColumnChecksums(ctx context.Context, database, table string, columns []string) (uint64, []uint64, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	sums := make([]string, 0, len(columns))
+	for _, column := range columns {
+		sums = append(sums, fmt.Sprintf("sum(cityHash64(`%s`))", column))
+	}
+
+	query := fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled identifiers
+		"SELECT %s FROM `%s`.`%s` FINAL",
+		strings.Join(append([]string{"count()"}, sums...), ", "), database, table,
+	)
+
+	var rowCount uint64
+
+	checksums := make([]uint64, len(columns))
+	dest := make([]any, 0, len(columns)+1)
+	dest = append(dest, &rowCount)
+
+	for i := range checksums {
+		dest = append(dest, &checksums[i])
+	}
+
+	if err := m.cbtConn.QueryRowContext(queryCtx, query).Scan(dest...); err != nil {
+		return 0, nil, fmt.Errorf("checksumming %s.%s: %w", database, table, err)
+	}
+
+	return rowCount, checksums, nil
+}
+
+// DiffModels runs the fixtures of each model test through the head models and the models of
+// a base ref, in separate CBT databases reading the same external data, and compares every
+// transformation table. Differences in allowed columns are ignored.
+func (o *Orchestrator) DiffModels(
+	ctx context.Context,
+	network string,
+	modelNames []string,
+	base *DiffBase,
+	allow *ColumnAllowList,
+	concurrency int,
+) ([]*DiffResult, error) {
+	start := time.Now()
+
+	o.log.WithFields(logrus.Fields{
+		"network": network,
+		"models":  modelNames,
+		"base":    base.Ref,
+	}).Info("diffing models against base ref")
+
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	testConfigs := make([]*testdef.TestDefinition, 0, len(modelNames))
+	for _, modelName := range modelNames {
+		testConfig, err := o.configLoader.LoadForModel(network, modelName)
+		if err != nil {
+			return nil, fmt.Errorf("loading test config for %s: %w", modelName, err)
+		}
+
+		testConfigs = append(testConfigs, testConfig)
+	}
+
+	tests, err := o.expandScenarios(testConfigs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.ensureTemplatesPrepared(ctx, network); err != nil {
+		return nil, fmt.Errorf("preparing templates: %w", err)
+	}
+
+	baseDBManager := o.dbManager.WithCBTTemplate(config.CBTBaseTemplateDatabase)
+	if err := baseDBManager.CreateCBTTemplate(ctx, base.MigrationDir); err != nil {
+		return nil, fmt.Errorf("preparing base template: %w", err)
+	}
+
+	baseEngine := o.cbtEngine.ForModels(base.ModelCache, base.ModelsDir)
+	defer func() {
+		if stopErr := baseEngine.Stop(); stopErr != nil {
+			o.log.WithError(stopErr).Warn("failed to stop base cbt engine")
+		}
+	}()
+
+	results := make([]*DiffResult, len(tests))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+
+	for i, test := range tests {
+		wg.Add(1)
+
+		go func(i int, test *testdef.TestDefinition) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = o.diffTest(ctx, network, test, base, baseDBManager, baseEngine, allow)
+		}(i, test)
+	}
+
+	wg.Wait()
+
+	o.log.WithFields(logrus.Fields{
+		"tests":    len(results),
+		"duration": time.Since(start),
+	}).Info("all diffs completed")
+
+	o.formatter.PrintDiffResults(diffMetrics(results))
+
+	return results, nil
+}
+
+// diffTest runs one test through both versions and compares their transformation tables.
+func (o *Orchestrator) diffTest(
+	ctx context.Context,
+	network string,
+	testConfig *testdef.TestDefinition,
+	base *DiffBase,
+	baseDBManager *DatabaseManager,
+	baseEngine *CBTEngine,
+	allow *ColumnAllowList,
+) *DiffResult {
+	result := &DiffResult{Model: testConfig.Model, Scenario: testConfig.Scenario}
+
+	logCtx := o.log.WithField("model", testConfig.ID())
+
+	headDeps, err := o.modelCache.ResolveTestDependencies(testConfig)
+	if err != nil {
+		result.Error = fmt.Errorf("resolving head dependencies: %w", err)
+		return result
+	}
+
+	baseDeps, err := base.ModelCache.ResolveTestDependencies(testConfig)
+	if err != nil {
+		result.Error = fmt.Errorf("resolving base dependencies: %w", err)
+		return result
+	}
+
+	// Both versions read one external database holding the fixtures either version needs.
+	fixtures := mergeFixtureDependencies(headDeps, baseDeps)
+
+	extDB, err := o.dbManager.CloneExternalDatabase(ctx, o.generateTestID(), o.externalTableRefs(testConfig, fixtures))
+	if err != nil {
+		result.Error = fmt.Errorf("cloning ext DB: %w", err)
+		return result
+	}
+
+	headDB, err := o.dbManager.CloneCBTDatabase(ctx, o.generateTestID(), extractCloneTableNames(headDeps.TransformationModels))
+	if err != nil {
+		o.dropDiffDatabases(ctx, logCtx, extDB, "", "")
+
+		result.Error = fmt.Errorf("cloning head cbt DB: %w", err)
+
+		return result
+	}
+
+	baseDB, err := baseDBManager.CloneCBTDatabase(ctx, o.generateTestID(), extractCloneTableNames(baseDeps.TransformationModels))
+	if err != nil {
+		o.dropDiffDatabases(ctx, logCtx, extDB, headDB, "")
+
+		result.Error = fmt.Errorf("cloning base cbt DB: %w", err)
+
+		return result
+	}
+
+	defer o.dropDiffDatabases(ctx, logCtx, extDB, headDB, baseDB)
+
+	// CBT logs are only kept for test runs; release the ones captured here.
+	defer func() {
+		_ = o.cbtEngine.TakeCBTLog(headDB)
+		_ = baseEngine.TakeCBTLog(baseDB)
+	}()
+
+	if err := o.loadTestData(ctx, network, testConfig, extDB, fixtures); err != nil {
+		result.Error = err
+		return result
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		allModels, transformationNames := transformationRunModels(headDeps)
+		if runErr := o.cbtEngine.RunTransformations(gctx, network, headDB, extDB, allModels, transformationNames); runErr != nil {
+			return fmt.Errorf("running head transformations: %w", runErr)
+		}
+
+		return nil
+	})
+
+	g.Go(func() error {
+		allModels, transformationNames := transformationRunModels(baseDeps)
+		if runErr := baseEngine.RunTransformations(gctx, network, baseDB, extDB, allModels, transformationNames); runErr != nil {
+			return fmt.Errorf("running base transformations: %w", runErr)
+		}
+
+		return nil
+	})
+
+	if err := g.Wait(); err != nil {
+		result.Error = err
+		return result
+	}
+
+	headTables := extractModelNames(headDeps.TransformationModels)
+	baseTables := extractModelNames(baseDeps.TransformationModels)
+
+	for _, table := range sortedUnion(headTables, baseTables) {
+		switch {
+		case !slices.Contains(baseTables, table):
+			result.Tables = append(result.Tables, &TableDiff{Table: table, SchemaChanges: []string{"table only in head"}})
+		case !slices.Contains(headTables, table):
+			result.Tables = append(result.Tables, &TableDiff{Table: table, SchemaChanges: []string{"table only in base"}})
+		default:
+			diff, diffErr := o.compareTable(ctx, baseDB, headDB, table, allow)
+			if diffErr != nil {
+				result.Error = diffErr
+				return result
+			}
+
+			result.Tables = append(result.Tables, diff)
+		}
+	}
+
+	logCtx.WithField("identical", result.Identical()).Info("diff completed")
+
+	return result
+}
+
+// compareTable compares a table produced by both versions: row counts, rows found in only
+// one version and per-column checksums over the columns both versions share.
+func (o *Orchestrator) compareTable(ctx context.Context, baseDB, headDB, table string, allow *ColumnAllowList) (*TableDiff, error) {
+	baseColumns, err := o.dbManager.DescribeColumns(ctx, baseDB, table)
+	if err != nil {
+		return nil, err
+	}
+
+	headColumns, err := o.dbManager.DescribeColumns(ctx, headDB, table)
+	if err != nil {
+		return nil, err
+	}
+
+	compared, changes := diffSchemas(table, baseColumns, headColumns, allow)
+	diff := &TableDiff{Table: table, SchemaChanges: changes}
+
+	if len(compared) == 0 {
+		return diff, nil
+	}
+
+	baseRows, baseChecksums, err := o.dbManager.ColumnChecksums(ctx, baseDB, table, compared)
+	if err != nil {
+		return nil, err
+	}
+
+	headRows, headChecksums, err := o.dbManager.ColumnChecksums(ctx, headDB, table, compared)
+	if err != nil {
+		return nil, err
+	}
+
+	diff.BaseRows, diff.HeadRows = baseRows, headRows
+
+	for i, column := range compared {
+		if baseChecksums[i] != headChecksums[i] {
+			diff.ChangedColumns = append(diff.ChangedColumns, column)
+		}
+	}
+
+	if len(diff.ChangedColumns) == 0 && baseRows == headRows {
+		return diff, nil
+	}
+
+	if diff.OnlyBase, err = o.dbManager.CountRowsMissing(ctx, baseDB, headDB, table, compared); err != nil {
+		return nil, err
+	}
+
+	if diff.OnlyHead, err = o.dbManager.CountRowsMissing(ctx, headDB, baseDB, table, compared); err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+// dropDiffDatabases drops the databases of a diff unless test databases are kept.
+func (o *Orchestrator) dropDiffDatabases(ctx context.Context, logCtx logrus.FieldLogger, extDB, headDB, baseDB string) {
+	if !o.cleanupTestDB {
+		logCtx.WithFields(logrus.Fields{
+			"ext_db":  extDB,
+			"head_db": headDB,
+			"base_db": baseDB,
+		}).Info("keeping diff databases")
+
+		return
+	}
+
+	if extDB != "" {
+		if err := o.dbManager.DropExternalDatabase(ctx, extDB); err != nil {
+			logCtx.WithError(err).Warn("failed to drop external database")
+		}
+	}
+
+	for _, db := range []string{headDB, baseDB} {
+		if db == "" {
+			continue
+		}
+
+		if err := o.dbManager.DropCBTDatabase(ctx, db); err != nil {
+			logCtx.WithError(err).WithField("database", db).Warn("failed to drop CBT database")
+		}
+	}
+}
+
+// mergeFixtureDependencies returns dependencies holding the external tables and parquet
+// URLs of both versions, for loading one external database both versions can read.
+func mergeFixtureDependencies(head, base *Dependencies) *Dependencies {
+	refs := make(map[string]ExternalTableRef, len(head.ExternalTableRefs)+len(base.ExternalTableRefs))
+	parquetURLs := make(map[string]string, len(head.ParquetURLs)+len(base.ParquetURLs))
+
+	for _, deps := range []*Dependencies{base, head} {
+		for _, ref := range deps.ExternalTableRefs {
+			refs[ref.ModelName] = ref
+		}
+
+		for table, url := range deps.ParquetURLs {
+			parquetURLs[table] = url
+		}
+	}
+
+	merged := &Dependencies{
+		ExternalTables:    sortedUnion(head.ExternalTables, base.ExternalTables),
+		ExternalTableRefs: make([]ExternalTableRef, 0, len(refs)),
+		ParquetURLs:       parquetURLs,
+	}
+
+	for _, table := range sortedKeys(refs) {
+		merged.ExternalTableRefs = append(merged.ExternalTableRefs, refs[table])
+	}
+
+	return merged
+}
+
+// sortedUnion returns the distinct values of both slices, sorted.
+func sortedUnion(a, b []string) []string {
+	union := slices.Concat(a, b)
+	sort.Strings(union)
+
+	return slices.Compact(union)
+}
+
+// sortedKeys returns the keys of a map, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// diffMetrics converts diff results to output rows, one per table or per failed test.
+func diffMetrics(results []*DiffResult) []output.TableDiffMetric {
+	metrics := make([]output.TableDiffMetric, 0, len(results))
+
+	for _, result := range results {
+		if result.Error != nil {
+			metrics = append(metrics, output.TableDiffMetric{Test: result.Name(), Error: result.Error.Error()})
+
+			continue
+		}
+
+		for _, table := range result.Tables {
+			metrics = append(metrics, output.TableDiffMetric{
+				Test:      result.Name(),
+				Table:     table.Table,
+				Identical: table.Identical(),
+				BaseRows:  table.BaseRows,
+				HeadRows:  table.HeadRows,
+				OnlyBase:  table.OnlyBase,
+				OnlyHead:  table.OnlyHead,
+				Details:   table.Details(),
+			})
+		}
+	}
+
+	return metrics
+}
+
+// CheckoutRef checks out a git ref of the repository at repoDir into a temporary worktree
+// and returns its path with a function that removes it again.
+func CheckoutRef(ctx context.Context, log logrus.FieldLogger, repoDir, ref string) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "xatu-cbt-diff-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("creating worktree directory: %w", err)
+	}
+
+	git := func(ctx context.Context, args ...string) error {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repoDir}, args...)...) //nolint:gosec // G204: Git command with controlled arguments
+
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		if runErr := cmd.Run(); runErr != nil {
+			return fmt.Errorf("git %s: %w (stderr: %s)", strings.Join(args, " "), runErr, strings.TrimSpace(stderr.String()))
+		}
+
+		return nil
+	}
+
+	if err := git(ctx, "worktree", "add", "--detach", dir, ref); err != nil {
+		_ = os.RemoveAll(dir)
+
+		return "", nil, fmt.Errorf("checking out %s: %w", ref, err)
+	}
+
+	// Cleanup runs after the run's context may have expired.
+	cleanup := func() {
+		if removeErr := git(context.Background(), "worktree", "remove", "--force", dir); removeErr != nil {
+			log.WithError(removeErr).WithField("worktree", dir).Warn("failed to remove worktree")
+
+			_ = os.RemoveAll(dir)
+			_ = git(context.Background(), "worktree", "prune")
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"ref":      ref,
+		"worktree": dir,
+	}).Info("checked out base ref")
+
+	return dir, cleanup, nil
+}
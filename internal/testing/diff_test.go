@@ -0,0 +1,148 @@
+package testing
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowColumns(t *testing.T) {
+	t.Parallel()
+
+	allow, err := ParseAllowColumns("block_total_bytes, fct_block.slot_start_date_time,")
+	require.NoError(t, err)
+
+	require.True(t, allow.Allowed("fct_block", "block_total_bytes"))
+	require.True(t, allow.Allowed("fct_block_head", "block_total_bytes"))
+	require.True(t, allow.Allowed("fct_block", "slot_start_date_time"))
+	require.False(t, allow.Allowed("fct_block_head", "slot_start_date_time"))
+	require.False(t, (*ColumnAllowList)(nil).Allowed("fct_block", "slot"))
+
+	for _, spec := range []string{"fct_block.", "a.b.c", ".slot"} {
+		_, err := ParseAllowColumns(spec)
+		require.ErrorIs(t, err, errInvalidAllowColumn, spec)
+	}
+}
+
+func TestDiffSchemas(t *testing.T) {
+	t.Parallel()
+
+	base := []schemaColumn{
+		{Name: "updated_date_time", Type: "DateTime"},
+		{Name: "slot", Type: "UInt32"},
+		{Name: "block_root", Type: "String"},
+		{Name: "proposer_index", Type: "UInt32"},
+		{Name: "legacy", Type: "String"},
+	}
+	head := []schemaColumn{
+		{Name: "updated_date_time", Type: "DateTime64(3)"},
+		{Name: "slot", Type: "UInt32"},
+		{Name: "block_root", Type: "FixedString(66)"},
+		{Name: "proposer_index", Type: "UInt32"},
+		{Name: "block_version", Type: "LowCardinality(String)"},
+		{Name: "blob_count", Type: "UInt8"},
+	}
+
+	allow, err := ParseAllowColumns("fct_block.blob_count")
+	require.NoError(t, err)
+
+	compared, changes := diffSchemas("fct_block", base, head, allow)
+	require.Equal(t, []string{"slot", "proposer_index"}, compared)
+	require.Equal(t, []string{
+		"column block_root String → FixedString(66)",
+		"added column block_version LowCardinality(String)",
+		"removed column legacy String",
+	}, changes)
+}
+
+func TestDiffResultIdentical(t *testing.T) {
+	t.Parallel()
+
+	same := &TableDiff{Table: "fct_block", BaseRows: 10, HeadRows: 10}
+	require.True(t, same.Identical())
+	require.Empty(t, same.Details())
+
+	changed := &TableDiff{
+		Table:          "fct_block",
+		BaseRows:       10,
+		HeadRows:       10,
+		OnlyBase:       2,
+		OnlyHead:       2,
+		SchemaChanges:  []string{"added column blob_count UInt8"},
+		ChangedColumns: []string{"block_root", "proposer_index"},
+	}
+	require.False(t, changed.Identical())
+	require.Equal(t, []string{"added column blob_count UInt8", "changed: block_root, proposer_index"}, changed.Details())
+
+	require.True(t, (&DiffResult{Model: "fct_block", Tables: []*TableDiff{same}}).Identical())
+	require.False(t, (&DiffResult{Model: "fct_block", Tables: []*TableDiff{same, changed}}).Identical())
+	require.False(t, (&DiffResult{Model: "fct_block", Error: errInvalidAllowColumn}).Identical())
+}
+
+func TestMergeFixtureDependencies(t *testing.T) {
+	t.Parallel()
+
+	head := &Dependencies{
+		ExternalTables:    []string{"canonical_beacon_block"},
+		ExternalTableRefs: []ExternalTableRef{{ModelName: "canonical_beacon_block"}},
+		ParquetURLs:       map[string]string{"canonical_beacon_block": "https://example.com/a.parquet"},
+	}
+	base := &Dependencies{
+		ExternalTables: []string{"beacon_api_eth_v1_events_block", "canonical_beacon_block"},
+		ExternalTableRefs: []ExternalTableRef{
+			{ModelName: "canonical_beacon_block"},
+			{ModelName: "beacon_api_eth_v1_events_block"},
+		},
+		ParquetURLs: map[string]string{
+			"canonical_beacon_block":         "https://example.com/a.parquet",
+			"beacon_api_eth_v1_events_block": "https://example.com/b.parquet",
+		},
+	}
+
+	merged := mergeFixtureDependencies(head, base)
+	require.Equal(t, []string{"beacon_api_eth_v1_events_block", "canonical_beacon_block"}, merged.ExternalTables)
+	require.Equal(t, []ExternalTableRef{
+		{ModelName: "beacon_api_eth_v1_events_block"},
+		{ModelName: "canonical_beacon_block"},
+	}, merged.ExternalTableRefs)
+	require.Len(t, merged.ParquetURLs, 2)
+}
+
+func TestCheckoutRef(t *testing.T) {
+	t.Parallel()
+
+	repoDir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	modelPath := filepath.Join(repoDir, "models", "transformations", "fct_block.sql")
+	require.NoError(t, os.MkdirAll(filepath.Dir(modelPath), 0o750))
+	require.NoError(t, os.WriteFile(modelPath, []byte("SELECT 1\n"), 0o600))
+
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+	git("tag", "base")
+
+	require.NoError(t, os.WriteFile(modelPath, []byte("SELECT 2\n"), 0o600))
+
+	dir, cleanup, err := CheckoutRef(context.Background(), logrus.New(), repoDir, "base")
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(dir, "models", "transformations", "fct_block.sql"))
+	require.NoError(t, err)
+	require.Equal(t, "SELECT 1\n", string(content))
+
+	cleanup()
+
+	_, err = os.Stat(dir)
+	require.True(t, os.IsNotExist(err))
+}
@@ -5,21 +5,69 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	_ "github.com/ClickHouse/clickhouse-go/v2" // ClickHouse driver registration
 	"github.com/ethpandaops/xatu-cbt/internal/config"
+	"github.com/ethpandaops/xatu-cbt/internal/runtime"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 )
 
+var errCBTEngineNotStarted = errors.New("cbt engine not started")
+
+// containerCleanupTimeout bounds killing and removing a CBT container, and reading its
+// logs, which also run after the test context is cancelled.
+const containerCleanupTimeout = 30 * time.Second
+
+// TransformationTimeoutError reports transformations CBT did not complete within
+// TestConfig.TransformationWaitTimeout, with the end of the CBT container log.
+type TransformationTimeoutError struct {
+	Database string
+	Timeout  time.Duration
+	// AdminTables is set when CBT never created its admin tables, so no model ran.
+	AdminTables bool
+	Pending     []string
+	// Coverage of the pending incremental models with an expected range.
+	Coverage []ModelCoverage
+	LogTail  []string
+}
+
+func (e *TransformationTimeoutError) Error() string {
+	var b strings.Builder
+
+	if e.AdminTables {
+		fmt.Fprintf(&b, "timeout after %s waiting for CBT admin tables in %s", e.Timeout, e.Database)
+	} else {
+		fmt.Fprintf(&b, "timeout after %s waiting for transformations in %s", e.Timeout, e.Database)
+	}
+
+	fmt.Fprintf(&b, ": %s still pending", strings.Join(e.Pending, ", "))
+
+	if len(e.Coverage) > 0 {
+		b.WriteString("\ncoverage:")
+
+		for _, coverage := range e.Coverage {
+			fmt.Fprintf(&b, "\n  %s", coverage)
+		}
+	}
+
+	if len(e.LogTail) > 0 {
+		fmt.Fprintf(&b, "\nlast %d CBT log lines:\n  %s", len(e.LogTail), strings.Join(e.LogTail, "\n  "))
+	}
+
+	return b.String()
+}
+
 // CBTEngine manages CBT engine lifecycle and transformation execution.
 // This is the concrete implementation without an interface abstraction.
 type CBTEngine struct {
@@ -31,13 +79,25 @@ type CBTEngine struct {
 	config        *TestConfig
 	modelCache    *ModelCache
 
-	// Track running containers for cleanup
-	runningContainers   []string
-	runningContainersMu sync.Mutex
-
-	// Pool of Redis DB numbers (1-15) for isolation between concurrent CBT containers.
-	// Each CBT container gets its own Redis DB to prevent task queue conflicts.
-	redisDBPool chan int
+	// Redis DBs holding CBT task state, leased by Start, and the Docker daemon running CBT
+	// containers, created by Start unless already set. leasedRedisDBs and ownsClients mark
+	// the leases and the client this engine must release, as opposed to shared ones.
+	redisDBs       *redisDBs
+	leasedRedisDBs bool
+	docker         runtime.Docker
+	ownsClients    bool
+
+	// Track running CBT instances for cleanup
+	running   []cbtProcess
+	runningMu sync.Mutex
+
+	// Rendered CBT config of the latest run per database, recorded in run manifests.
+	renderedConfigs   map[string]string
+	renderedConfigsMu sync.Mutex
+
+	// CBT container logs per database, filtered to the models of the runs against it.
+	cbtLogs   map[string]*bytes.Buffer
+	cbtLogsMu sync.Mutex
 }
 
 // Config represents the structure of the CBT YAML configuration file.
@@ -59,8 +119,7 @@ type cbtConfig struct {
 		} `yaml:"admin"`
 	} `yaml:"clickhouse"`
 	Redis struct {
-		URL    string `yaml:"url"`
-		Prefix string `yaml:"prefix"`
+		URL string `yaml:"url"`
 	} `yaml:"redis"`
 	Models struct {
 		External struct {
@@ -89,9 +148,32 @@ type modelOverrides struct {
 		Lag       *int              `yaml:"lag,omitempty"`
 		Schedule  string            `yaml:"schedule,omitempty"`
 		Schedules map[string]string `yaml:"schedules,omitempty"`
+		Interval  *intervalOverride `yaml:"interval,omitempty"`
 	} `yaml:"config"`
 }
 
+// intervalOverride overrides the interval bounds of an incremental model.
+type intervalOverride struct {
+	Max uint64 `yaml:"max"`
+}
+
+// chunkedSchedule is the forwardfill/backfill schedule of incremental models in chunked
+// runs, which process many more intervals than a normal run.
+const chunkedSchedule = "@every 1s"
+
+// runOptions adjusts how a CBT run is configured and when it is considered complete.
+type runOptions struct {
+	// minCoverage waits until each listed model has processed intervals covering at least
+	// this many positions, rather than just one interval.
+	minCoverage map[string]uint64
+	// chunkModel, when set, is the incremental model whose interval.max is lowered to
+	// intervalMax, with a faster schedule. Its dependencies run as configured.
+	chunkModel  string
+	intervalMax uint64
+	// redisDB is the Redis DB taken for the run's container.
+	redisDB int
+}
+
 // NewCBTEngine creates a new CBT engine manager.
 func NewCBTEngine(
 	log logrus.FieldLogger,
@@ -105,16 +187,9 @@ func NewCBTEngine(
 		cfg = DefaultTestConfig()
 	}
 
-	// Create Redis DB pool with numbers 1-15 (max 15 concurrent, DB 0 reserved).
-	// This ensures each concurrent CBT container gets its own Redis DB for task isolation.
-	poolSize := cfg.CBTConcurrency
-	if poolSize > 15 {
-		poolSize = 15 // Redis only has DBs 0-15, reserve 0
-	}
-
-	redisDBPool := make(chan int, poolSize)
-	for i := 1; i <= poolSize; i++ {
-		redisDBPool <- i
+	slots := cfg.CBTConcurrency
+	if slots < 1 {
+		slots = 1
 	}
 
 	return &CBTEngine{
@@ -125,13 +200,141 @@ func NewCBTEngine(
 		log:           log.WithField("component", "cbt_engine"),
 		config:        cfg,
 		modelCache:    modelCache,
-		redisDBPool:   redisDBPool,
+		redisDBs:      newRedisDBs(redisURL, slots),
+
+		renderedConfigs: make(map[string]string),
+		cbtLogs:         make(map[string]*bytes.Buffer),
+	}
+}
+
+// ForModels returns an engine that runs another set of models, such as a base git ref's,
+// with the same settings. It shares this engine's Redis DBs, so both engines together stay
+// within the configured concurrency, and its Docker client, so this engine
+// must be started first. The returned engine must be stopped separately.
+func (e *CBTEngine) ForModels(modelCache *ModelCache, modelsDir string) *CBTEngine {
+	return &CBTEngine{
+		clickhouseURL: e.clickhouseURL,
+		redisURL:      e.redisURL,
+		docker:        e.docker,
+		modelsDir:     modelsDir,
+		externalDir:   filepath.Join(modelsDir, "external"),
+		log:           e.log.WithField("models_dir", modelsDir),
+		config:        e.config,
+		modelCache:    modelCache,
+		redisDBs:      e.redisDBs,
+
+		renderedConfigs: make(map[string]string),
+		cbtLogs:         make(map[string]*bytes.Buffer),
 	}
 }
 
-// Start initializes the CBT engine.
-func (e *CBTEngine) Start(_ context.Context) error {
-	e.log.Debug("starting cbt engine")
+// RenderedConfig returns the CBT config of the latest run against dbName, if any.
+func (e *CBTEngine) RenderedConfig(dbName string) string {
+	e.renderedConfigsMu.Lock()
+	defer e.renderedConfigsMu.Unlock()
+
+	return e.renderedConfigs[dbName]
+}
+
+// TakeCBTLog returns the CBT container logs of the runs against dbName, filtered to the
+// models in play and followed by each run's coverage report, and forgets them. It returns
+// an empty string if nothing was captured. Callers of RunTransformations and its variants
+// must take the logs of the database once done with it, or they are kept for the run.
+func (e *CBTEngine) TakeCBTLog(dbName string) string {
+	e.cbtLogsMu.Lock()
+	defer e.cbtLogsMu.Unlock()
+
+	logs, ok := e.cbtLogs[dbName]
+	if !ok {
+		return ""
+	}
+
+	delete(e.cbtLogs, dbName)
+
+	return logs.String()
+}
+
+// recordCBTLog appends the filtered logs of a CBT run against dbName.
+func (e *CBTEngine) recordCBTLog(dbName, containerName string, logs []byte, models []string) {
+	e.cbtLogsMu.Lock()
+	defer e.cbtLogsMu.Unlock()
+
+	buf := e.cbtLogBuffer(dbName)
+
+	fmt.Fprintf(buf, "=== %s %s ===\n", containerName, time.Now().Format(time.RFC3339))
+	buf.WriteString(filterCBTLog(logs, models))
+}
+
+// recordCoverage appends the coverage report of a CBT run against dbName to its logs.
+func (e *CBTEngine) recordCoverage(dbName string, coverage []ModelCoverage) {
+	if len(coverage) == 0 {
+		return
+	}
+
+	e.cbtLogsMu.Lock()
+	defer e.cbtLogsMu.Unlock()
+
+	buf := e.cbtLogBuffer(dbName)
+
+	fmt.Fprintf(buf, "=== coverage %s ===\n", time.Now().Format(time.RFC3339))
+
+	for _, modelCoverage := range coverage {
+		buf.WriteString(modelCoverage.String())
+		buf.WriteByte('\n')
+	}
+}
+
+// cbtLogBuffer returns the logs of dbName, creating them if needed. cbtLogsMu must be held.
+func (e *CBTEngine) cbtLogBuffer(dbName string) *bytes.Buffer {
+	buf, ok := e.cbtLogs[dbName]
+	if !ok {
+		buf = &bytes.Buffer{}
+		e.cbtLogs[dbName] = buf
+	}
+
+	return buf
+}
+
+// Start initializes the CBT engine, connecting to Redis and, for the Docker runner, the
+// Docker daemon.
+func (e *CBTEngine) Start(ctx context.Context) error {
+	e.log.WithField("runner", e.config.CBTRunner).Debug("starting cbt engine")
+
+	switch e.config.CBTRunner {
+	case CBTRunnerDocker:
+	case CBTRunnerBinary:
+		if e.config.CBTBinary == "" {
+			return errCBTBinaryRequired
+		}
+
+		if _, err := exec.LookPath(e.config.CBTBinary); err != nil {
+			return fmt.Errorf("finding cbt binary: %w", err)
+		}
+	default:
+		return fmt.Errorf("%w: %q", errUnknownCBTRunner, e.config.CBTRunner)
+	}
+
+	if e.docker == nil && e.config.CBTRunner == CBTRunnerDocker {
+		docker, err := runtime.NewDockerClient("")
+		if err != nil {
+			return fmt.Errorf("creating docker client: %w", err)
+		}
+
+		e.docker = docker
+		e.ownsClients = true
+	}
+
+	if err := e.redisDBs.lease(ctx, e.log); err != nil {
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), containerCleanupTimeout)
+		defer cancel()
+
+		_ = e.redisDBs.release(releaseCtx)
+
+		return err
+	}
+
+	e.leasedRedisDBs = true
+
 	return nil
 }
 
@@ -139,43 +342,53 @@ func (e *CBTEngine) Start(_ context.Context) error {
 func (e *CBTEngine) Stop() error {
 	e.log.Debug("stopping cbt engine")
 
-	// Kill all running containers
-	e.runningContainersMu.Lock()
-	containers := make([]string, len(e.runningContainers))
-	copy(containers, e.runningContainers)
-	e.runningContainersMu.Unlock()
+	// Kill all running CBT instances
+	e.runningMu.Lock()
+	running := make([]cbtProcess, len(e.running))
+	copy(running, e.running)
+	e.runningMu.Unlock()
+
+	for _, proc := range running {
+		e.log.WithField("cbt", proc.name()).Debug("cleaning up CBT instance")
+		e.stopCBT(proc)
+	}
 
-	for _, containerName := range containers {
-		e.log.WithField("container", containerName).Debug("cleaning up CBT container")
+	var errs []error
 
-		killCmd := exec.Command("docker", "kill", containerName) //nolint:gosec // G204: Docker command with trusted container name
-		_ = killCmd.Run()                                        // Ignore errors - container may already be stopped
+	if e.leasedRedisDBs {
+		ctx, cancel := context.WithTimeout(context.Background(), containerCleanupTimeout)
+		defer cancel()
 
-		rmCmd := exec.Command("docker", "rm", "-f", containerName) //nolint:gosec // G204: Docker command with trusted container name
-		if err := rmCmd.Run(); err != nil {
-			e.log.WithError(err).WithField("container", containerName).Warn("failed to remove container")
+		if err := e.redisDBs.release(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("releasing redis dbs: %w", err))
 		}
+
+		e.leasedRedisDBs = false
 	}
 
-	return nil
-}
+	if e.ownsClients && e.docker != nil {
+		if err := e.docker.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing docker client: %w", err))
+		}
 
-// flushRedisDB flushes a specific Redis database to clear stale data.
-// This is called when acquiring a DB number from the pool to ensure clean state.
-func (e *CBTEngine) flushRedisDB(ctx context.Context, dbNum int) error {
-	// Use redis-cli -n to select the DB, then FLUSHDB to clear it.
-	// dbNum is from a controlled pool (1-15), not user input.
-	dbNumStr := fmt.Sprintf("%d", dbNum)
-	args := redisComposeArgs("-n", dbNumStr, "FLUSHDB")
+		e.docker = nil
+		e.ownsClients = false
+	}
 
-	cmd := exec.CommandContext(ctx, "docker", args...) //nolint:gosec // G204: args are from trusted internal pool
+	return errors.Join(errs...)
+}
 
-	output, err := cmd.CombinedOutput()
+// flushRedisDB clears a Redis DB taken for a CBT run.
+func (e *CBTEngine) flushRedisDB(ctx context.Context, db int) error {
+	deleted, err := e.redisDBs.flush(ctx, db)
 	if err != nil {
-		return fmt.Errorf("redis FLUSHDB failed: %w (output: %s)", err, string(output))
+		return err
 	}
 
-	e.log.WithField("redisDB", dbNum).Debug("flushed redis database")
+	e.log.WithFields(logrus.Fields{
+		"redis_db": db,
+		"deleted":  deleted,
+	}).Debug("flushed redis db")
 
 	return nil
 }
@@ -189,28 +402,85 @@ func (e *CBTEngine) RunTransformations(
 	allModels,
 	transformationModels []string,
 ) error {
-	// Acquire a Redis DB number from the pool for isolation.
-	// This ensures each concurrent CBT container uses a separate Redis database.
-	var redisDB int
-	select {
-	case redisDB = <-e.redisDBPool:
-		defer func() { e.redisDBPool <- redisDB }()
-	case <-ctx.Done():
-		return ctx.Err()
-	}
-
-	// Flush the Redis DB to clear any stale data from previous tests.
-	// This is critical because DB numbers are reused from a pool.
+	return e.runTransformations(ctx, network, dbName, externalDB, allModels, transformationModels, runOptions{})
+}
+
+// ReprocessTransformations runs the transformations again after processed intervals were
+// cleared from admin_cbt_incremental, waiting until each model in minCoverage has
+// processed intervals covering at least that many positions rather than just one interval.
+func (e *CBTEngine) ReprocessTransformations(
+	ctx context.Context,
+	network,
+	dbName,
+	externalDB string,
+	allModels,
+	transformationModels []string,
+	minCoverage map[string]uint64,
+) error {
+	return e.runTransformations(ctx, network, dbName, externalDB, allModels, transformationModels, runOptions{
+		minCoverage: minCoverage,
+	})
+}
+
+// RunChunkedTransformations executes CBT transformations with interval.max of model
+// lowered to intervalMax, so its data is processed in many small intervals. It waits
+// until model has covered coverage positions.
+func (e *CBTEngine) RunChunkedTransformations(
+	ctx context.Context,
+	network,
+	dbName,
+	externalDB string,
+	allModels,
+	transformationModels []string,
+	model string,
+	intervalMax,
+	coverage uint64,
+) error {
+	return e.runTransformations(ctx, network, dbName, externalDB, allModels, transformationModels, runOptions{
+		minCoverage: map[string]uint64{model: coverage},
+		chunkModel:  model,
+		intervalMax: intervalMax,
+	})
+}
+
+// runTransformations executes CBT transformations configured by opts.
+func (e *CBTEngine) runTransformations(
+	ctx context.Context,
+	network,
+	dbName,
+	externalDB string,
+	allModels,
+	transformationModels []string,
+	opts runOptions,
+) error {
+	redisDB, err := e.redisDBs.take(ctx)
+	if err != nil {
+		return err
+	}
+	defer e.redisDBs.put(redisDB)
+
+	// Clear the keys of the DB's previous run, whichever database it ran against.
 	if err := e.flushRedisDB(ctx, redisDB); err != nil {
-		return fmt.Errorf("flushing redis DB %d: %w", redisDB, err)
+		return fmt.Errorf("flushing redis db: %w", err)
 	}
 
+	opts.redisDB = redisDB
+
 	logCtx := e.log.WithFields(logrus.Fields{
 		"database":   dbName,
 		"externalDB": externalDB,
 		"models":     len(transformationModels),
 		"redisDB":    redisDB,
 	})
+
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+
+		if err := e.flushRedisDB(flushCtx, redisDB); err != nil {
+			logCtx.WithError(err).Warn("failed to flush redis db after run")
+		}
+	}()
 	logCtx.Info("running transformations")
 
 	start := time.Now()
@@ -225,13 +495,19 @@ func (e *CBTEngine) RunTransformations(
 	// Use local variable for config path to avoid race condition.
 	// Multiple concurrent tests share the same CBTEngine instance.
 	configPath := filepath.Join(tmpDir, "config.yml")
-	if err := e.generateConfig(network, dbName, externalDB, allModels, redisDB, configPath); err != nil {
+	if err := e.generateConfig(network, dbName, externalDB, allModels, opts, configPath); err != nil {
 		return fmt.Errorf("generating CBT config: %w", err)
 	}
 
-	// Execute CBT via docker, but only wait for test models
-	if err := e.runDockerCBT(ctx, network, dbName, externalDB, transformationModels, configPath); err != nil {
-		return fmt.Errorf("running CBT docker: %w", err)
+	if rendered, readErr := os.ReadFile(configPath); readErr == nil { //nolint:gosec // G304: Config path in our own temp dir
+		e.renderedConfigsMu.Lock()
+		e.renderedConfigs[dbName] = string(rendered)
+		e.renderedConfigsMu.Unlock()
+	}
+
+	// Execute CBT, but only wait for test models
+	if err := e.runCBT(ctx, network, dbName, externalDB, allModels, transformationModels, opts.minCoverage, configPath); err != nil {
+		return fmt.Errorf("running CBT: %w", err)
 	}
 
 	logCtx.WithFields(logrus.Fields{
@@ -242,7 +518,13 @@ func (e *CBTEngine) RunTransformations(
 }
 
 // generateConfig generates a CBT config file for specific models.
-func (e *CBTEngine) generateConfig(network, dbName, externalDB string, models []string, redisDB int, outputPath string) error {
+// The chunk model of opts, if any, gets its interval.max lowered.
+func (e *CBTEngine) generateConfig(
+	network, dbName, externalDB string,
+	models []string,
+	opts runOptions,
+	outputPath string,
+) error {
 	// Build model paths separated by type.
 	externalPaths, transformationPaths, err := e.buildModelPaths(models)
 	if err != nil {
@@ -251,13 +533,18 @@ func (e *CBTEngine) generateConfig(network, dbName, externalDB string, models []
 
 	cfg := &cbtConfig{}
 
-	clickhouseHTTPURL := strings.Replace(e.clickhouseURL, "clickhouse://", "http://", 1)
-
-	// Replace localhost with container hostname and adjust ports for Docker network access.
-	containerEndpoint := fmt.Sprintf("%s:%s", config.ClickHouseContainer, config.ClickHouseContainerHTTPPort)
-	clickhouseHTTPURL = strings.Replace(clickhouseHTTPURL, "localhost:9000", containerEndpoint, 1)
-	clickhouseHTTPURL = strings.Replace(clickhouseHTTPURL, "localhost:8123", containerEndpoint, 1)
-	cfg.ClickHouse.URL = clickhouseHTTPURL
+	if e.config.CBTRunner == CBTRunnerBinary {
+		// A local binary reaches ClickHouse on its published host port.
+		cfg.ClickHouse.URL = e.config.CBTClickHouseHTTPURL
+	} else {
+		clickhouseHTTPURL := strings.Replace(e.clickhouseURL, "clickhouse://", "http://", 1)
+
+		// Replace localhost with container hostname and adjust ports for Docker network access.
+		containerEndpoint := fmt.Sprintf("%s:%s", config.ClickHouseContainer, config.ClickHouseContainerHTTPPort)
+		clickhouseHTTPURL = strings.Replace(clickhouseHTTPURL, "localhost:9000", containerEndpoint, 1)
+		clickhouseHTTPURL = strings.Replace(clickhouseHTTPURL, "localhost:8123", containerEndpoint, 1)
+		cfg.ClickHouse.URL = clickhouseHTTPURL
+	}
 
 	// Configure cluster settings for transformation models in CBT cluster
 	cfg.ClickHouse.Cluster = config.CBTClusterName
@@ -269,13 +556,16 @@ func (e *CBTEngine) generateConfig(network, dbName, externalDB string, models []
 	cfg.ClickHouse.Admin.Scheduled.Database = dbName
 	cfg.ClickHouse.Admin.Scheduled.Table = "admin_cbt_scheduled"
 
-	// Configure Redis with isolated database per concurrent CBT container.
-	// Each container gets its own Redis DB (1-15) to prevent task queue conflicts.
-	redisContainerEndpoint := fmt.Sprintf("%s:%s", config.RedisContainerName, config.RedisContainerPort)
-	redisURL := strings.Replace(e.redisURL, "localhost:6380", redisContainerEndpoint, 1)
-	redisURL = strings.Replace(redisURL, "localhost:6379", redisContainerEndpoint, 1)
-	cfg.Redis.URL = fmt.Sprintf("%s/%d", redisURL, redisDB)
-	cfg.Redis.Prefix = fmt.Sprintf("test:%s:", dbName)
+	// Configure Redis with the DB taken for this run, which no other CBT instance uses
+	// meanwhile. CBT's Redis prefix isn't set, as it doesn't cover the task queue keys.
+	redisURL := e.redisURL
+	if e.config.CBTRunner != CBTRunnerBinary {
+		redisContainerEndpoint := fmt.Sprintf("%s:%s", config.RedisContainerName, config.RedisContainerPort)
+		redisURL = strings.Replace(redisURL, "localhost:6380", redisContainerEndpoint, 1)
+		redisURL = strings.Replace(redisURL, "localhost:6379", redisContainerEndpoint, 1)
+	}
+
+	cfg.Redis.URL = fmt.Sprintf("%s/%d", redisURL, opts.redisDB)
 
 	// Set model paths and default databases
 	cfg.Models.External.Paths = externalPaths
@@ -285,22 +575,14 @@ func (e *CBTEngine) generateConfig(network, dbName, externalDB string, models []
 	cfg.Models.Transformations.DefaultDatabase = dbName
 
 	// Set global environment variables
-	cfg.Models.Env = map[string]string{
-		"NETWORK":                                network,
-		"EXTERNAL_MODEL_MIN_TIMESTAMP":           "0",
-		"EXTERNAL_MODEL_MIN_BLOCK":               "0",
-		"EXTERNAL_MODEL_SCAN_SIZE_BLOCK":         "50000000",
-		"DATA_COLUMN_AVAILABILITY_LOOKBACK_DAYS": "3650", // 10 years for tests.
-		"EXTERNAL_DATABASE":                      externalDB,
-		"GENESIS_TIMESTAMP":                      genesisTimestampForNetwork(network),
-	}
+	cfg.Models.Env = cbtModelEnv(network, externalDB)
 
 	// Configure for fast test execution
 	cfg.Scheduler.Concurrency = 10
 	cfg.Worker.Concurrency = 10
 
 	// Add test-optimized overrides
-	cfg.Models.Overrides = e.buildTestOverrides(models)
+	cfg.Models.Overrides = e.buildTestOverrides(models, opts.chunkModel, opts.intervalMax)
 
 	// Template config with database name
 	e.templateConfig(cfg, dbName)
@@ -323,39 +605,62 @@ func (e *CBTEngine) generateConfig(network, dbName, externalDB string, models []
 	return nil
 }
 
-// buildModelPaths converts model names to file paths for CBT, separated by type
+// cbtModelEnv returns the environment variables CBT model templates are rendered with.
+func cbtModelEnv(network, externalDB string) map[string]string {
+	return map[string]string{
+		"NETWORK":                                network,
+		"EXTERNAL_MODEL_MIN_TIMESTAMP":           "0",
+		"EXTERNAL_MODEL_MIN_BLOCK":               "0",
+		"EXTERNAL_MODEL_SCAN_SIZE_BLOCK":         "50000000",
+		"DATA_COLUMN_AVAILABILITY_LOOKBACK_DAYS": "3650", // 10 years for tests.
+		"EXTERNAL_DATABASE":                      externalDB,
+		"GENESIS_TIMESTAMP":                      genesisTimestampForNetwork(network),
+	}
+}
+
+// buildModelPaths converts model names to file paths for CBT, separated by type.
+// Paths are below /models in the container, or the host models directory for the binary runner.
 func (e *CBTEngine) buildModelPaths(models []string) (externalPaths, transformationPaths []string, err error) {
 	externalPaths = make([]string, 0)
 	transformationPaths = make([]string, 0)
 
 	transformationDir := filepath.Join(e.modelsDir, "transformations")
 
+	modelsRoot, join := "/models", path.Join
+	if e.config.CBTRunner == CBTRunnerBinary {
+		if modelsRoot, err = filepath.Abs(e.modelsDir); err != nil {
+			return nil, nil, fmt.Errorf("getting absolute models path: %w", err)
+		}
+
+		join = filepath.Join
+	}
+
 	for _, modelName := range models {
 		// Try transformation directory first (.sql)
 		transformPath := filepath.Join(transformationDir, modelName+".sql")
 		if _, statErr := os.Stat(transformPath); statErr == nil {
-			transformationPaths = append(transformationPaths, path.Join("/models/transformations", modelName+".sql"))
+			transformationPaths = append(transformationPaths, join(modelsRoot, "transformations", modelName+".sql"))
 			continue
 		}
 
 		// Try transformation directory (.yml)
 		transformYmlPath := filepath.Join(transformationDir, modelName+".yml")
 		if _, statErr := os.Stat(transformYmlPath); statErr == nil {
-			transformationPaths = append(transformationPaths, path.Join("/models/transformations", modelName+".yml"))
+			transformationPaths = append(transformationPaths, join(modelsRoot, "transformations", modelName+".yml"))
 			continue
 		}
 
 		// Try external directory (.sql)
 		externalPath := filepath.Join(e.externalDir, modelName+".sql")
 		if _, statErr := os.Stat(externalPath); statErr == nil {
-			externalPaths = append(externalPaths, path.Join("/models/external", modelName+".sql"))
+			externalPaths = append(externalPaths, join(modelsRoot, "external", modelName+".sql"))
 			continue
 		}
 
 		// Try external directory (.yml)
 		externalYmlPath := filepath.Join(e.externalDir, modelName+".yml")
 		if _, statErr := os.Stat(externalYmlPath); statErr == nil {
-			externalPaths = append(externalPaths, path.Join("/models/external", modelName+".yml"))
+			externalPaths = append(externalPaths, join(modelsRoot, "external", modelName+".yml"))
 			continue
 		}
 
@@ -368,10 +673,6 @@ func (e *CBTEngine) buildModelPaths(models []string) (externalPaths, transformat
 // templateConfig applies database-specific templating to config
 func (e *CBTEngine) templateConfig(cfg *cbtConfig, dbName string) {
 	cfg.ClickHouse.URL = strings.ReplaceAll(cfg.ClickHouse.URL, "${NETWORK_NAME}", dbName)
-
-	if !strings.Contains(cfg.Redis.Prefix, dbName) {
-		e.log.WithField("database", dbName).Warn("Redis prefix does not contain database name")
-	}
 }
 
 // buildTestOverrides creates test-optimized overrides for all models.
@@ -381,7 +682,8 @@ func (e *CBTEngine) templateConfig(cfg *cbtConfig, dbName string) {
 //   - Incremental transformations: forwardfill/backfill="@every 5s"
 //
 // If overrides.tests.yaml exists, those entries take precedence over auto-generated defaults.
-func (e *CBTEngine) buildTestOverrides(models []string) map[string]*modelOverrides {
+// For chunked runs, intervalMax is applied last to chunkModel, together with chunkedSchedule.
+func (e *CBTEngine) buildTestOverrides(models []string, chunkModel string, intervalMax uint64) map[string]*modelOverrides {
 	allOverrides := make(map[string]*modelOverrides, len(models))
 
 	// Auto-generate defaults from model cache
@@ -418,7 +720,7 @@ func (e *CBTEngine) buildTestOverrides(models []string) map[string]*modelOverrid
 
 	data, err := os.ReadFile(overridesPath) //nolint:gosec // G304: Trusted path for test overrides
 	if err != nil {
-		return allOverrides
+		return e.applyIntervalMax(allOverrides, chunkModel, intervalMax)
 	}
 
 	var overridesConfig struct {
@@ -430,7 +732,7 @@ func (e *CBTEngine) buildTestOverrides(models []string) map[string]*modelOverrid
 	if err := yaml.Unmarshal(data, &overridesConfig); err != nil {
 		e.log.WithError(err).Warn("failed to parse overrides.tests.yaml, using auto-generated overrides only")
 
-		return allOverrides
+		return e.applyIntervalMax(allOverrides, chunkModel, intervalMax)
 	}
 
 	// File overrides win over auto-generated defaults
@@ -443,93 +745,172 @@ func (e *CBTEngine) buildTestOverrides(models []string) map[string]*modelOverrid
 		"auto_generated": len(allOverrides) - len(overridesConfig.Models.Overrides),
 	}).Info("applied overrides.tests.yaml on top of auto-generated defaults")
 
-	return allOverrides
+	return e.applyIntervalMax(allOverrides, chunkModel, intervalMax)
+}
+
+// applyIntervalMax lowers interval.max of the incremental transformation model and speeds
+// up its schedules. Without a model or intervalMax the overrides are left unchanged.
+func (e *CBTEngine) applyIntervalMax(
+	overrides map[string]*modelOverrides,
+	model string,
+	intervalMax uint64,
+) map[string]*modelOverrides {
+	if model == "" || intervalMax == 0 {
+		return overrides
+	}
+
+	tm := e.modelCache.GetTransformationModel(model)
+	if tm == nil || tm.ExecutionType == "scheduled" {
+		return overrides
+	}
+
+	override, ok := overrides[model]
+	if !ok || override == nil {
+		override = &modelOverrides{}
+		overrides[model] = override
+	}
+
+	override.Config.Interval = &intervalOverride{Max: intervalMax}
+	override.Config.Schedules = map[string]string{
+		"forwardfill": chunkedSchedule,
+		"backfill":    chunkedSchedule,
+	}
+
+	return overrides
 }
 
-// runDockerCBT runs CBT in a docker container
-func (e *CBTEngine) runDockerCBT(
+// runCBT runs CBT with the generated config until the transformation models completed.
+// The CBT logs, filtered to allModels, are recorded for the database. A timeout is
+// returned as a *TransformationTimeoutError ending with the last lines of the logs.
+func (e *CBTEngine) runCBT(
 	ctx context.Context,
 	network,
 	dbName,
 	externalDB string,
+	allModels,
 	models []string,
+	minCoverage map[string]uint64,
 	configPath string,
 ) error {
 	e.log.WithFields(logrus.Fields{
 		"network":  network,
 		"database": dbName,
+		"runner":   e.config.CBTRunner,
 	}).Debug("starting cbt")
 
-	absConfigPath, err := filepath.Abs(configPath)
+	proc, err := e.startCBT(ctx, network, externalDB, configPath)
 	if err != nil {
-		return fmt.Errorf("getting absolute config path: %w", err)
+		return err
 	}
+	defer e.stopCBT(proc)
 
-	absModelsDir, err := filepath.Abs(e.modelsDir)
-	if err != nil {
-		return fmt.Errorf("getting absolute models path: %w", err)
+	execCtx, cancel := context.WithTimeout(ctx, e.config.ExecutionTimeout)
+	defer cancel()
+
+	coverage, waitErr := e.waitForTransformations(execCtx, network, dbName, externalDB, models, minCoverage)
+
+	logsCtx, logsCancel := context.WithTimeout(context.Background(), containerCleanupTimeout)
+	defer logsCancel()
+
+	logs, logsErr := proc.logs(logsCtx)
+	if logsErr != nil {
+		e.log.WithError(logsErr).WithField("cbt", proc.name()).Warn("failed to read CBT logs")
+	} else {
+		e.recordCBTLog(dbName, proc.name(), logs, allModels)
 	}
 
-	// Use UnixNano to avoid container name collisions when tests start within the same second
-	containerName := fmt.Sprintf("xatu-cbt-test-%d", time.Now().UnixNano())
-	args := []string{
-		"run",
-		"--rm",
-		"--name", containerName,
-		"--network", e.config.DockerNetwork,
-		"-e", fmt.Sprintf("NETWORK=%s", network),
-		"-e", fmt.Sprintf("EXTERNAL_DATABASE=%s", externalDB),
-		"-v", fmt.Sprintf("%s:/config/config.yml", absConfigPath),
-		"-v", fmt.Sprintf("%s:/models", absModelsDir),
-		e.config.DockerImage,
-		"--config", "/config/config.yml",
+	e.recordCoverage(dbName, coverage)
+
+	var timeoutErr *TransformationTimeoutError
+	if errors.As(waitErr, &timeoutErr) {
+		timeoutErr.LogTail = tailLines(logs, e.config.CBTLogTailLines)
 	}
 
-	execCtx, cancel := context.WithTimeout(ctx, e.config.ExecutionTimeout)
-	defer cancel()
+	if waitErr != nil {
+		return fmt.Errorf("waiting for transformations: %w", waitErr)
+	}
 
-	cmd := exec.CommandContext(execCtx, "docker", args...) //nolint:gosec // G204: Docker command with controlled arguments
+	return nil
+}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// filterCBTLog keeps the log lines that mention one of models, plus warnings and errors,
+// which explain failures such as an invalid config before any model is mentioned.
+func filterCBTLog(logs []byte, models []string) string {
+	var b strings.Builder
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("starting docker command: %w", err)
-	}
+	for _, line := range strings.Split(string(logs), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
 
-	e.runningContainersMu.Lock()
-	e.runningContainers = append(e.runningContainers, containerName)
-	e.runningContainersMu.Unlock()
+		keep := isCBTLogProblem(line)
+		for i := 0; !keep && i < len(models); i++ {
+			keep = strings.Contains(line, models[i])
+		}
 
-	defer func() {
-		killCmd := exec.Command("docker", "kill", containerName) //nolint:gosec // G204: Docker cleanup with trusted container name
-		_ = killCmd.Run()
+		if keep {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
 
-		rmCmd := exec.Command("docker", "rm", "-f", containerName) //nolint:gosec // G204: Docker cleanup with trusted container name
-		_ = rmCmd.Run()
+	return b.String()
+}
 
-		e.runningContainersMu.Lock()
-		for i, name := range e.runningContainers {
-			if name == containerName {
-				e.runningContainers = append(e.runningContainers[:i], e.runningContainers[i+1:]...)
-				break
-			}
+// isCBTLogProblem reports whether a logrus text or JSON log line is a warning or worse,
+// or a Go panic.
+func isCBTLogProblem(line string) bool {
+	for _, level := range []string{"warning", "error", "fatal", "panic"} {
+		if strings.Contains(line, "level="+level) || strings.Contains(line, `"level":"`+level+`"`) {
+			return true
 		}
-		e.runningContainersMu.Unlock()
-	}()
+	}
+
+	return strings.HasPrefix(line, "panic:") || strings.HasPrefix(line, "goroutine ")
+}
+
+// tailLines returns the last n non-empty lines of logs.
+func tailLines(logs []byte, n int) []string {
+	if n <= 0 {
+		return nil
+	}
 
-	if err := e.waitForTransformations(ctx, dbName, externalDB, models); err != nil {
-		e.log.WithError(err).Warn("error waiting for transformations, continuing anyway")
+	lines := strings.Split(strings.TrimRight(string(logs), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
 	}
 
-	return nil
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines
+}
+
+// waitTarget is what waitForTransformations waits for.
+type waitTarget struct {
+	// models are the transformation models that must complete.
+	models map[string]bool
+	// minCoverage is the number of positions listed models must have covered.
+	minCoverage map[string]uint64
+	// expected is the range listed incremental models must cover without gaps.
+	expected map[string]PositionRange
 }
 
 // waitForTransformations polls admin tables until all transformation models have been
-// processed by CBT. Once a model appears in admin tables, CBT has executed it.
+// processed by CBT. Once a model appears in admin tables, CBT has executed it; incremental
+// models must also have covered the range of their dependencies' data, and models in
+// minCoverage that many positions. It returns the coverage of the incremental models with
+// an expected range, which a timeout error also carries for the pending ones.
 // Correctness (row counts, data quality) is validated by assertions, not here.
-func (e *CBTEngine) waitForTransformations(ctx context.Context, dbName, _ string, models []string) error {
+func (e *CBTEngine) waitForTransformations(
+	ctx context.Context,
+	network,
+	dbName,
+	externalDB string,
+	models []string,
+	minCoverage map[string]uint64,
+) ([]ModelCoverage, error) {
 	allModels := make(map[string]bool)
 
 	for _, model := range models {
@@ -540,7 +921,7 @@ func (e *CBTEngine) waitForTransformations(ctx context.Context, dbName, _ string
 
 	if len(allModels) == 0 {
 		e.log.Debug("no transformation models to wait for")
-		return nil
+		return nil, nil
 	}
 
 	e.log.WithFields(logrus.Fields{
@@ -550,28 +931,55 @@ func (e *CBTEngine) waitForTransformations(ctx context.Context, dbName, _ string
 
 	conn, err := sql.Open("clickhouse", e.clickhouseURL)
 	if err != nil {
-		return fmt.Errorf("opening clickhouse connection: %w", err)
+		return nil, fmt.Errorf("opening clickhouse connection: %w", err)
 	}
 	defer func() { _ = conn.Close() }()
 
 	timeout := time.NewTimer(e.config.TransformationWaitTimeout)
 	defer timeout.Stop()
 
-	if err := e.waitForAdminTables(ctx, conn, dbName, timeout); err != nil {
-		return fmt.Errorf("waiting for admin tables: %w", err)
+	expected, unchecked := e.expectedRanges(allModels, func(model *ModelMetadata) (PositionRange, error) {
+		return e.externalBounds(ctx, conn, network, externalDB, model)
+	})
+
+	target := waitTarget{
+		models:      allModels,
+		minCoverage: minCoverage,
+		expected:    expected,
 	}
 
-	return e.pollUntilAllCompleted(ctx, conn, dbName, allModels, timeout)
+	// Models without an expected range only have to process an interval; the coverage
+	// report lists them with the reason.
+	uncheckedCoverage := make([]ModelCoverage, 0, len(unchecked))
+
+	for _, model := range sortedModels(allModels) {
+		if err, ok := unchecked[model]; ok {
+			e.log.WithError(err).WithFields(logrus.Fields{
+				"model":    model,
+				"database": dbName,
+			}).Warn("not checking the coverage of model")
+
+			uncheckedCoverage = append(uncheckedCoverage, ModelCoverage{Model: model, Unchecked: err})
+		}
+	}
+
+	if err := e.waitForAdminTables(ctx, conn, dbName, sortedModels(allModels), timeout); err != nil {
+		return nil, fmt.Errorf("waiting for admin tables: %w", err)
+	}
+
+	coverage, err := e.pollUntilAllCompleted(ctx, conn, dbName, target, timeout)
+
+	return append(coverage, uncheckedCoverage...), err
 }
 
-// pollUntilAllCompleted polls admin tables with backoff until all models appear or timeout.
+// pollUntilAllCompleted polls admin tables with backoff until all models complete or timeout.
 func (e *CBTEngine) pollUntilAllCompleted(
 	ctx context.Context,
 	conn *sql.DB,
 	dbName string,
-	allModels map[string]bool,
+	target waitTarget,
 	timeout *time.Timer,
-) error {
+) ([]ModelCoverage, error) {
 	interval := e.config.InitialPollInterval
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -579,19 +987,25 @@ func (e *CBTEngine) pollUntilAllCompleted(
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, ctx.Err()
 		case <-timeout.C:
-			pending := e.getPendingModels(ctx, conn, dbName, allModels)
-
-			return fmt.Errorf("timeout waiting for transformations: %v still pending", pending) //nolint:err113 // Dynamic timeout message
+			pending, coverage := e.getPendingModels(ctx, conn, dbName, target)
+			sort.Strings(pending)
+
+			return coverage, &TransformationTimeoutError{
+				Database: dbName,
+				Timeout:  e.config.TransformationWaitTimeout,
+				Pending:  pending,
+				Coverage: pendingCoverage(coverage, pending),
+			}
 		case <-ticker.C:
-			pending, err := e.checkTransformationProgress(ctx, conn, dbName, allModels)
+			pending, coverage, err := e.checkTransformationProgress(ctx, conn, dbName, target)
 			if err != nil {
 				continue
 			}
 
 			if len(pending) == 0 {
-				return nil
+				return coverage, nil
 			}
 
 			interval = time.Duration(float64(interval) * e.config.PollBackoffMultiplier)
@@ -603,26 +1017,63 @@ func (e *CBTEngine) pollUntilAllCompleted(
 	}
 }
 
-// checkTransformationProgress checks admin tables and returns pending models.
+// checkTransformationProgress checks admin tables and returns pending models, with the
+// coverage of the incremental models with an expected range.
 func (e *CBTEngine) checkTransformationProgress(
 	ctx context.Context,
 	conn *sql.DB,
 	dbName string,
-	allModels map[string]bool,
-) ([]string, error) {
+	target waitTarget,
+) ([]string, []ModelCoverage, error) {
+	allModels, minCoverage := target.models, target.minCoverage
+
 	// incrementalTimes maps each incremental model to when it last wrote data;
 	// presence in the map means it has processed at least one interval.
 	incrementalTimes, err := e.getModelTimes(ctx, conn, dbName, "admin_cbt_incremental", "updated_date_time")
 	if err != nil {
 		e.log.WithError(err).Debug("error checking incremental models")
-		return nil, err
+		return nil, nil, err
+	}
+
+	// Intervals are only needed when waiting for more than one interval.
+	var (
+		positions   map[string]uint64
+		coverage    []ModelCoverage
+		gapsByModel = make(map[string]bool)
+	)
+
+	if len(minCoverage) > 0 || len(target.expected) > 0 {
+		intervals, err := e.getIntervals(ctx, conn, dbName)
+		if err != nil {
+			e.log.WithError(err).Debug("error reading incremental intervals")
+			return nil, nil, err
+		}
+
+		positions = make(map[string]uint64, len(intervals))
+
+		for model, modelIntervals := range intervals {
+			for _, interval := range modelIntervals {
+				positions[model] += interval.End - interval.Start
+			}
+		}
+
+		for _, model := range sortedModels(allModels) {
+			expected, ok := target.expected[model]
+			if !ok {
+				continue
+			}
+
+			modelCoverage := newModelCoverage(model, expected, intervals[model])
+			coverage = append(coverage, modelCoverage)
+			gapsByModel[model] = !modelCoverage.Complete()
+		}
 	}
 
 	// scheduledTimes maps each scheduled model to its latest run start time.
 	scheduledTimes, err := e.getModelTimes(ctx, conn, dbName, "admin_cbt_scheduled", "start_date_time")
 	if err != nil {
 		e.log.WithError(err).Debug("error checking scheduled models")
-		return nil, err
+		return nil, nil, err
 	}
 
 	// A scheduled model is only "complete" once every transformation dependency
@@ -656,8 +1107,15 @@ func (e *CBTEngine) checkTransformationProgress(
 		var result bool
 
 		if tm.ExecutionType != "scheduled" {
-			// Incremental: complete once it has processed an interval.
+			// Incremental: complete once it has processed an interval that
+			// leaves no gap in its expected range, and covered the required
+			// positions when given.
 			_, result = incrementalTimes[model]
+			result = result && !gapsByModel[model]
+
+			if want := minCoverage[model]; result && want > 0 {
+				result = positions[model] >= want
+			}
 		} else if runTime, ran := scheduledTimes[model]; ran {
 			result = true
 
@@ -692,7 +1150,35 @@ func (e *CBTEngine) checkTransformationProgress(
 		"pending":   pending,
 	}).Debug("transformation progress")
 
-	return pending, nil
+	return pending, coverage, nil
+}
+
+// pendingCoverage returns the coverage of the pending models.
+func pendingCoverage(coverage []ModelCoverage, pending []string) []ModelCoverage {
+	var result []ModelCoverage
+
+	for _, modelCoverage := range coverage {
+		for _, model := range pending {
+			if modelCoverage.Model == model {
+				result = append(result, modelCoverage)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// sortedModels returns the models of a set in name order.
+func sortedModels(models map[string]bool) []string {
+	sorted := make([]string, 0, len(models))
+	for model := range models {
+		sorted = append(sorted, model)
+	}
+
+	sort.Strings(sorted)
+
+	return sorted
 }
 
 // modelReadyTime returns when a dependency's data became available: a scheduled
@@ -734,10 +1220,16 @@ func (e *CBTEngine) getModelTimes(ctx context.Context, conn *sql.DB, dbName, adm
 	return times, nil
 }
 
-// getPendingModels returns models that haven't appeared in admin tables yet.
-func (e *CBTEngine) getPendingModels(ctx context.Context, conn *sql.DB, dbName string, allModels map[string]bool) []string {
-	pending, _ := e.checkTransformationProgress(ctx, conn, dbName, allModels)
-	return pending
+// getPendingModels returns models that haven't completed yet, with the coverage of the
+// incremental models with an expected range.
+func (e *CBTEngine) getPendingModels(
+	ctx context.Context,
+	conn *sql.DB,
+	dbName string,
+	target waitTarget,
+) ([]string, []ModelCoverage) {
+	pending, coverage, _ := e.checkTransformationProgress(ctx, conn, dbName, target)
+	return pending, coverage
 }
 
 // waitForAdminTables waits for CBT admin tables to be created
@@ -745,6 +1237,7 @@ func (e *CBTEngine) waitForAdminTables(
 	ctx context.Context,
 	conn *sql.DB,
 	dbName string,
+	pending []string,
 	timeout *time.Timer,
 ) error {
 	ticker := time.NewTicker(e.config.AdminTablePollInterval)
@@ -757,7 +1250,12 @@ func (e *CBTEngine) waitForAdminTables(
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-timeout.C:
-			return fmt.Errorf("timeout waiting for admin tables to be created") //nolint:err113 // Static timeout message
+			return &TransformationTimeoutError{
+				Database:    dbName,
+				Timeout:     e.config.TransformationWaitTimeout,
+				AdminTables: true,
+				Pending:     pending,
+			}
 		case <-ticker.C:
 			allExist := true
 			for _, tableName := range adminTables {
@@ -0,0 +1,331 @@
+package testing
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/config"
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errExportRangeRequired = errors.New("range required for interval type")
+	errNoRangeColumn       = errors.New("no range column found for interval type")
+	errInvalidRange        = errors.New("invalid range, expected START-END")
+)
+
+// intervalRangeColumns lists the candidate range columns per external model interval type.
+// Interval types not listed here (entity, address) are exported filtered by network only.
+var intervalRangeColumns = map[string][]string{
+	"slot":  {"slot", "wallclock_slot"},
+	"block": {"block_number"},
+}
+
+// ExportRange is an inclusive range of slots or block numbers.
+type ExportRange struct {
+	Start uint64
+	End   uint64
+}
+
+// ParseExportRange parses an inclusive "START-END" range.
+func ParseExportRange(spec string) (*ExportRange, error) {
+	if spec == "" {
+		return nil, nil //nolint:nilnil // No range requested
+	}
+
+	var r ExportRange
+
+	if _, err := fmt.Sscanf(spec, "%d-%d", &r.Start, &r.End); err != nil || r.End < r.Start {
+		return nil, fmt.Errorf("%w: %q", errInvalidRange, spec)
+	}
+
+	return &r, nil
+}
+
+// ExportOptions configures a fixture export for a single model.
+type ExportOptions struct {
+	Model   string
+	Network string
+	Slots   *ExportRange
+	Blocks  *ExportRange
+	// OutDir receives the parquet files, named <model>_<table>.parquet.
+	OutDir string
+	// BaseURL is where the files will be published; used for the test definition URLs.
+	BaseURL string
+}
+
+// ExportedTable is a parquet fixture written for an external table.
+type ExportedTable struct {
+	Table         string
+	Path          string
+	URL           string
+	Size          int64
+	Filter        string
+	NetworkColumn string
+}
+
+// Exporter exports parquet fixtures for a model's leaf external tables from a
+// ClickHouse HTTP endpoint.
+// This is the concrete implementation without an interface abstraction.
+type Exporter struct {
+	sourceURL  string
+	httpClient *http.Client
+	modelCache *ModelCache
+	log        logrus.FieldLogger
+}
+
+// NewExporter creates a fixture exporter reading from the ClickHouse HTTP endpoint at sourceURL.
+func NewExporter(log logrus.FieldLogger, modelCache *ModelCache, sourceURL string, timeout time.Duration) *Exporter {
+	return &Exporter{
+		sourceURL:  strings.TrimSuffix(sourceURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+		modelCache: modelCache,
+		log:        log.WithField("component", "exporter"),
+	}
+}
+
+// ExternalTables returns the leaf external tables a model's test needs.
+func (e *Exporter) ExternalTables(definition *testdef.TestDefinition) ([]ExternalTableRef, error) {
+	if model := e.modelCache.GetExternalModel(definition.Model); model != nil {
+		return []ExternalTableRef{{
+			ModelName:   model.Name,
+			SourceDB:    model.SourceDB,
+			SourceTable: model.SourceTable,
+		}}, nil
+	}
+
+	deps, err := e.modelCache.ResolveTestDependencies(definition)
+	if err != nil {
+		return nil, fmt.Errorf("resolving dependencies for %s: %w", definition.Model, err)
+	}
+
+	return deps.ExternalTableRefs, nil
+}
+
+// Export writes a parquet file per external table the model depends on.
+// Existing network columns from the definition are respected.
+func (e *Exporter) Export(
+	ctx context.Context,
+	definition *testdef.TestDefinition,
+	opts *ExportOptions,
+) ([]*ExportedTable, error) {
+	refs, err := e.ExternalTables(definition)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].ModelName < refs[j].ModelName })
+
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil { //nolint:gosec // G301: Output directory with standard permissions
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+
+	exported := make([]*ExportedTable, 0, len(refs))
+
+	for _, ref := range refs {
+		networkColumn := DefaultNetworkColumn
+		if ext, ok := definition.ExternalData[ref.ModelName]; ok && ext.NetworkColumn != "" {
+			networkColumn = ext.NetworkColumn
+		}
+
+		table, err := e.exportTable(ctx, ref, networkColumn, opts)
+		if err != nil {
+			return exported, fmt.Errorf("exporting %s: %w", ref.ModelName, err)
+		}
+
+		exported = append(exported, table)
+	}
+
+	return exported, nil
+}
+
+// exportTable exports a single external table to parquet.
+func (e *Exporter) exportTable(
+	ctx context.Context,
+	ref ExternalTableRef,
+	networkColumn string,
+	opts *ExportOptions,
+) (*ExportedTable, error) {
+	database, table := config.DefaultDatabase, ref.ModelName
+	if ref.SourceDB != "" {
+		database, table = ref.SourceDB, ref.SourceTable
+	}
+
+	var intervalType string
+	if model := e.modelCache.GetExternalModel(ref.ModelName); model != nil {
+		intervalType = model.IntervalType
+	}
+
+	filter, err := e.buildFilter(ctx, database, table, intervalType, networkColumn, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := fmt.Sprintf("%s_%s.parquet", opts.Model, ref.ModelName)
+	path := filepath.Join(opts.OutDir, fileName)
+
+	query := fmt.Sprintf("SELECT * FROM `%s`.`%s` WHERE %s FORMAT Parquet", database, table, filter)
+
+	logCtx := e.log.WithFields(logrus.Fields{
+		"table":    ref.ModelName,
+		"interval": intervalType,
+		"filter":   filter,
+	})
+	logCtx.Info("exporting parquet")
+
+	size, err := e.queryToFile(ctx, query, path)
+	if err != nil {
+		return nil, err
+	}
+
+	logCtx.WithFields(logrus.Fields{"path": path, "size": size}).Info("exported parquet")
+
+	return &ExportedTable{
+		Table:         ref.ModelName,
+		Path:          path,
+		URL:           strings.TrimSuffix(opts.BaseURL, "/") + "/" + fileName,
+		Size:          size,
+		Filter:        filter,
+		NetworkColumn: networkColumn,
+	}, nil
+}
+
+// buildFilter builds the WHERE clause for a table from its interval type: slot and block
+// tables are limited to the requested range, other tables to the network only.
+func (e *Exporter) buildFilter(
+	ctx context.Context,
+	database, table, intervalType, networkColumn string,
+	opts *ExportOptions,
+) (string, error) {
+	networkFilter := fmt.Sprintf("`%s` = '%s'", networkColumn, escapeSQLString(opts.Network))
+
+	candidates, ranged := intervalRangeColumns[intervalType]
+	if !ranged {
+		return networkFilter, nil
+	}
+
+	r := opts.Slots
+	if intervalType == "block" {
+		r = opts.Blocks
+	}
+
+	if r == nil {
+		return "", fmt.Errorf("%w %s (use --slots/--blocks)", errExportRangeRequired, intervalType)
+	}
+
+	columns, err := e.tableColumns(ctx, database, table)
+	if err != nil {
+		return "", err
+	}
+
+	for _, candidate := range candidates {
+		if columns[candidate] {
+			return fmt.Sprintf("`%s` BETWEEN %d AND %d AND %s", candidate, r.Start, r.End, networkFilter), nil
+		}
+	}
+
+	return "", fmt.Errorf("%w %s in %s.%s (tried %s)",
+		errNoRangeColumn, intervalType, database, table, strings.Join(candidates, ", "))
+}
+
+// tableColumns returns the column names of a source table.
+func (e *Exporter) tableColumns(ctx context.Context, database, table string) (map[string]bool, error) {
+	query := fmt.Sprintf(
+		"SELECT name FROM system.columns WHERE database = '%s' AND table = '%s' FORMAT TabSeparated",
+		escapeSQLString(database), escapeSQLString(table),
+	)
+
+	body, err := e.query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("reading columns of %s.%s: %w", database, table, err)
+	}
+	defer func() { _ = body.Close() }()
+
+	columns := make(map[string]bool)
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		columns[strings.TrimSpace(scanner.Text())] = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading columns of %s.%s: %w", database, table, err)
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s.%s not found on source", database, table) //nolint:err113 // Include table for debugging
+	}
+
+	return columns, nil
+}
+
+// queryToFile streams the result of a query into a file, replacing it atomically.
+func (e *Exporter) queryToFile(ctx context.Context, query, path string) (int64, error) {
+	body, err := e.query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = body.Close() }()
+
+	tmpPath := path + tmpFileSuffix
+
+	file, err := os.Create(tmpPath) //nolint:gosec // G304: Output path from trusted CLI input
+	if err != nil {
+		return 0, fmt.Errorf("creating %s: %w", tmpPath, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	written, err := io.Copy(file, body)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+
+		return 0, fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+
+		return 0, fmt.Errorf("closing %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+
+		return 0, fmt.Errorf("moving %s into place: %w", path, err)
+	}
+
+	return written, nil
+}
+
+// query posts a query to the ClickHouse HTTP interface and returns the response body.
+func (e *Exporter) query(ctx context.Context, query string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.sourceURL+"/", strings.NewReader(query))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("querying source: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+		return nil, fmt.Errorf("source returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message))) //nolint:err113 // Include ClickHouse error for debugging
+	}
+
+	return resp.Body, nil
+}
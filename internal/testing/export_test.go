@@ -0,0 +1,121 @@
+package testing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExportRange(t *testing.T) {
+	t.Parallel()
+
+	r, err := ParseExportRange("100-120")
+	require.NoError(t, err)
+	require.Equal(t, &ExportRange{Start: 100, End: 120}, r)
+
+	r, err = ParseExportRange("")
+	require.NoError(t, err)
+	require.Nil(t, r)
+
+	_, err = ParseExportRange("120-100")
+	require.ErrorIs(t, err, errInvalidRange)
+
+	_, err = ParseExportRange("abc")
+	require.ErrorIs(t, err, errInvalidRange)
+}
+
+func TestExporter_Export(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	externalDir := filepath.Join(root, "external")
+	transformationDir := filepath.Join(root, "transformations")
+
+	require.NoError(t, os.MkdirAll(externalDir, 0o750))
+	require.NoError(t, os.MkdirAll(transformationDir, 0o750))
+
+	files := map[string]string{
+		filepath.Join(externalDir, "canonical_beacon_block.sql"):    "---\ntable: canonical_beacon_block\ninterval:\n  type: slot\n---\nSELECT 1\n",
+		filepath.Join(externalDir, "canonical_execution_block.sql"): "---\ntable: canonical_execution_block\ninterval:\n  type: block\n---\nSELECT 1\n",
+		filepath.Join(externalDir, "ethseer_validator_entity.sql"):  "---\ntable: ethseer_validator_entity\ninterval:\n  type: entity\n---\nSELECT 1\n",
+		filepath.Join(transformationDir, "fct_block.sql"): "---\ntable: fct_block\ndependencies:\n" +
+			"  - \"{{external}}.canonical_beacon_block\"\n" +
+			"  - \"{{external}}.canonical_execution_block\"\n" +
+			"  - \"{{external}}.ethseer_validator_entity\"\n---\nSELECT 1\n",
+	}
+
+	for path, content := range files {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	cache := NewModelCache(logrus.New())
+	require.NoError(t, cache.LoadAll(context.Background(), externalDir, transformationDir))
+
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query := string(body)
+
+		mu.Lock()
+		queries = append(queries, query)
+		mu.Unlock()
+
+		switch {
+		case strings.Contains(query, "system.columns"):
+			_, _ = io.WriteString(w, "slot\nblock_number\nmeta_network_name\n")
+		case strings.Contains(query, "FORMAT Parquet"):
+			_, _ = io.WriteString(w, "PAR1")
+		default:
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	outDir := filepath.Join(root, "out")
+	exporter := NewExporter(logrus.New(), cache, server.URL, time.Minute)
+
+	exported, err := exporter.Export(context.Background(), &testdef.TestDefinition{Model: "fct_block", Network: "mainnet"}, &ExportOptions{
+		Model:   "fct_block",
+		Network: "mainnet",
+		Slots:   &ExportRange{Start: 10, End: 20},
+		Blocks:  &ExportRange{Start: 100, End: 200},
+		OutDir:  outDir,
+		BaseURL: "https://example.com/tests/mainnet/",
+	})
+	require.NoError(t, err)
+	require.Len(t, exported, 3)
+
+	require.Equal(t, "canonical_beacon_block", exported[0].Table)
+	require.Equal(t, "`slot` BETWEEN 10 AND 20 AND `meta_network_name` = 'mainnet'", exported[0].Filter)
+	require.Equal(t, "https://example.com/tests/mainnet/fct_block_canonical_beacon_block.parquet", exported[0].URL)
+	require.Equal(t, "`block_number` BETWEEN 100 AND 200 AND `meta_network_name` = 'mainnet'", exported[1].Filter)
+	require.Equal(t, "`meta_network_name` = 'mainnet'", exported[2].Filter)
+
+	data, err := os.ReadFile(exported[0].Path)
+	require.NoError(t, err)
+	require.Equal(t, "PAR1", string(data))
+
+	// Block ranges are required for block tables.
+	_, err = exporter.Export(context.Background(), &testdef.TestDefinition{Model: "fct_block", Network: "mainnet"}, &ExportOptions{
+		Model:   "fct_block",
+		Network: "mainnet",
+		Slots:   &ExportRange{Start: 10, End: 20},
+		OutDir:  outDir,
+	})
+	require.ErrorIs(t, err, errExportRangeRequired)
+}
@@ -0,0 +1,230 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ethpandaops/xatu-cbt/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errNotIdempotent       = errors.New("model is not idempotent: reprocessing changed its rows")
+	errReprocessIncomplete = errors.New("reprocess did not complete")
+)
+
+// fingerprintIgnoredColumns are excluded from table fingerprints and comparisons because
+// reprocessing legitimately rewrites them.
+var fingerprintIgnoredColumns = []string{"updated_date_time"}
+
+// TableFingerprint is an order-independent summary of a table's rows under FINAL.
+type TableFingerprint struct {
+	Rows     uint64
+	Checksum uint64
+}
+
+// String formats the fingerprint for failure messages.
+func (f *TableFingerprint) String() string {
+	return fmt.Sprintf("%d rows, checksum %016x", f.Rows, f.Checksum)
+}
+
+// FingerprintTable counts a table's rows under FINAL and sums their hashes. The sum is
+// order-independent but, unlike XOR, still changes when rows are duplicated. Columns in
+// fingerprintIgnoredColumns are left out of the hash.
+func (m *DatabaseManager) FingerprintTable(ctx context.Context, database, table string) (*TableFingerprint, error) {
+	columns, err := m.comparableColumns(ctx, database, table)
+	if err != nil {
+		return nil, err
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	fingerprint := &TableFingerprint{}
+	if err := m.cbtConn.QueryRowContext(queryCtx, fingerprintQuery(database, table, columns)).Scan(&fingerprint.Rows, &fingerprint.Checksum); err != nil {
+		return nil, fmt.Errorf("fingerprinting %s.%s: %w", database, table, err)
+	}
+
+	return fingerprint, nil
+}
+
+// fingerprintQuery returns the query counting a table's rows under FINAL and summing the
+// hashes of the given columns.
+func fingerprintQuery(database, table string, columns []string) string {
+	return fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled identifiers
+		"SELECT count(), sum(cityHash64(%s)) FROM `%s`.`%s` FINAL",
+		quoteIdentifiers(columns), database, table,
+	)
+}
+
+// comparableColumns returns a table's columns in order, without fingerprintIgnoredColumns.
+func (m *DatabaseManager) comparableColumns(ctx context.Context, database, table string) ([]string, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	rows, err := m.cbtConn.QueryContext(queryCtx,
+		"SELECT name FROM system.columns WHERE database = ? AND table = ? ORDER BY position",
+		database, table,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading columns of %s.%s: %w", database, table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	names := make([]string, 0)
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning column: %w", err)
+		}
+
+		names = append(names, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading columns of %s.%s: %w", database, table, err)
+	}
+
+	columns := withoutIgnoredColumns(names)
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s.%s has no columns to compare", database, table) //nolint:err113 // Include table for debugging
+	}
+
+	return columns, nil
+}
+
+// withoutIgnoredColumns returns columns without fingerprintIgnoredColumns, in order.
+func withoutIgnoredColumns(columns []string) []string {
+	kept := make([]string, 0, len(columns))
+
+	for _, column := range columns {
+		if !slices.Contains(fingerprintIgnoredColumns, column) {
+			kept = append(kept, column)
+		}
+	}
+
+	return kept
+}
+
+// IncrementalCoverage returns the number of positions covered by the intervals
+// admin_cbt_incremental records for a model.
+func (m *DatabaseManager) IncrementalCoverage(ctx context.Context, database, model string) (uint64, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled identifiers
+		"SELECT sum(interval) FROM `%s`.`admin_cbt_incremental` FINAL WHERE database = ? AND table = ?",
+		database,
+	)
+
+	var covered uint64
+	if err := m.cbtConn.QueryRowContext(queryCtx, query, database, model).Scan(&covered); err != nil {
+		return 0, fmt.Errorf("reading coverage of %s: %w", model, err)
+	}
+
+	return covered, nil
+}
+
+// ClearIncrementalIntervals removes a model's processed intervals from admin_cbt_incremental
+// so CBT reprocesses them on its next run. The model's rows are left in place.
+func (m *DatabaseManager) ClearIncrementalIntervals(ctx context.Context, database, model string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	if _, err := m.cbtConn.ExecContext(queryCtx, clearIntervalsSQL(database, model)); err != nil {
+		return fmt.Errorf("clearing intervals of %s: %w", model, err)
+	}
+
+	return nil
+}
+
+// clearIntervalsSQL returns the mutation deleting a model's rows from the local
+// admin_cbt_incremental tables on every node of the CBT cluster.
+func clearIntervalsSQL(database, model string) string {
+	return fmt.Sprintf( //nolint:gosec // G201: Safe SQL with controlled identifiers
+		"ALTER TABLE `%s`.`admin_cbt_incremental%s` ON CLUSTER %s DELETE WHERE database = '%s' AND table = '%s' SETTINGS mutations_sync = 2",
+		database, config.ClickHouseLocalSuffix, config.CBTClusterName, escapeSQLString(database), escapeSQLString(model),
+	)
+}
+
+// checkIdempotency reprocesses a passing incremental model and fails if its rows change.
+// It fingerprints the target table, clears the model's intervals from admin_cbt_incremental,
+// runs the transformations again until the same positions are covered, and compares.
+// Scheduled models and external model tests are skipped.
+func (o *Orchestrator) checkIdempotency(
+	ctx context.Context,
+	network, cbtDB, extDB, model string,
+	deps *Dependencies,
+) error {
+	logCtx := o.log.WithFields(logrus.Fields{
+		"model":  model,
+		"cbt_db": cbtDB,
+	})
+
+	if tm := o.modelCache.GetTransformationModel(model); tm == nil || tm.ExecutionType == "scheduled" {
+		logCtx.Debug("skipping idempotency check for non-incremental model")
+
+		return nil
+	}
+
+	before, err := o.dbManager.FingerprintTable(ctx, cbtDB, model)
+	if err != nil {
+		return fmt.Errorf("fingerprinting before reprocess: %w", err)
+	}
+
+	coverage, err := o.dbManager.IncrementalCoverage(ctx, cbtDB, model)
+	if err != nil {
+		return err
+	}
+
+	if err := o.dbManager.ClearIncrementalIntervals(ctx, cbtDB, model); err != nil {
+		return err
+	}
+
+	logCtx.WithField("coverage", coverage).Info("reprocessing model to check idempotency")
+
+	allModels, transformationNames := transformationRunModels(deps)
+
+	if err := o.cbtEngine.ReprocessTransformations(
+		ctx, network, cbtDB, extDB, allModels, transformationNames, map[string]uint64{model: coverage},
+	); err != nil {
+		return fmt.Errorf("reprocessing transformations: %w", err)
+	}
+
+	reprocessed, err := o.dbManager.IncrementalCoverage(ctx, cbtDB, model)
+	if err != nil {
+		return err
+	}
+
+	after, err := o.dbManager.FingerprintTable(ctx, cbtDB, model)
+	if err != nil {
+		return fmt.Errorf("fingerprinting after reprocess: %w", err)
+	}
+
+	if err := compareReprocess(coverage, reprocessed, before, after); err != nil {
+		return err
+	}
+
+	logCtx.WithField("fingerprint", before.String()).Info("model is idempotent")
+
+	return nil
+}
+
+// compareReprocess decides an idempotency check: the reprocess must cover at least the
+// positions of the first run, and leave the table's fingerprint unchanged.
+func compareReprocess(coverage, reprocessed uint64, before, after *TableFingerprint) error {
+	if reprocessed < coverage {
+		return fmt.Errorf("%w: %d of %d positions reprocessed", errReprocessIncomplete, reprocessed, coverage)
+	}
+
+	if *before != *after {
+		return fmt.Errorf("%w: %s before, %s after (ignoring %s)",
+			errNotIdempotent, before, after, strings.Join(fingerprintIgnoredColumns, ", "))
+	}
+
+	return nil
+}
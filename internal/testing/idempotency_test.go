@@ -0,0 +1,68 @@
+package testing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprintQuery(t *testing.T) {
+	t.Parallel()
+
+	columns := withoutIgnoredColumns([]string{"updated_date_time", "slot", "block_root"})
+	require.Equal(t, []string{"slot", "block_root"}, columns)
+
+	require.Equal(t,
+		"SELECT count(), sum(cityHash64(\"slot\", \"block_root\")) FROM `cbt_1`.`fct_block` FINAL",
+		fingerprintQuery("cbt_1", "fct_block", columns),
+	)
+}
+
+func TestClearIntervalsSQL(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t,
+		"ALTER TABLE `cbt_1`.`admin_cbt_incremental_local` ON CLUSTER cluster_2S_1R "+
+			"DELETE WHERE database = 'cbt_1' AND table = 'fct_block' SETTINGS mutations_sync = 2",
+		clearIntervalsSQL("cbt_1", "fct_block"),
+	)
+	require.Contains(t, clearIntervalsSQL("cbt_1", "fct_'block"), `table = 'fct_\'block'`)
+}
+
+func TestCompareReprocess(t *testing.T) {
+	t.Parallel()
+
+	fingerprint := &TableFingerprint{Rows: 10, Checksum: 0xabc}
+
+	tests := []struct {
+		name        string
+		coverage    uint64
+		reprocessed uint64
+		after       *TableFingerprint
+		wantErr     error
+	}{
+		{name: "unchanged", coverage: 100, reprocessed: 100, after: &TableFingerprint{Rows: 10, Checksum: 0xabc}},
+		{name: "reprocessed beyond the first run", coverage: 100, reprocessed: 120, after: &TableFingerprint{Rows: 10, Checksum: 0xabc}},
+		{name: "incomplete reprocess", coverage: 100, reprocessed: 60, after: &TableFingerprint{Rows: 6, Checksum: 0x1}, wantErr: errReprocessIncomplete},
+		{name: "duplicated rows", coverage: 100, reprocessed: 100, after: &TableFingerprint{Rows: 20, Checksum: 0xabc}, wantErr: errNotIdempotent},
+		{name: "changed values", coverage: 100, reprocessed: 100, after: &TableFingerprint{Rows: 10, Checksum: 0xdef}, wantErr: errNotIdempotent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := compareReprocess(tt.coverage, tt.reprocessed, fingerprint, tt.after)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	err := compareReprocess(100, 100, fingerprint, &TableFingerprint{Rows: 10, Checksum: 0xdef})
+	require.EqualError(t, err, "model is not idempotent: reprocessing changed its rows: "+
+		"10 rows, checksum 0000000000000abc before, 10 rows, checksum 0000000000000def after (ignoring updated_date_time)")
+}
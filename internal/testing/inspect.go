@@ -0,0 +1,275 @@
+package testing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/output"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errRetainedDatabaseGone = errors.New("retained database no longer exists")
+	errInvalidInspectTarget = errors.New("invalid inspect target (must be cbt or ext)")
+)
+
+// TableSummary is a table of a test database with its engine and row count.
+type TableSummary struct {
+	Name   string
+	Engine string
+	Rows   uint64
+}
+
+// Inspector explores the databases a test run kept, as recorded in the run manifest.
+type Inspector struct {
+	log       logrus.FieldLogger
+	dbManager *DatabaseManager
+	w         io.Writer
+}
+
+// NewInspector creates an inspector writing to w. The database manager must be started.
+func NewInspector(log logrus.FieldLogger, dbManager *DatabaseManager, w io.Writer) *Inspector {
+	return &Inspector{
+		log:       log.WithField("component", "inspector"),
+		dbManager: dbManager,
+		w:         w,
+	}
+}
+
+// Describe prints the connection details of a retained test and the tables of its databases.
+func (i *Inspector) Describe(ctx context.Context, test *RetainedTest) error {
+	status := "passed"
+	if !test.Success {
+		status = "failed"
+	}
+
+	_, _ = fmt.Fprintf(i.w, "Test:       %s (%s, %s)\n", test.Test, test.Network, status)
+	_, _ = fmt.Fprintf(i.w, "Recorded:   %s\n", test.RecordedAt.Format("2006-01-02 15:04:05"))
+
+	if test.Error != "" {
+		_, _ = fmt.Fprintf(i.w, "Error:      %s\n", test.Error)
+	}
+
+	if test.CBTLog != "" {
+		_, _ = fmt.Fprintf(i.w, "CBT logs:   %s\n", test.CBTLog)
+	}
+
+	if test.ChunkedCBTDB != "" {
+		_, _ = fmt.Fprintf(i.w, "Chunked:    %s (CBT database of the chunked run)\n", test.ChunkedCBTDB)
+	}
+
+	_, _ = fmt.Fprintf(i.w, "Assertions: run against the %s database\n\n", test.AssertionsOn)
+
+	renderer := output.NewTableRenderer(i.log)
+	headers := []string{"Target", "Database", "Table", "Engine", "Rows"}
+	rows := make([][]string, 0)
+
+	for _, target := range []string{AssertionTargetExternal, AssertionTargetCBT} {
+		database := test.ExternalDB
+		if target == AssertionTargetCBT {
+			database = test.CBTDB
+		}
+
+		dsn, err := i.dbManager.DSN(target == AssertionTargetExternal, database)
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(i.w, "%-4s %s\n", target, dsn)
+
+		tables, err := i.dbManager.ListTables(ctx, target == AssertionTargetExternal, database)
+		if err != nil {
+			return err
+		}
+
+		for _, table := range tables {
+			rows = append(rows, []string{target, database, table.Name, table.Engine, strconv.FormatUint(table.Rows, 10)})
+		}
+	}
+
+	_, _ = fmt.Fprintln(i.w)
+	renderer.RenderToWriter(i.w, headers, rows)
+
+	return nil
+}
+
+// Query runs an ad-hoc query against the cbt or ext database of a retained test and prints
+// at most maxRows rows. An empty target queries the database the assertions ran against.
+func (i *Inspector) Query(ctx context.Context, test *RetainedTest, target, query string, maxRows int) error {
+	if target == "" {
+		target = test.AssertionsOn
+	}
+
+	var database string
+
+	switch target {
+	case AssertionTargetCBT:
+		database = test.CBTDB
+	case AssertionTargetExternal:
+		database = test.ExternalDB
+	default:
+		return fmt.Errorf("%w: %q", errInvalidInspectTarget, target)
+	}
+
+	columns, rows, err := i.dbManager.QueryDatabase(ctx, target == AssertionTargetExternal, database, query, maxRows)
+	if err != nil {
+		return err
+	}
+
+	output.NewTableRenderer(i.log).RenderToWriter(i.w, columns, rows)
+	_, _ = fmt.Fprintf(i.w, "%d row(s) from %s\n", len(rows), database)
+
+	return nil
+}
+
+// connFor returns the connection of the cluster holding external or CBT test databases.
+func (m *DatabaseManager) connFor(external bool) (*sql.DB, string) {
+	if external {
+		return m.xatuConn, m.xatuConnStr
+	}
+
+	return m.cbtConn, m.cbtConnStr
+}
+
+// DSN returns the connection string for a test database, with any password redacted.
+func (m *DatabaseManager) DSN(external bool, database string) (string, error) {
+	_, connStr := m.connFor(external)
+
+	dsn, err := scopedDSN(connStr, database)
+	if err != nil {
+		return "", err
+	}
+
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("parsing connection string: %w", err)
+	}
+
+	return parsed.Redacted(), nil
+}
+
+// ListTables lists the tables of a test database with their engines and row counts.
+func (m *DatabaseManager) ListTables(ctx context.Context, external bool, database string) ([]TableSummary, error) {
+	conn, _ := m.connFor(external)
+
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	var exists uint64
+	if err := conn.QueryRowContext(queryCtx,
+		"SELECT count() FROM system.databases WHERE name = ?", database,
+	).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("checking database %s: %w", database, err)
+	}
+
+	if exists == 0 {
+		return nil, fmt.Errorf("%w: %s", errRetainedDatabaseGone, database)
+	}
+
+	rows, err := conn.QueryContext(queryCtx,
+		"SELECT name, engine, ifNull(total_rows, 0) FROM system.tables WHERE database = ? ORDER BY name", database,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing tables of %s: %w", database, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	tables := make([]TableSummary, 0)
+
+	for rows.Next() {
+		var table TableSummary
+		if err := rows.Scan(&table.Name, &table.Engine, &table.Rows); err != nil {
+			return nil, fmt.Errorf("scanning table of %s: %w", database, err)
+		}
+
+		tables = append(tables, table)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading tables of %s: %w", database, err)
+	}
+
+	return tables, nil
+}
+
+// QueryDatabase runs query with database as the session database and returns the column
+// names and at most maxRows rows rendered as strings.
+func (m *DatabaseManager) QueryDatabase(
+	ctx context.Context,
+	external bool,
+	database, query string,
+	maxRows int,
+) ([]string, [][]string, error) {
+	pool, _ := m.connFor(external)
+
+	queryCtx, cancel := context.WithTimeout(ctx, m.config.QueryTimeout)
+	defer cancel()
+
+	conn, err := pool.Conn(queryCtx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting connection from pool: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.ExecContext(queryCtx, fmt.Sprintf("USE `%s`", database)); err != nil {
+		return nil, nil, fmt.Errorf("setting database: %w", err)
+	}
+
+	rows, err := conn.QueryContext(queryCtx, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("executing query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting columns: %w", err)
+	}
+
+	result := make([][]string, 0)
+
+	for (maxRows <= 0 || len(result) < maxRows) && rows.Next() {
+		var (
+			values    = make([]interface{}, len(columns))
+			valuePtrs = make([]interface{}, len(columns))
+		)
+
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, nil, fmt.Errorf("scanning row: %w", err)
+		}
+
+		row := make([]string, len(columns))
+		for i, value := range values {
+			row[i] = formatInspectValue(value)
+		}
+
+		result = append(result, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("reading rows: %w", err)
+	}
+
+	return columns, result, nil
+}
+
+// formatInspectValue renders a scanned value for display.
+func formatInspectValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
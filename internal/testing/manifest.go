@@ -0,0 +1,211 @@
+package testing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/assertion"
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+)
+
+var (
+	errNoRetainedTest        = errors.New("no retained databases for test")
+	errAmbiguousRetainedTest = errors.New("several scenarios of the model have retained databases")
+)
+
+const (
+	// AssertionTargetCBT marks tests whose assertions run against the CBT database.
+	AssertionTargetCBT = "cbt"
+	// AssertionTargetExternal marks external model tests, whose assertions run against the external database.
+	AssertionTargetExternal = "ext"
+)
+
+// RunManifest records the databases kept after test runs, so they can be inspected later.
+type RunManifest struct {
+	Tests []*RetainedTest `json:"tests"`
+}
+
+// RetainedTest describes the kept databases of one test and how the test ran against them.
+type RetainedTest struct {
+	Test         string              `json:"test"` // model, or model[scenario]
+	Model        string              `json:"model"`
+	Network      string              `json:"network"`
+	Success      bool                `json:"success"`
+	Error        string              `json:"error,omitempty"`
+	ExternalDB   string              `json:"external_db"`
+	CBTDB        string              `json:"cbt_db"`
+	ChunkedCBTDB string              `json:"chunked_cbt_db,omitempty"` // CBT database of the chunked run
+	AssertionsOn string              `json:"assertions_on"`            // cbt or ext
+	CBTConfig    string              `json:"cbt_config,omitempty"`
+	CBTLog       string              `json:"cbt_log,omitempty"`
+	Assertions   []RetainedAssertion `json:"assertions,omitempty"`
+	RecordedAt   time.Time           `json:"recorded_at"`
+}
+
+// RetainedAssertion is an assertion's SQL as it ran against the kept databases.
+type RetainedAssertion struct {
+	Name   string `json:"name"`
+	SQL    string `json:"sql"`
+	Failed bool   `json:"failed,omitempty"`
+}
+
+// Database returns the database the test's assertions ran against.
+func (t *RetainedTest) Database() string {
+	if t.AssertionsOn == AssertionTargetExternal {
+		return t.ExternalDB
+	}
+
+	return t.CBTDB
+}
+
+// WriteConfig writes the CBT config the test ran with.
+func (t *RetainedTest) WriteConfig(w io.Writer) {
+	_, _ = fmt.Fprint(w, t.CBTConfig)
+}
+
+// WriteAssertions writes the assertion SQL as it ran against the kept database.
+func (t *RetainedTest) WriteAssertions(w io.Writer) {
+	for _, a := range t.Assertions {
+		status := "passed"
+		if a.Failed {
+			status = "FAILED"
+		}
+
+		_, _ = fmt.Fprintf(w, "-- %s (%s)\n%s\n\n", a.Name, status, strings.TrimSpace(a.SQL))
+	}
+
+	_, _ = fmt.Fprintf(w, "-- run against %s\n", t.Database())
+}
+
+// LoadManifest reads a run manifest. A missing file is an empty manifest.
+func LoadManifest(path string) (*RunManifest, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Manifest path from our own flags
+	if errors.Is(err, os.ErrNotExist) {
+		return &RunManifest{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading run manifest: %w", err)
+	}
+
+	var manifest RunManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing run manifest %s: %w", path, err)
+	}
+
+	return &manifest, nil
+}
+
+// Save writes the manifest, creating its directory if needed.
+func (m *RunManifest) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:gosec // G301: Manifest directory is not sensitive
+		return fmt.Errorf("creating run manifest directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding run manifest: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil { //nolint:gosec // G306: Manifest is not sensitive
+		return fmt.Errorf("writing run manifest: %w", err)
+	}
+
+	return nil
+}
+
+// Record replaces the entries of the tests that ran on network with the retained ones.
+// Tests that ran but kept no databases are removed, since their databases are gone.
+func (m *RunManifest) Record(network string, ran []string, retained []*RetainedTest) {
+	replaced := make(map[string]bool, len(ran)+len(retained))
+	for _, test := range ran {
+		replaced[test] = true
+	}
+
+	for _, test := range retained {
+		replaced[test.Test] = true
+	}
+
+	kept := make([]*RetainedTest, 0, len(m.Tests)+len(retained))
+	for _, test := range m.Tests {
+		if test.Network == network && replaced[test.Test] {
+			continue
+		}
+
+		kept = append(kept, test)
+	}
+
+	m.Tests = append(kept, retained...)
+
+	sort.SliceStable(m.Tests, func(i, j int) bool {
+		if m.Tests[i].Network != m.Tests[j].Network {
+			return m.Tests[i].Network < m.Tests[j].Network
+		}
+
+		return m.Tests[i].Test < m.Tests[j].Test
+	})
+}
+
+// Find returns the retained test on network named test. A model name also matches its
+// only retained scenario.
+func (m *RunManifest) Find(network, test string) (*RetainedTest, error) {
+	var scenarios []*RetainedTest
+
+	for _, retained := range m.Tests {
+		if retained.Network != network {
+			continue
+		}
+
+		if retained.Test == test {
+			return retained, nil
+		}
+
+		if retained.Model == test {
+			scenarios = append(scenarios, retained)
+		}
+	}
+
+	switch len(scenarios) {
+	case 0:
+		return nil, fmt.Errorf("%w %s on %s", errNoRetainedTest, test, network)
+	case 1:
+		return scenarios[0], nil
+	default:
+		names := make([]string, 0, len(scenarios))
+		for _, scenario := range scenarios {
+			names = append(names, scenario.Test)
+		}
+
+		return nil, fmt.Errorf("%w %s: %s", errAmbiguousRetainedTest, test, strings.Join(names, ", "))
+	}
+}
+
+// retainedAssertions renders the assertions of a test against database, marking the ones that failed.
+func retainedAssertions(assertions []*testdef.Assertion, database string, results *assertion.RunResult) []RetainedAssertion {
+	failed := make(map[string]bool)
+	if results != nil {
+		for _, result := range results.Results {
+			if !result.Passed {
+				failed[result.Name] = true
+			}
+		}
+	}
+
+	retained := make([]RetainedAssertion, 0, len(assertions))
+	for _, a := range assertions {
+		retained = append(retained, RetainedAssertion{
+			Name:   a.Name,
+			SQL:    assertion.RenderQuery(a.SQL, database),
+			Failed: failed[a.Name],
+		})
+	}
+
+	return retained
+}
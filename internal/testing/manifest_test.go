@@ -0,0 +1,132 @@
+package testing
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/assertion"
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunManifest_SaveLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "runs", "manifest.json")
+
+	empty, err := LoadManifest(path)
+	require.NoError(t, err)
+	require.Empty(t, empty.Tests)
+
+	manifest := &RunManifest{Tests: []*RetainedTest{{
+		Test:         "fct_block",
+		Model:        "fct_block",
+		Network:      "mainnet",
+		ExternalDB:   "ext_1",
+		CBTDB:        "cbt_1",
+		AssertionsOn: AssertionTargetCBT,
+		CBTConfig:    "clickhouse: {}\n",
+		Assertions:   []RetainedAssertion{{Name: "Row count", SQL: "SELECT 1", Failed: true}},
+		RecordedAt:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}}}
+	require.NoError(t, manifest.Save(path))
+
+	loaded, err := LoadManifest(path)
+	require.NoError(t, err)
+	require.Equal(t, manifest, loaded)
+}
+
+func TestRunManifest_Record(t *testing.T) {
+	t.Parallel()
+
+	manifest := &RunManifest{Tests: []*RetainedTest{
+		{Test: "fct_block", Network: "mainnet", CBTDB: "cbt_old"},
+		{Test: "fct_block_head", Network: "mainnet", CBTDB: "cbt_head"},
+		{Test: "fct_block", Network: "sepolia", CBTDB: "cbt_sepolia"},
+		{Test: "fct_reorg", Network: "mainnet", CBTDB: "cbt_reorg"},
+	}}
+
+	// fct_block failed again and fct_reorg passed, dropping its databases.
+	manifest.Record("mainnet", []string{"fct_block", "fct_reorg"}, []*RetainedTest{
+		{Test: "fct_block", Network: "mainnet", CBTDB: "cbt_new"},
+	})
+
+	got := make(map[string]string, len(manifest.Tests))
+	for _, test := range manifest.Tests {
+		got[test.Network+"/"+test.Test] = test.CBTDB
+	}
+
+	require.Equal(t, map[string]string{
+		"mainnet/fct_block":      "cbt_new",
+		"mainnet/fct_block_head": "cbt_head",
+		"sepolia/fct_block":      "cbt_sepolia",
+	}, got)
+}
+
+func TestRunManifest_Find(t *testing.T) {
+	t.Parallel()
+
+	manifest := &RunManifest{Tests: []*RetainedTest{
+		{Test: "fct_block", Model: "fct_block", Network: "mainnet"},
+		{Test: "fct_reorg[deep]", Model: "fct_reorg", Network: "mainnet"},
+		{Test: "fct_head[a]", Model: "fct_head", Network: "mainnet"},
+		{Test: "fct_head[b]", Model: "fct_head", Network: "mainnet"},
+	}}
+
+	tests := []struct {
+		name    string
+		network string
+		test    string
+		want    string
+		err     error
+	}{
+		{name: "model", network: "mainnet", test: "fct_block", want: "fct_block"},
+		{name: "scenario", network: "mainnet", test: "fct_head[b]", want: "fct_head[b]"},
+		{name: "only scenario of model", network: "mainnet", test: "fct_reorg", want: "fct_reorg[deep]"},
+		{name: "several scenarios", network: "mainnet", test: "fct_head", err: errAmbiguousRetainedTest},
+		{name: "other network", network: "sepolia", test: "fct_block", err: errNoRetainedTest},
+		{name: "missing", network: "mainnet", test: "fct_missing", err: errNoRetainedTest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := manifest.Find(tt.network, tt.test)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.Test)
+		})
+	}
+}
+
+func TestRetainedAssertions(t *testing.T) {
+	t.Parallel()
+
+	assertions := []*testdef.Assertion{
+		{Name: "Row count", SQL: "SELECT count() FROM default.fct_block FINAL"},
+		{Name: "Database", SQL: "SELECT '{database}'"},
+	}
+	results := &assertion.RunResult{Results: []*assertion.Result{
+		{Name: "Row count", Passed: false},
+		{Name: "Database", Passed: true},
+	}}
+
+	require.Equal(t, []RetainedAssertion{
+		{Name: "Row count", SQL: "SELECT count() FROM fct_block FINAL", Failed: true},
+		{Name: "Database", SQL: "SELECT 'cbt_1'"},
+	}, retainedAssertions(assertions, "cbt_1", results))
+
+	test := &RetainedTest{CBTDB: "cbt_1", AssertionsOn: AssertionTargetCBT, Assertions: retainedAssertions(assertions, "cbt_1", nil)}
+
+	var out bytes.Buffer
+	test.WriteAssertions(&out)
+	require.Equal(t, "-- Row count (passed)\nSELECT count() FROM fct_block FINAL\n\n-- Database (passed)\nSELECT 'cbt_1'\n\n-- run against cbt_1\n", out.String())
+}
@@ -33,10 +33,11 @@ type ParquetLoadMetric struct {
 
 // FailedAssertionDetail captures details about a single failed assertion.
 type FailedAssertionDetail struct {
-	Name     string
-	Expected map[string]interface{}
-	Actual   map[string]interface{}
-	Error    string
+	Name       string
+	Expected   map[string]interface{}
+	Actual     map[string]interface{}
+	Error      string
+	SampleRows []map[string]interface{} // Offending rows from the diagnostic query
 }
 
 // TestResultMetric captures metrics about a test execution.
@@ -49,6 +50,7 @@ type TestResultMetric struct {
 	AssertionsFailed int
 	ErrorMessage     string // empty if passed
 	FailedAssertions []FailedAssertionDetail
+	CBTLogPath       string // empty if no CBT logs were captured
 	Timestamp        time.Time
 }
 
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 
@@ -34,11 +35,14 @@ const (
 // ModelMetadata represents a parsed model with all cached metadata.
 // Parsing happens once during initialization to eliminate redundant file reads.
 type ModelMetadata struct {
-	Name          string   // Table name (inferred from filename or frontmatter)
-	ExecutionType string   // incremental, scheduled, or empty for external models
-	Dependencies  []string // List of table dependencies
-	SourceDB      string   // Source database for cross-database external models (empty = default)
-	SourceTable   string   // Actual table name in source database (empty = same as Name)
+	Name             string     // Table name (inferred from filename or frontmatter)
+	ExecutionType    string     // incremental, scheduled, or empty for external models
+	Dependencies     []string   // List of table dependencies
+	DependencyGroups [][]string // Dependencies as declared: all groups are required, any one option of a group
+	SourceDB         string     // Source database for cross-database external models (empty = default)
+	SourceTable      string     // Actual table name in source database (empty = same as Name)
+	FilePath         string     // Path of the model file the metadata was parsed from
+	IntervalType     string     // Interval type from frontmatter (slot, block, entity, ...)
 }
 
 // ExternalTableRef describes the source location of an external table in ClickHouse.
@@ -74,7 +78,10 @@ type Frontmatter struct {
 	Table        string        `yaml:"table"`
 	Type         string        `yaml:"type"`         // incremental or scheduled
 	Dependencies []interface{} `yaml:"dependencies"` // Can be []string or [][]string (OR dependencies)
-	Cache        *struct {
+	Interval     *struct {
+		Type string `yaml:"type"`
+	} `yaml:"interval,omitempty"`
+	Cache *struct {
 		IncrementalScanInterval string `yaml:"incremental_scan_interval"`
 		FullScanInterval        string `yaml:"full_scan_interval"`
 	} `yaml:"cache,omitempty"`
@@ -247,6 +254,31 @@ func (c *ModelCache) ResolveTestDependencies(testConfig *testdef.TestDefinition)
 	return deps, nil
 }
 
+// LeafExternalTables returns the external tables a model ultimately reads from, sorted by name.
+// An external model is its own leaf table.
+func (c *ModelCache) LeafExternalTables(model string) ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if _, ok := c.externalModels[model]; ok {
+		return []string{model}, nil
+	}
+
+	if _, ok := c.transformationModels[model]; !ok {
+		return nil, fmt.Errorf("model %s not found in external or transformation models", model) //nolint:err113 // Include model name for debugging
+	}
+
+	transformations, err := c.buildDependencyGraph(model, make(map[string]bool))
+	if err != nil {
+		return nil, fmt.Errorf("building dependency graph: %w", err)
+	}
+
+	externalTables, _ := c.extractLeafExternalTablesWithDependents(transformations)
+	sort.Strings(externalTables)
+
+	return externalTables, nil
+}
+
 // IsExternalModel checks if a model exists in the external models.
 func (c *ModelCache) IsExternalModel(name string) bool {
 	c.mu.RLock()
@@ -265,6 +297,20 @@ func (c *ModelCache) GetExternalModel(name string) *ModelMetadata {
 	return c.externalModels[name]
 }
 
+// ResolveDependency returns the name of the model a normalized dependency refers to,
+// resolving cross-database "database.table" references to their external model. Other
+// dependencies are returned as is.
+func (c *ModelCache) ResolveDependency(dep string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if name, isExternal := c.resolveExternalDependency(dep); isExternal {
+		return name
+	}
+
+	return dep
+}
+
 // GetTransformationModel returns the metadata for a transformation model, or nil if not found.
 func (c *ModelCache) GetTransformationModel(name string) *ModelMetadata {
 	c.mu.RLock()
@@ -301,6 +347,71 @@ func (c *ModelCache) ListAllModels() []string {
 	return models
 }
 
+// ModelNameForFile returns the name of the model parsed from the given file.
+// Files are matched by base name, so both absolute and repo-relative paths work.
+func (c *ModelCache) ModelNameForFile(path string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	base := filepath.Base(path)
+
+	for _, models := range []map[string]*ModelMetadata{c.transformationModels, c.externalModels} {
+		for name, model := range models {
+			if filepath.Base(model.FilePath) == base && filepath.Base(filepath.Dir(model.FilePath)) == filepath.Base(filepath.Dir(path)) {
+				return name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Dependents returns every transformation model that transitively depends on any of
+// the given models, walking the reverse dependency graph. The input models are not
+// included unless they depend on one another.
+func (c *ModelCache) Dependents(models ...string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// Build reverse edges: dependency -> models that depend on it.
+	reverse := make(map[string][]string, len(c.transformationModels))
+
+	for _, model := range c.transformationModels {
+		for _, dep := range model.Dependencies {
+			if canonical, isExternal := c.resolveExternalDependency(dep); isExternal {
+				dep = canonical
+			}
+
+			reverse[dep] = append(reverse[dep], model.Name)
+		}
+	}
+
+	var (
+		visited   = make(map[string]bool, len(models))
+		queue     = append([]string{}, models...)
+		dependent = make([]string, 0)
+	)
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, next := range reverse[current] {
+			if visited[next] {
+				continue
+			}
+
+			visited[next] = true
+			dependent = append(dependent, next)
+			queue = append(queue, next)
+		}
+	}
+
+	sort.Strings(dependent)
+
+	return dependent
+}
+
 // parseDirectory parses all model files in a directory.
 // Caller must not hold c.mu lock as this method doesn't acquire it.
 func (c *ModelCache) parseDirectory(_ context.Context, dir string, modelType ModelType) ([]*ModelMetadata, error) {
@@ -384,7 +495,7 @@ func (c *ModelCache) parseModel(path string, _ ModelType) (*ModelMetadata, error
 		modelName = frontmatter.Table
 	}
 
-	dependencies := c.normalizeDependencies(frontmatter.Dependencies)
+	dependencyGroups := c.normalizeDependencyGroups(frontmatter.Dependencies)
 
 	// Normalize execution type (incremental, scheduled, or empty)
 	executionType := strings.ToLower(strings.TrimSpace(frontmatter.Type))
@@ -401,12 +512,20 @@ func (c *ModelCache) parseModel(path string, _ ModelType) (*ModelMetadata, error
 		sourceTable = frontmatter.Table
 	}
 
+	var intervalType string
+	if frontmatter.Interval != nil {
+		intervalType = strings.ToLower(strings.TrimSpace(frontmatter.Interval.Type))
+	}
+
 	return &ModelMetadata{
-		Name:          modelName,
-		ExecutionType: executionType,
-		Dependencies:  dependencies,
-		SourceDB:      sourceDB,
-		SourceTable:   sourceTable,
+		Name:             modelName,
+		ExecutionType:    executionType,
+		Dependencies:     flattenDependencyGroups(dependencyGroups),
+		DependencyGroups: dependencyGroups,
+		SourceDB:         sourceDB,
+		SourceTable:      sourceTable,
+		FilePath:         path,
+		IntervalType:     intervalType,
 	}, nil
 }
 
@@ -435,25 +554,42 @@ func (c *ModelCache) extractFrontmatter(content string) (*Frontmatter, string, e
 	return &frontmatter, sql, nil
 }
 
-// normalizeDependencies converts raw frontmatter dependencies to normalized table names.
-// Handles both simple string dependencies and OR dependencies (nested lists).
-func (c *ModelCache) normalizeDependencies(raw []interface{}) []string {
-	deps := make([]string, 0, len(raw))
+// normalizeDependencyGroups converts raw frontmatter dependencies to groups of normalized
+// table names. A simple string dependency is a group of one; an OR dependency (nested
+// list) is a group of its options.
+func (c *ModelCache) normalizeDependencyGroups(raw []interface{}) [][]string {
+	groups := make([][]string, 0, len(raw))
 
 	for _, dep := range raw {
 		switch v := dep.(type) {
 		case string:
-			deps = append(deps, c.normalizeDependency(v))
+			groups = append(groups, []string{c.normalizeDependency(v)})
 		case []interface{}:
-			// OR dependency: flatten all options
+			options := make([]string, 0, len(v))
+
 			for _, orDep := range v {
 				if depStr, ok := orDep.(string); ok {
-					deps = append(deps, c.normalizeDependency(depStr))
+					options = append(options, c.normalizeDependency(depStr))
 				}
 			}
+
+			if len(options) > 0 {
+				groups = append(groups, options)
+			}
 		}
 	}
 
+	return groups
+}
+
+// flattenDependencyGroups lists every option of every dependency group.
+func flattenDependencyGroups(groups [][]string) []string {
+	deps := make([]string, 0, len(groups))
+
+	for _, group := range groups {
+		deps = append(deps, group...)
+	}
+
 	return deps
 }
 
@@ -30,3 +30,49 @@ SELECT 1
 	require.Equal(t, "observoor", model.SourceDB)
 	require.Equal(t, "cpu_utilization", model.SourceTable)
 }
+
+func TestParseModel_DependencyGroups(t *testing.T) {
+	t.Parallel()
+
+	modelPath := filepath.Join(t.TempDir(), "fct_block_blob_count_head.sql")
+
+	content := `---
+table: fct_block_blob_count_head
+type: incremental
+dependencies:
+  - "{{transformation}}.fct_block_head"
+  - [
+    "{{external}}.beacon_api_eth_v1_events_blob_sidecar",
+    "{{external}}.libp2p_gossipsub_blob_sidecar"
+  ]
+  - "observoor.cpu_utilization"
+---
+SELECT 1
+`
+	require.NoError(t, os.WriteFile(modelPath, []byte(content), 0o600))
+
+	cache := NewModelCache(logrus.New())
+	model, err := cache.parseModel(modelPath, ModelTypeTransformation)
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{
+		{"fct_block_head"},
+		{"beacon_api_eth_v1_events_blob_sidecar", "libp2p_gossipsub_blob_sidecar"},
+		{"observoor.cpu_utilization"},
+	}, model.DependencyGroups)
+	require.Equal(t, []string{
+		"fct_block_head",
+		"beacon_api_eth_v1_events_blob_sidecar",
+		"libp2p_gossipsub_blob_sidecar",
+		"observoor.cpu_utilization",
+	}, model.Dependencies)
+
+	cache.externalModels["observoor_cpu_utilization"] = &ModelMetadata{
+		Name:        "observoor_cpu_utilization",
+		SourceDB:    "observoor",
+		SourceTable: "cpu_utilization",
+	}
+
+	require.Equal(t, "observoor_cpu_utilization", cache.ResolveDependency("observoor.cpu_utilization"))
+	require.Equal(t, "fct_block_head", cache.ResolveDependency("fct_block_head"))
+}
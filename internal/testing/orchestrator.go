@@ -3,23 +3,31 @@ package testing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/ethpandaops/xatu-cbt/internal/testing/assertion"
 	"github.com/ethpandaops/xatu-cbt/internal/testing/output"
+	"github.com/ethpandaops/xatu-cbt/internal/testing/report"
 	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
 	"github.com/sirupsen/logrus"
 )
 
-// TestResult contains comprehensive test results for a single model.
+var errNoScenarioMatch = errors.New("no test definition has a matching scenario")
+
+// TestResult contains comprehensive test results for a single model or model scenario.
 type TestResult struct {
 	Model            string
+	Scenario         string
 	Network          string
 	ExternalTables   []string
 	ParquetURLs      map[string]string
@@ -28,6 +36,13 @@ type TestResult struct {
 	Duration         time.Duration
 	Success          bool
 	Error            error
+	CBTLogPath       string // CBT container logs of the test, filtered to its models
+	ChunkedDB        string // CBT database of the chunked run, if the chunking check cloned one
+}
+
+// Name identifies the test in output: the model, or model[scenario] for a scenario.
+func (r *TestResult) Name() string {
+	return testdef.TestID(r.Model, r.Scenario)
 }
 
 // OrchestratorConfig contains configuration for test orchestration.
@@ -45,24 +60,38 @@ type OrchestratorConfig struct {
 	AssertionRunner  assertion.Runner // For CBT cluster (transformation models)
 	XatuAssertion    assertion.Runner // For Xatu cluster (external models)
 	MigrationDir     string
+	Reports          []report.Target // Machine-readable reports written after each run
+	CheckIdempotency bool            // Reprocess passing incremental models and compare their rows
+	CheckChunking    bool            // Rerun passing incremental models in small intervals and compare their rows
+	ChunkIntervalMax uint64          // interval.max of the model in chunked runs (0 = one slot or block)
+	KeepFailed       bool            // Keep the databases of failing tests only, overriding CleanupTestDB
+	ManifestPath     string          // Run manifest recording kept databases (empty = none)
+	ArtifactsDir     string          // Directory for per-test artifacts such as CBT logs (empty = none)
 }
 
 // Orchestrator coordinates end-to-end test execution.
 // This is the concrete implementation without an interface abstraction.
 type Orchestrator struct {
-	configLoader    testdef.Loader
-	modelCache      *ModelCache
-	cache           *ParquetCache
-	dbManager       *DatabaseManager
-	cbtEngine       *CBTEngine
-	assertionRunner assertion.Runner // For CBT cluster (transformation models)
-	xatuAssertion   assertion.Runner // For Xatu cluster (external models)
-	migrationDir    string
-	log             logrus.FieldLogger
-	metrics         Collector
-	formatter       *output.Formatter
-	verbose         bool
-	cleanupTestDB   bool
+	configLoader     testdef.Loader
+	modelCache       *ModelCache
+	cache            *ParquetCache
+	dbManager        *DatabaseManager
+	cbtEngine        *CBTEngine
+	assertionRunner  assertion.Runner // For CBT cluster (transformation models)
+	xatuAssertion    assertion.Runner // For Xatu cluster (external models)
+	migrationDir     string
+	log              logrus.FieldLogger
+	metrics          Collector
+	formatter        *output.Formatter
+	verbose          bool
+	cleanupTestDB    bool
+	reports          []report.Target
+	idempotency      bool
+	chunking         bool
+	chunkIntervalMax uint64
+	keepFailed       bool
+	manifestPath     string
+	artifactsDir     string
 
 	// Template database tracking for per-test isolation
 	templatesPrepared bool
@@ -100,10 +129,11 @@ func (m *metricsAdapter) GetTestMetrics() []output.TestResultMetric {
 		failedAssertions := make([]output.FailedAssertionDetail, len(metric.FailedAssertions))
 		for j, fa := range metric.FailedAssertions {
 			failedAssertions[j] = output.FailedAssertionDetail{
-				Name:     fa.Name,
-				Expected: fa.Expected,
-				Actual:   fa.Actual,
-				Error:    fa.Error,
+				Name:       fa.Name,
+				Expected:   fa.Expected,
+				Actual:     fa.Actual,
+				Error:      fa.Error,
+				SampleRows: fa.SampleRows,
 			}
 		}
 		result[i] = output.TestResultMetric{
@@ -115,6 +145,7 @@ func (m *metricsAdapter) GetTestMetrics() []output.TestResultMetric {
 			AssertionsFailed: metric.AssertionsFailed,
 			ErrorMessage:     metric.ErrorMessage,
 			FailedAssertions: failedAssertions,
+			CBTLogPath:       metric.CBTLogPath,
 			Timestamp:        metric.Timestamp,
 		}
 	}
@@ -153,19 +184,26 @@ func NewOrchestrator(cfg *OrchestratorConfig) *Orchestrator {
 	)
 
 	return &Orchestrator{
-		configLoader:    cfg.ConfigLoader,
-		modelCache:      cfg.ModelCache,
-		cache:           cfg.ParquetCache,
-		dbManager:       cfg.DBManager,
-		cbtEngine:       cfg.CBTEngine,
-		assertionRunner: cfg.AssertionRunner,
-		xatuAssertion:   cfg.XatuAssertion,
-		migrationDir:    cfg.MigrationDir,
-		log:             cfg.Logger.WithField("component", "test_orchestrator"),
-		metrics:         cfg.MetricsCollector,
-		formatter:       outputFormatter,
-		verbose:         cfg.Verbose,
-		cleanupTestDB:   cfg.CleanupTestDB,
+		configLoader:     cfg.ConfigLoader,
+		modelCache:       cfg.ModelCache,
+		cache:            cfg.ParquetCache,
+		dbManager:        cfg.DBManager,
+		cbtEngine:        cfg.CBTEngine,
+		assertionRunner:  cfg.AssertionRunner,
+		xatuAssertion:    cfg.XatuAssertion,
+		migrationDir:     cfg.MigrationDir,
+		log:              cfg.Logger.WithField("component", "test_orchestrator"),
+		metrics:          cfg.MetricsCollector,
+		formatter:        outputFormatter,
+		verbose:          cfg.Verbose,
+		cleanupTestDB:    cfg.CleanupTestDB,
+		reports:          cfg.Reports,
+		idempotency:      cfg.CheckIdempotency,
+		chunking:         cfg.CheckChunking,
+		chunkIntervalMax: cfg.ChunkIntervalMax,
+		keepFailed:       cfg.KeepFailed,
+		manifestPath:     cfg.ManifestPath,
+		artifactsDir:     cfg.ArtifactsDir,
 	}
 }
 
@@ -197,30 +235,18 @@ func (o *Orchestrator) Start(ctx context.Context) error {
 		return fmt.Errorf("starting cbt engine: %w", err)
 	}
 
-	// Flush Redis once at startup to clear stale tasks from previous runs.
-	// This must happen BEFORE any tests run, not per-test (which would race).
-	if err := o.flushRedisCache(ctx); err != nil {
-		o.log.WithError(err).Warn("failed to flush Redis at startup (non-fatal)")
-	}
-
 	o.log.Info("test orchestrator started")
 
 	return nil
 }
 
-// Stop cleans up all orchestrator resources and flushes Redis cache once.
+// Stop cleans up all orchestrator resources. Stopping the CBT engine flushes and releases
+// its Redis DBs.
 func (o *Orchestrator) Stop() error {
 	o.log.Debug("stopping test orchestrator")
 
 	var errs []error
 
-	// Flush Redis cache once at shutdown.
-	flushCmd := exec.Command("docker", redisComposeArgs("FLUSHALL")...) //nolint:gosec // G204: Docker command with controlled arguments.
-	if err := flushCmd.Run(); err != nil {
-		o.log.WithError(err).Warn("failed to flush redis cache")
-		errs = append(errs, fmt.Errorf("flushing redis: %w", err))
-	}
-
 	// Stop all components in reverse order of start
 	if err := o.cbtEngine.Stop(); err != nil {
 		errs = append(errs, fmt.Errorf("stopping cbt engine: %w", err))
@@ -255,15 +281,19 @@ func (o *Orchestrator) Stop() error {
 
 // TestModels tests multiple models using grouped execution.
 // All tests for the same network share one database and one CBT container.
+// Models with scenarios run one test per scenario; a non-empty scenarios list
+// runs only the scenarios it names.
 func (o *Orchestrator) TestModels(
 	ctx context.Context,
 	network string,
 	modelNames []string,
+	scenarios []string,
 	concurrency int,
 ) ([]*TestResult, error) {
 	o.log.WithFields(logrus.Fields{
 		"network":     network,
 		"models":      modelNames,
+		"scenarios":   scenarios,
 		"concurrency": concurrency,
 	}).Info("testing models")
 
@@ -282,7 +312,12 @@ func (o *Orchestrator) TestModels(
 		testConfigs = append(testConfigs, testConfig)
 	}
 
-	return o.executeTestGroup(ctx, network, testConfigs, concurrency)
+	tests, err := o.expandScenarios(testConfigs, scenarios)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.executeTestGroup(ctx, network, tests, concurrency)
 }
 
 // TestAll tests all models for a network using grouped execution.
@@ -309,7 +344,67 @@ func (o *Orchestrator) TestAll(ctx context.Context, network string, concurrency
 		testConfigs = append(testConfigs, cfg)
 	}
 
-	return o.executeTestGroup(ctx, network, testConfigs, concurrency)
+	tests, err := o.expandScenarios(testConfigs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.executeTestGroup(ctx, network, tests, concurrency)
+}
+
+// expandScenarios flattens definitions into one test per scenario, keeping only the
+// named scenarios when any are given. Every named scenario must match a test, and the
+// models left without any test are logged.
+func (o *Orchestrator) expandScenarios(definitions []*testdef.TestDefinition, scenarios []string) ([]*testdef.TestDefinition, error) {
+	wanted := make(map[string]bool, len(scenarios))
+	for _, scenario := range scenarios {
+		wanted[scenario] = true
+	}
+
+	var (
+		tests    = make([]*testdef.TestDefinition, 0, len(definitions))
+		matched  = make(map[string]bool, len(scenarios))
+		filtered = make([]string, 0)
+	)
+
+	for _, definition := range definitions {
+		kept := false
+
+		for _, test := range definition.Tests() {
+			if len(wanted) == 0 || wanted[test.Scenario] {
+				tests = append(tests, test)
+				matched[test.Scenario] = true
+				kept = true
+			}
+		}
+
+		if !kept {
+			filtered = append(filtered, definition.Model)
+		}
+	}
+
+	unmatched := make([]string, 0)
+
+	for _, scenario := range scenarios {
+		if !matched[scenario] && !slices.Contains(unmatched, scenario) {
+			unmatched = append(unmatched, scenario)
+		}
+	}
+
+	if len(unmatched) > 0 {
+		return nil, fmt.Errorf("%w: %s", errNoScenarioMatch, strings.Join(unmatched, ","))
+	}
+
+	if len(filtered) > 0 {
+		sort.Strings(filtered)
+
+		o.log.WithFields(logrus.Fields{
+			"scenarios": scenarios,
+			"models":    filtered,
+		}).Warn("skipping models without a requested scenario")
+	}
+
+	return tests, nil
 }
 
 // preclonedDBs holds pre-cloned database names and resolved dependencies for a test.
@@ -326,9 +421,17 @@ func (o *Orchestrator) executeTestGroup(
 	network string,
 	testConfigs []*testdef.TestDefinition,
 	concurrency int,
-) ([]*TestResult, error) {
+) (results []*TestResult, err error) {
 	start := time.Now()
 
+	// Reports are written however the group ends, so a run that fails before its tests
+	// still leaves a report with the error for CI.
+	defer func() {
+		if reportErr := o.writeReports(network, results, err); reportErr != nil {
+			err = errors.Join(err, fmt.Errorf("writing reports: %w", reportErr))
+		}
+	}()
+
 	o.log.WithFields(logrus.Fields{
 		"network":     network,
 		"tests":       len(testConfigs),
@@ -354,11 +457,12 @@ func (o *Orchestrator) executeTestGroup(
 		"duration": time.Since(cloneStart),
 	}).Info("all test databases pre-cloned")
 
-	// Ensure cleanup of all pre-cloned databases
-	defer o.cleanupPreclonedDatabases(ctx, testDBs)
+	// Ensure cleanup of the pre-cloned databases that are not kept
+	resultsByTest := make(map[string]*TestResult, len(testConfigs))
+	defer o.releaseDatabases(ctx, network, testConfigs, testDBs, resultsByTest)
 
 	// Step 3: Run tests in parallel with worker pool (DBs already cloned)
-	results := make([]*TestResult, 0, len(testConfigs))
+	results = make([]*TestResult, 0, len(testConfigs))
 	resultChan := make(chan *TestResult, len(testConfigs))
 	sem := make(chan struct{}, concurrency)
 
@@ -375,7 +479,7 @@ func (o *Orchestrator) executeTestGroup(
 			defer func() { <-sem }()
 
 			// Get pre-cloned databases and deps for this test
-			dbs := testDBs[cfg.Model]
+			dbs := testDBs[cfg.ID()]
 
 			// Execute test with pre-cloned databases and pre-resolved deps
 			result := o.executeTestWithDBs(ctx, network, cfg, dbs.extDB, dbs.cbtDB, dbs.deps)
@@ -392,6 +496,7 @@ func (o *Orchestrator) executeTestGroup(
 	// Collect results
 	for result := range resultChan {
 		results = append(results, result)
+		resultsByTest[result.Name()] = result
 	}
 
 	o.log.WithFields(logrus.Fields{
@@ -407,6 +512,67 @@ func (o *Orchestrator) executeTestGroup(
 	return results, nil
 }
 
+// writeReports writes the configured machine-readable reports for a run, with runErr as
+// the error that ended it, if any.
+func (o *Orchestrator) writeReports(network string, results []*TestResult, runErr error) error {
+	if len(o.reports) == 0 {
+		return nil
+	}
+
+	r := &report.Report{
+		Network:      network,
+		GeneratedAt:  time.Now(),
+		Suites:       make([]*report.Suite, 0, len(results)),
+		ParquetLoads: make([]report.ParquetLoad, 0),
+	}
+
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+
+	for _, result := range results {
+		suite := &report.Suite{
+			Name:            result.Name(),
+			Network:         result.Network,
+			ExternalTables:  result.ExternalTables,
+			Transformations: result.Transformations,
+			Duration:        result.Duration,
+			Success:         result.Success,
+			Assertions:      result.AssertionResults,
+			CBTLog:          result.CBTLogPath,
+		}
+
+		if result.Error != nil {
+			suite.Error = result.Error.Error()
+		}
+
+		r.Suites = append(r.Suites, suite)
+	}
+
+	for _, metric := range o.metrics.GetParquetMetrics() {
+		r.ParquetLoads = append(r.ParquetLoads, report.ParquetLoad{
+			Table:     metric.Table,
+			Source:    string(metric.Source),
+			SizeBytes: metric.SizeBytes,
+			Duration:  metric.Duration,
+			Timestamp: metric.Timestamp,
+		})
+	}
+
+	if err := report.Write(r, o.reports); err != nil {
+		return err
+	}
+
+	for _, target := range o.reports {
+		o.log.WithFields(logrus.Fields{
+			"format": target.Format,
+			"path":   target.Path,
+		}).Info("wrote test report")
+	}
+
+	return nil
+}
+
 // precloneAllDatabases clones all test databases in parallel upfront.
 // It resolves dependencies first to clone only the needed tables per test.
 func (o *Orchestrator) precloneAllDatabases(
@@ -420,10 +586,10 @@ func (o *Orchestrator) precloneAllDatabases(
 	for _, cfg := range testConfigs {
 		deps, err := o.modelCache.ResolveTestDependencies(cfg)
 		if err != nil {
-			return nil, fmt.Errorf("resolving dependencies for %s: %w", cfg.Model, err)
+			return nil, fmt.Errorf("resolving dependencies for %s: %w", cfg.ID(), err)
 		}
 
-		testDBs[cfg.Model] = &preclonedDBs{deps: deps}
+		testDBs[cfg.ID()] = &preclonedDBs{deps: deps}
 	}
 
 	// Step 2: Clone databases in parallel with only needed tables
@@ -437,33 +603,9 @@ func (o *Orchestrator) precloneAllDatabases(
 
 	for _, cfg := range testConfigs {
 		testID := o.generateTestID()
-		model := cfg.Model
+		model := cfg.ID()
 		deps := testDBs[model].deps
-
-		// Merge external table refs from model deps + test definition's ExternalData.
-		// Build a map keyed by model name to deduplicate and enrich with cross-database info.
-		refMap := make(map[string]ExternalTableRef, len(deps.ExternalTableRefs))
-		for _, ref := range deps.ExternalTableRefs {
-			refMap[ref.ModelName] = ref
-		}
-
-		for tableName := range cfg.ExternalData {
-			if _, exists := refMap[tableName]; !exists {
-				// Table from test config without model metadata — use defaults (standard database)
-				ref := ExternalTableRef{ModelName: tableName}
-				if ext := o.modelCache.GetExternalModel(tableName); ext != nil {
-					ref.SourceDB = ext.SourceDB
-					ref.SourceTable = ext.SourceTable
-				}
-
-				refMap[tableName] = ref
-			}
-		}
-
-		extRefs := make([]ExternalTableRef, 0, len(refMap))
-		for _, ref := range refMap {
-			extRefs = append(extRefs, ref)
-		}
+		extRefs := o.externalTableRefs(cfg, deps)
 
 		// Clone external DB with only needed tables
 		wg.Add(1)
@@ -516,7 +658,7 @@ func (o *Orchestrator) precloneAllDatabases(
 
 	// Check for errors
 	for err := range errChan {
-		o.cleanupPreclonedDatabases(ctx, testDBs)
+		o.cleanupPreclonedDatabases(ctx, testDBs, nil)
 
 		return nil, err
 	}
@@ -524,27 +666,178 @@ func (o *Orchestrator) precloneAllDatabases(
 	return testDBs, nil
 }
 
-// cleanupPreclonedDatabases drops all pre-cloned databases.
-func (o *Orchestrator) cleanupPreclonedDatabases(ctx context.Context, testDBs map[string]*preclonedDBs) {
-	if !o.cleanupTestDB {
-		return
+// externalTableRefs merges the external table refs of a test's dependencies with the
+// tables of its external_data, deduplicated by model name and enriched with cross-database info.
+func (o *Orchestrator) externalTableRefs(cfg *testdef.TestDefinition, deps *Dependencies) []ExternalTableRef {
+	refMap := make(map[string]ExternalTableRef, len(deps.ExternalTableRefs))
+	for _, ref := range deps.ExternalTableRefs {
+		refMap[ref.ModelName] = ref
 	}
 
+	for tableName := range cfg.ExternalData {
+		if _, exists := refMap[tableName]; !exists {
+			// Table from test config without model metadata — use defaults (standard database)
+			ref := ExternalTableRef{ModelName: tableName}
+			if ext := o.modelCache.GetExternalModel(tableName); ext != nil {
+				ref.SourceDB = ext.SourceDB
+				ref.SourceTable = ext.SourceTable
+			}
+
+			refMap[tableName] = ref
+		}
+	}
+
+	extRefs := make([]ExternalTableRef, 0, len(refMap))
+	for _, ref := range refMap {
+		extRefs = append(extRefs, ref)
+	}
+
+	return extRefs
+}
+
+// keepDatabases reports whether the databases of a test are kept after the run.
+// A nil result means the test never ran.
+func (o *Orchestrator) keepDatabases(result *TestResult) bool {
+	switch {
+	case result == nil:
+		return !o.cleanupTestDB && !o.keepFailed
+	case o.keepFailed:
+		return !result.Success
+	default:
+		return !o.cleanupTestDB
+	}
+}
+
+// cleanupPreclonedDatabases drops the pre-cloned databases that are not kept,
+// given the results of the tests that ran.
+func (o *Orchestrator) cleanupPreclonedDatabases(
+	ctx context.Context,
+	testDBs map[string]*preclonedDBs,
+	results map[string]*TestResult,
+) {
 	for model, dbs := range testDBs {
+		if o.keepDatabases(results[model]) {
+			continue
+		}
+
 		if dbs.extDB != "" {
 			if err := o.dbManager.DropExternalDatabase(ctx, dbs.extDB); err != nil {
 				o.log.WithError(err).WithField("model", model).Warn("failed to drop external database")
 			}
 		}
 
-		if dbs.cbtDB != "" {
-			if err := o.dbManager.DropCBTDatabase(ctx, dbs.cbtDB); err != nil {
+		for _, cbtDB := range cbtDatabases(dbs, results[model]) {
+			if err := o.dbManager.DropCBTDatabase(ctx, cbtDB); err != nil {
 				o.log.WithError(err).WithField("model", model).Warn("failed to drop CBT database")
 			}
 		}
 	}
 }
 
+// cbtDatabases returns the CBT databases of a test: its pre-cloned one and, if the
+// chunking check ran, the chunked run's.
+func cbtDatabases(dbs *preclonedDBs, result *TestResult) []string {
+	databases := make([]string, 0, 2)
+
+	if dbs.cbtDB != "" {
+		databases = append(databases, dbs.cbtDB)
+	}
+
+	if result != nil && result.ChunkedDB != "" {
+		databases = append(databases, result.ChunkedDB)
+	}
+
+	return databases
+}
+
+// releaseDatabases drops the databases of a finished test group that are not kept
+// and records the kept ones in the run manifest.
+func (o *Orchestrator) releaseDatabases(
+	ctx context.Context,
+	network string,
+	testConfigs []*testdef.TestDefinition,
+	testDBs map[string]*preclonedDBs,
+	results map[string]*TestResult,
+) {
+	o.cleanupPreclonedDatabases(ctx, testDBs, results)
+
+	if o.manifestPath == "" {
+		return
+	}
+
+	ran := make([]string, 0, len(testConfigs))
+	retained := make([]*RetainedTest, 0)
+
+	for _, cfg := range testConfigs {
+		ran = append(ran, cfg.ID())
+
+		result, dbs := results[cfg.ID()], testDBs[cfg.ID()]
+		if result == nil || dbs == nil || !o.keepDatabases(result) {
+			continue
+		}
+
+		retained = append(retained, o.retainedTest(network, cfg, dbs, result))
+	}
+
+	manifest, err := LoadManifest(o.manifestPath)
+	if err != nil {
+		o.log.WithError(err).Warn("failed to load run manifest")
+
+		return
+	}
+
+	manifest.Record(network, ran, retained)
+
+	if err := manifest.Save(o.manifestPath); err != nil {
+		o.log.WithError(err).Warn("failed to save run manifest")
+
+		return
+	}
+
+	for _, test := range retained {
+		o.log.WithFields(logrus.Fields{
+			"test":       test.Test,
+			"ext_db":     test.ExternalDB,
+			"cbt_db":     test.CBTDB,
+			"chunked_db": test.ChunkedCBTDB,
+		}).Info("kept test databases, see xatu-cbt test inspect")
+	}
+}
+
+// retainedTest describes the kept databases of a test for the run manifest.
+func (o *Orchestrator) retainedTest(
+	network string,
+	cfg *testdef.TestDefinition,
+	dbs *preclonedDBs,
+	result *TestResult,
+) *RetainedTest {
+	retained := &RetainedTest{
+		Test:         cfg.ID(),
+		Model:        cfg.Model,
+		Network:      network,
+		Success:      result.Success,
+		ExternalDB:   dbs.extDB,
+		CBTDB:        dbs.cbtDB,
+		ChunkedCBTDB: result.ChunkedDB,
+		AssertionsOn: AssertionTargetCBT,
+		CBTConfig:    o.cbtEngine.RenderedConfig(dbs.cbtDB),
+		CBTLog:       result.CBTLogPath,
+		RecordedAt:   time.Now(),
+	}
+
+	if len(dbs.deps.TransformationModels) == 0 {
+		retained.AssertionsOn = AssertionTargetExternal
+	}
+
+	if result.Error != nil {
+		retained.Error = result.Error.Error()
+	}
+
+	retained.Assertions = retainedAssertions(cfg.Assertions, retained.Database(), result.AssertionResults)
+
+	return retained
+}
+
 // executeTestWithDBs runs a test using pre-cloned databases and pre-resolved dependencies.
 // This is used when databases are pre-cloned upfront for all tests.
 // Cleanup is handled at the group level, not per-test.
@@ -558,20 +851,24 @@ func (o *Orchestrator) executeTestWithDBs(
 	start := time.Now()
 
 	logCtx := o.log.WithFields(logrus.Fields{
-		"model":  testConfig.Model,
+		"model":  testConfig.ID(),
 		"ext_db": extDB,
 		"cbt_db": cbtDB,
 	})
 	logCtx.Info("executing test")
 
 	result := &TestResult{
-		Model:   testConfig.Model,
-		Network: network,
+		Model:    testConfig.Model,
+		Scenario: testConfig.Scenario,
+		Network:  network,
 	}
 
 	// Ensure metrics are recorded for ALL cases (including early errors)
 	defer func() {
 		result.Duration = time.Since(start)
+		// Releases the logs of every CBT run of the test: the normal run, the idempotency
+		// reprocess, which runs against cbtDB again, and the chunked run.
+		result.CBTLogPath = o.saveCBTLog(network, testConfig.ID(), cbtDB, result.ChunkedDB)
 		o.recordTestMetrics(result, testConfig)
 
 		switch {
@@ -589,44 +886,10 @@ func (o *Orchestrator) executeTestWithDBs(
 	result.ParquetURLs = deps.ParquetURLs
 	result.Transformations = extractModelNames(deps.TransformationModels)
 
-	// Step 2: Fetch and load parquet data.
-	// Standard external models load into the per-test ext database.
-	// Cross-database external models (e.g., observoor.cpu_utilization) load into the
-	// source database so the CBT bounds scan and dependency helpers resolve correctly.
-	standardURLs, crossDBLoads := splitParquetBySourceDB(deps)
-
-	if len(standardURLs) > 0 {
-		if loadErr := o.fetchAndLoadParquetData(ctx, extDB, standardURLs); loadErr != nil {
-			result.Error = loadErr
-			return result
-		}
-	}
-
-	for sourceDB, sourceURLs := range crossDBLoads {
-		if loadErr := o.fetchAndLoadParquetData(ctx, sourceDB, sourceURLs); loadErr != nil {
-			result.Error = loadErr
-			return result
-		}
-	}
-
-	// Step 2.5: Validate external data has rows after parquet load.
-	// Catches broken/empty parquets early before wasting time on CBT transformations.
-	if len(standardURLs) > 0 {
-		tables := make([]string, 0, len(standardURLs))
-		optionalTables := make(map[string]bool)
-
-		for tableName := range standardURLs {
-			tables = append(tables, tableName)
-
-			if ext, ok := testConfig.ExternalData[tableName]; ok && ext.Optional {
-				optionalTables[tableName] = true
-			}
-		}
-
-		if validateErr := o.dbManager.ValidateExternalData(ctx, extDB, tables, optionalTables); validateErr != nil {
-			result.Error = validateErr
-			return result
-		}
+	// Step 2: Fetch, load and validate parquet data
+	if loadErr := o.loadTestData(ctx, network, testConfig, extDB, deps); loadErr != nil {
+		result.Error = loadErr
+		return result
 	}
 
 	// Step 3: Run transformations (reads extDB, writes cbtDB)
@@ -643,10 +906,10 @@ func (o *Orchestrator) executeTestWithDBs(
 
 	if len(deps.TransformationModels) == 0 {
 		// External model test - use Xatu cluster runner with extDB
-		assertionResults, assertErr = o.xatuAssertion.RunAssertions(ctx, testConfig.Model, extDB, testConfig.Assertions)
+		assertionResults, assertErr = o.xatuAssertion.RunAssertions(ctx, testConfig.ID(), extDB, testConfig.Assertions)
 	} else {
 		// Transformation model test - use CBT cluster runner with cbtDB
-		assertionResults, assertErr = o.assertionRunner.RunAssertions(ctx, testConfig.Model, cbtDB, testConfig.Assertions)
+		assertionResults, assertErr = o.assertionRunner.RunAssertions(ctx, testConfig.ID(), cbtDB, testConfig.Assertions)
 	}
 
 	if assertErr != nil {
@@ -659,16 +922,91 @@ func (o *Orchestrator) executeTestWithDBs(
 	result.AssertionResults = assertionResults
 	result.Success = assertionResults.Failed == 0
 
+	// Step 5: Rerun in small intervals and compare, once the output is known to be correct.
+	// This runs before the idempotency check, which reprocesses the model in place.
+	if result.Success && o.chunking && len(deps.TransformationModels) > 0 {
+		chunkedDB, chunkingErr := o.checkChunking(ctx, network, cbtDB, extDB, testConfig.Model, deps)
+		result.ChunkedDB = chunkedDB
+
+		if chunkingErr != nil {
+			result.Error = fmt.Errorf("checking chunking: %w", chunkingErr)
+			result.Success = false
+		}
+	}
+
+	// Step 6: Reprocess and compare
+	if result.Success && o.idempotency && len(deps.TransformationModels) > 0 {
+		if idempotencyErr := o.checkIdempotency(ctx, network, cbtDB, extDB, testConfig.Model, deps); idempotencyErr != nil {
+			result.Error = fmt.Errorf("checking idempotency: %w", idempotencyErr)
+			result.Success = false
+		}
+	}
+
 	return result
 }
 
+// loadTestData loads a test's parquet fixtures and checks the external data has rows.
+// Standard external models load into the per-test ext database.
+// Cross-database external models (e.g., observoor.cpu_utilization) load into the
+// source database so the CBT bounds scan and dependency helpers resolve correctly.
+func (o *Orchestrator) loadTestData(
+	ctx context.Context,
+	network string,
+	testConfig *testdef.TestDefinition,
+	extDB string,
+	deps *Dependencies,
+) error {
+	standardURLs, crossDBLoads := splitParquetBySourceDB(deps)
+
+	if len(standardURLs) > 0 {
+		lookup := func(table string) *testdef.ExternalTable { return testConfig.ExternalData[table] }
+
+		if err := o.fetchAndLoadParquetData(ctx, extDB, network, standardURLs, lookup); err != nil {
+			return err
+		}
+	}
+
+	for sourceDB, sourceURLs := range crossDBLoads {
+		lookup := func(table string) *testdef.ExternalTable {
+			return crossDBExternalTable(testConfig, deps, sourceDB, table)
+		}
+
+		if err := o.fetchAndLoadParquetData(ctx, sourceDB, network, sourceURLs, lookup); err != nil {
+			return err
+		}
+	}
+
+	// Validate external data has rows after parquet load.
+	// Catches broken/empty parquets early before wasting time on CBT transformations.
+	if len(standardURLs) > 0 {
+		tables := make([]string, 0, len(standardURLs))
+		optionalTables := make(map[string]bool)
+
+		for tableName := range standardURLs {
+			tables = append(tables, tableName)
+
+			if ext, ok := testConfig.ExternalData[tableName]; ok && ext.Optional {
+				optionalTables[tableName] = true
+			}
+		}
+
+		if err := o.dbManager.ValidateExternalData(ctx, extDB, tables, optionalTables); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // fetchAndLoadParquetData fetches parquet files from cache and loads them into the database.
+// The lookup resolves each table to its test definition entry for network handling.
 func (o *Orchestrator) fetchAndLoadParquetData(
 	ctx context.Context,
-	database string,
+	database, network string,
 	parquetURLs map[string]string,
+	lookup func(table string) *testdef.ExternalTable,
 ) error {
-	localPaths := make(map[string]string, len(parquetURLs))
+	files := make(map[string]*ParquetFile, len(parquetURLs))
 
 	for tableName, url := range parquetURLs {
 		path, err := o.cache.Get(ctx, url, tableName)
@@ -676,10 +1014,26 @@ func (o *Orchestrator) fetchAndLoadParquetData(
 			return fmt.Errorf("fetching parquet file for %s: %w", tableName, err)
 		}
 
-		localPaths[tableName] = path
+		file := &ParquetFile{
+			Path:          path,
+			NetworkColumn: DefaultNetworkColumn,
+			NetworkMode:   testdef.NetworkModeFilter,
+		}
+
+		if ext := lookup(tableName); ext != nil {
+			if ext.NetworkColumn != "" {
+				file.NetworkColumn = ext.NetworkColumn
+			}
+
+			if ext.NetworkMode != "" {
+				file.NetworkMode = ext.NetworkMode
+			}
+		}
+
+		files[tableName] = file
 	}
 
-	if err := o.dbManager.LoadParquetData(ctx, database, localPaths); err != nil {
+	if err := o.dbManager.LoadParquetData(ctx, database, network, files); err != nil {
 		return fmt.Errorf("loading parquet data: %w", err)
 	}
 
@@ -698,10 +1052,7 @@ func (o *Orchestrator) runTestTransformations(
 		return nil
 	}
 
-	transformationNames := extractModelNames(deps.TransformationModels)
-	allModels := make([]string, 0, len(deps.ExternalTables)+len(transformationNames))
-	allModels = append(allModels, deps.ExternalTables...)
-	allModels = append(allModels, transformationNames...)
+	allModels, transformationNames := transformationRunModels(deps)
 
 	if err := o.cbtEngine.RunTransformations(ctx, network, cbtDB, extDB, allModels, transformationNames); err != nil {
 		return fmt.Errorf("running transformations: %w", err)
@@ -710,6 +1061,17 @@ func (o *Orchestrator) runTestTransformations(
 	return nil
 }
 
+// transformationRunModels returns the models to configure in CBT (external tables and
+// transformations) and the transformations to wait for.
+func transformationRunModels(deps *Dependencies) (allModels, transformationNames []string) {
+	transformationNames = extractModelNames(deps.TransformationModels)
+	allModels = make([]string, 0, len(deps.ExternalTables)+len(transformationNames))
+	allModels = append(allModels, deps.ExternalTables...)
+	allModels = append(allModels, transformationNames...)
+
+	return allModels, transformationNames
+}
+
 // generateTestID creates a unique identifier for a test execution.
 // Uses an atomic counter combined with timestamp to guarantee uniqueness even when
 // consecutive calls occur within the same nanosecond (common in tight loops).
@@ -748,17 +1110,18 @@ func (o *Orchestrator) recordTestMetrics(result *TestResult, testConfig *testdef
 				}
 
 				failedAssertions = append(failedAssertions, FailedAssertionDetail{
-					Name:     assertionResult.Name,
-					Expected: assertionResult.Expected,
-					Actual:   assertionResult.Actual,
-					Error:    errMsg,
+					Name:       assertionResult.Name,
+					Expected:   assertionResult.Expected,
+					Actual:     assertionResult.Actual,
+					Error:      errMsg,
+					SampleRows: assertionResult.SampleRows,
 				})
 			}
 		}
 	}
 
 	o.metrics.RecordTestResult(&TestResultMetric{
-		Model:            testConfig.Model,
+		Model:            testConfig.ID(),
 		Passed:           result.Success,
 		Duration:         result.Duration,
 		AssertionsTotal:  assertionsTotal,
@@ -766,10 +1129,56 @@ func (o *Orchestrator) recordTestMetrics(result *TestResult, testConfig *testdef
 		AssertionsFailed: assertionsFailed,
 		ErrorMessage:     errorMessage,
 		FailedAssertions: failedAssertions,
+		CBTLogPath:       result.CBTLogPath,
 		Timestamp:        time.Now(),
 	})
 }
 
+// saveCBTLog writes the CBT logs captured for a test's databases, in order, to the
+// artifacts directory and returns the file path, or an empty string if there are none or
+// no directory is set. The logs are released either way.
+func (o *Orchestrator) saveCBTLog(network, testID string, cbtDBs ...string) string {
+	var logs strings.Builder
+
+	for _, cbtDB := range cbtDBs {
+		if cbtDB != "" {
+			logs.WriteString(o.cbtEngine.TakeCBTLog(cbtDB))
+		}
+	}
+
+	if logs.Len() == 0 || o.artifactsDir == "" {
+		return ""
+	}
+
+	logPath := filepath.Join(o.artifactsDir, network, artifactFileName(testID)+".cbt.log")
+
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil { //nolint:gosec // G301: Artifacts are not sensitive
+		o.log.WithError(err).Warn("failed to create artifacts directory")
+
+		return ""
+	}
+
+	if err := os.WriteFile(logPath, []byte(logs.String()), 0o644); err != nil { //nolint:gosec // G306: Artifacts are not sensitive
+		o.log.WithError(err).WithField("test", testID).Warn("failed to write CBT log")
+
+		return ""
+	}
+
+	return logPath
+}
+
+// artifactFileName turns a test ID such as model[scenario] into a portable file name.
+func artifactFileName(testID string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, testID)
+}
+
 // splitParquetBySourceDB separates parquet URLs into standard loads (keyed by model name)
 // and cross-database loads (keyed by source database → source table name).
 // Cross-database external models (where SourceDB is set) need data loaded into their
@@ -801,6 +1210,22 @@ func splitParquetBySourceDB(deps *Dependencies) (standard map[string]string, cro
 	return standard, crossDB
 }
 
+// crossDBExternalTable maps a cross-database source table back to the test definition
+// entry of the external model that reads it.
+func crossDBExternalTable(
+	testConfig *testdef.TestDefinition,
+	deps *Dependencies,
+	sourceDB, sourceTable string,
+) *testdef.ExternalTable {
+	for _, ref := range deps.ExternalTableRefs {
+		if ref.SourceDB == sourceDB && ref.SourceTable == sourceTable {
+			return testConfig.ExternalData[ref.ModelName]
+		}
+	}
+
+	return nil
+}
+
 // extractModelNames extracts model names from ModelMetadata structs.
 func extractModelNames(models []*ModelMetadata) []string {
 	names := make([]string, 0, len(models))
@@ -886,15 +1311,3 @@ func (o *Orchestrator) ensureTemplatesPrepared(ctx context.Context, network stri
 
 	return nil
 }
-
-// flushRedisCache clears Redis cache to prevent stale cached values.
-func (o *Orchestrator) flushRedisCache(ctx context.Context) error {
-	cmd := exec.CommandContext(ctx, "docker", redisComposeArgs("FLUSHDB")...) //nolint:gosec // G204: Docker command with controlled arguments.
-	if cmdOutput, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("flushing redis: %w (output: %s)", err, string(cmdOutput))
-	}
-
-	o.log.Debug("flushed redis cache")
-
-	return nil
-}
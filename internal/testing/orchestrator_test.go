@@ -0,0 +1,201 @@
+package testing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/report"
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandScenarios(t *testing.T) {
+	t.Parallel()
+
+	baseDir := t.TempDir()
+	path := testdef.Path(baseDir, "mainnet", "fct_block")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+	require.NoError(t, os.WriteFile(path, []byte(`model: fct_block
+network: mainnet
+assertions:
+    - name: Row count
+      sql: SELECT COUNT(*) AS row_count FROM fct_block FINAL
+      row_count: {min: 1}
+scenarios:
+    - name: pectra
+    - name: reorg
+`), 0o600))
+
+	fctBlock, err := testdef.NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+	require.NoError(t, err)
+
+	fctHead := &testdef.TestDefinition{Model: "fct_block_head"}
+
+	tests := []struct {
+		name      string
+		scenarios []string
+		want      []string
+		err       error
+	}{
+		{name: "no filter", want: []string{"fct_block[pectra]", "fct_block[reorg]", "fct_block_head"}},
+		{name: "filter", scenarios: []string{"reorg"}, want: []string{"fct_block[reorg]"}},
+		{name: "unknown scenario", scenarios: []string{"fork"}, err: errNoScenarioMatch},
+		{name: "one unknown scenario", scenarios: []string{"pectar", "reorg"}, err: errNoScenarioMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			o := &Orchestrator{log: logrus.New()}
+
+			got, err := o.expandScenarios([]*testdef.TestDefinition{fctBlock, fctHead}, tt.scenarios)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				require.ErrorContains(t, err, tt.scenarios[0], "the error names the scenario that matched nothing")
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			ids := make([]string, 0, len(got))
+			for _, test := range got {
+				ids = append(ids, test.ID())
+			}
+
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestTestResultName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "fct_block", (&TestResult{Model: "fct_block"}).Name())
+	require.Equal(t, "fct_block[reorg]", (&TestResult{Model: "fct_block", Scenario: "reorg"}).Name())
+}
+
+func TestOrchestratorKeepDatabases(t *testing.T) {
+	t.Parallel()
+
+	passed := &TestResult{Success: true}
+	failed := &TestResult{Success: false}
+
+	tests := []struct {
+		name       string
+		cleanup    bool
+		keepFailed bool
+		result     *TestResult
+		want       bool
+	}{
+		{name: "default keeps passing", result: passed, want: true},
+		{name: "default keeps failing", result: failed, want: true},
+		{name: "default keeps unrun", want: true},
+		{name: "cleanup drops passing", cleanup: true, result: passed, want: false},
+		{name: "cleanup drops failing", cleanup: true, result: failed, want: false},
+		{name: "keep failed drops passing", keepFailed: true, result: passed, want: false},
+		{name: "keep failed keeps failing", keepFailed: true, result: failed, want: true},
+		{name: "keep failed drops unrun", keepFailed: true, want: false},
+		{name: "keep failed overrides cleanup", cleanup: true, keepFailed: true, result: failed, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			o := &Orchestrator{cleanupTestDB: tt.cleanup, keepFailed: tt.keepFailed}
+			require.Equal(t, tt.want, o.keepDatabases(tt.result))
+		})
+	}
+}
+
+func TestOrchestratorSaveCBTLog(t *testing.T) {
+	t.Parallel()
+
+	engine := NewCBTEngine(logrus.New(), nil, NewModelCache(logrus.New()), "", "", t.TempDir())
+	engine.recordCBTLog("cbt_1", "xatu-cbt-test-1", []byte("level=info model=cbt_1.fct_block\n"), []string{"fct_block"})
+	engine.recordCBTLog("cbt_1", "xatu-cbt-test-2", []byte("level=info msg=reprocessed model=cbt_1.fct_block\n"), []string{"fct_block"})
+
+	artifactsDir := t.TempDir()
+	o := &Orchestrator{cbtEngine: engine, artifactsDir: artifactsDir, log: logrus.New()}
+
+	logPath := o.saveCBTLog("mainnet", "fct_block[missing_slots]", "cbt_1")
+	require.Equal(t, filepath.Join(artifactsDir, "mainnet", "fct_block_missing_slots_.cbt.log"), logPath)
+
+	data, err := os.ReadFile(logPath) //nolint:gosec // G304: Test temp dir
+	require.NoError(t, err)
+	require.Contains(t, string(data), "=== xatu-cbt-test-1 ")
+	require.Contains(t, string(data), "msg=reprocessed", "a reprocess against the same database is included")
+	require.Empty(t, engine.TakeCBTLog("cbt_1"), "saved logs are released")
+
+	require.Empty(t, o.saveCBTLog("mainnet", "fct_block", "cbt_2"), "no logs captured")
+}
+
+func TestCBTDatabases(t *testing.T) {
+	t.Parallel()
+
+	dbs := &preclonedDBs{extDB: "ext_1", cbtDB: "cbt_1"}
+
+	require.Equal(t, []string{"cbt_1"}, cbtDatabases(dbs, nil), "test never ran")
+	require.Equal(t, []string{"cbt_1"}, cbtDatabases(dbs, &TestResult{Success: true}))
+	require.Equal(t, []string{"cbt_1", "cbt_2"}, cbtDatabases(dbs, &TestResult{ChunkedDB: "cbt_2"}))
+	require.Empty(t, cbtDatabases(&preclonedDBs{extDB: "ext_1"}, nil), "external model test")
+}
+
+func TestOrchestratorRetainedTest_Chunked(t *testing.T) {
+	t.Parallel()
+
+	engine := NewCBTEngine(logrus.New(), nil, NewModelCache(logrus.New()), "", "", t.TempDir())
+	o := &Orchestrator{cbtEngine: engine, keepFailed: true}
+
+	result := &TestResult{Model: "fct_block", ChunkedDB: "cbt_2", Error: errChunkedMismatch}
+	require.True(t, o.keepDatabases(result), "a failed chunking check keeps the chunked database")
+
+	retained := o.retainedTest("mainnet", &testdef.TestDefinition{Model: "fct_block"},
+		&preclonedDBs{extDB: "ext_1", cbtDB: "cbt_1", deps: &Dependencies{}}, result)
+	require.Equal(t, "cbt_1", retained.CBTDB)
+	require.Equal(t, "cbt_2", retained.ChunkedCBTDB)
+	require.Equal(t, errChunkedMismatch.Error(), retained.Error)
+}
+
+func TestOrchestratorSaveCBTLog_Chunked(t *testing.T) {
+	t.Parallel()
+
+	engine := NewCBTEngine(logrus.New(), nil, NewModelCache(logrus.New()), "", "", t.TempDir())
+	engine.recordCBTLog("cbt_1", "xatu-cbt-test-1", []byte("level=info model=cbt_1.fct_block\n"), []string{"fct_block"})
+	engine.recordCBTLog("cbt_2", "xatu-cbt-test-2", []byte("level=info model=cbt_2.fct_block\n"), []string{"fct_block"})
+
+	artifactsDir := t.TempDir()
+	o := &Orchestrator{cbtEngine: engine, artifactsDir: artifactsDir, log: logrus.New()}
+
+	logPath := o.saveCBTLog("mainnet", "fct_block", "cbt_1", "cbt_2")
+
+	data, err := os.ReadFile(logPath) //nolint:gosec // G304: Test temp dir
+	require.NoError(t, err)
+	require.Less(t, strings.Index(string(data), "model=cbt_1.fct_block"), strings.Index(string(data), "model=cbt_2.fct_block"))
+
+	require.Empty(t, engine.TakeCBTLog("cbt_2"), "chunked run logs are released")
+	require.Empty(t, (&Orchestrator{cbtEngine: engine, log: logrus.New()}).saveCBTLog("mainnet", "fct_block", "cbt_1", ""))
+}
+
+func TestOrchestratorWriteReports_RunError(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	o := &Orchestrator{
+		log:     logrus.New(),
+		metrics: NewCollector(logrus.New()),
+		reports: []report.Target{{Format: report.FormatJSON, Path: path}},
+	}
+
+	require.NoError(t, o.writeReports("mainnet", nil, errors.New("pre-cloning databases: boom")))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"error": "pre-cloning databases: boom"`)
+}
@@ -62,6 +62,12 @@ func (f *Formatter) PrintSummary() {
 	_, _ = fmt.Fprintln(f.writer, output) // Ignore write errors to stdout
 }
 
+// PrintDiffResults prints a table of table comparisons between two versions of the models.
+func (f *Formatter) PrintDiffResults(diffMetrics []TableDiffMetric) {
+	output := FormatDiffResults(f.tableRenderer, diffMetrics)
+	_, _ = fmt.Fprintln(f.writer, output) // Ignore write errors to stdout
+}
+
 // formatDuration formats a duration for human-readable output.
 // Handles microseconds, milliseconds, seconds, and minutes.
 func formatDuration(d time.Duration) string {
@@ -78,8 +84,8 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%.1fm", d.Minutes())
 }
 
-// formatBytes converts bytes to human-readable format (KiB, MiB, GiB, etc.)
-func formatBytes(bytes int64) string {
+// FormatBytes converts bytes to human-readable format (KiB, MiB, GiB, etc.)
+func FormatBytes(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
 		return fmt.Sprintf("%d B", bytes)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
@@ -33,10 +34,11 @@ type ParquetLoadMetric struct {
 
 // FailedAssertionDetail captures details about a single failed assertion.
 type FailedAssertionDetail struct {
-	Name     string
-	Expected map[string]interface{}
-	Actual   map[string]interface{}
-	Error    string
+	Name       string
+	Expected   map[string]interface{}
+	Actual     map[string]interface{}
+	Error      string
+	SampleRows []map[string]interface{} // Offending rows from the diagnostic query
 }
 
 // TestResultMetric captures metrics about a test execution.
@@ -49,6 +51,7 @@ type TestResultMetric struct {
 	AssertionsFailed int
 	ErrorMessage     string
 	FailedAssertions []FailedAssertionDetail
+	CBTLogPath       string
 	Timestamp        time.Time
 }
 
@@ -64,6 +67,19 @@ type SummaryMetric struct {
 	TotalDataSize int64
 }
 
+// TableDiffMetric captures the comparison of one table between two versions of the models.
+type TableDiffMetric struct {
+	Test      string
+	Table     string
+	Identical bool
+	BaseRows  uint64
+	HeadRows  uint64
+	OnlyBase  uint64   // Rows produced only by the base version
+	OnlyHead  uint64   // Rows produced only by the head version
+	Details   []string // Schema and column differences
+	Error     string
+}
+
 // TableRenderer provides table rendering utilities using tablewriter.
 type TableRenderer struct {
 	log logrus.FieldLogger
@@ -201,7 +217,7 @@ func FormatParquetMetrics(renderer *TableRenderer, parquetMetrics []ParquetLoadM
 		rows = append(rows, []string{
 			metric.Table,
 			string(metric.Source),
-			formatBytes(metric.SizeBytes),
+			FormatBytes(metric.SizeBytes),
 			formatDuration(metric.Duration),
 		})
 	}
@@ -290,12 +306,17 @@ func formatFailureDetails(failedTests []TestResultMetric) string {
 func formatTestFailureDetails(builder *strings.Builder, test *TestResultMetric) {
 	if len(test.FailedAssertions) == 0 {
 		formatTestErrorMessage(builder, test.ErrorMessage)
-		return
 	}
 
 	for _, assertion := range test.FailedAssertions {
 		formatAssertionFailure(builder, &assertion)
 	}
+
+	if test.CBTLogPath != "" {
+		fmt.Fprintf(builder, "  %s: %s\n",
+			colorInfo("CBT logs"),
+			test.CBTLogPath)
+	}
 }
 
 func formatTestErrorMessage(builder *strings.Builder, errorMessage string) {
@@ -333,6 +354,33 @@ func formatAssertionFailure(builder *strings.Builder, assertion *FailedAssertion
 			colorFailure("Error"),
 			assertion.Error)
 	}
+
+	if len(assertion.SampleRows) > 0 {
+		fmt.Fprintf(builder, "    %s (%d):\n",
+			colorInfo("Sample rows"),
+			len(assertion.SampleRows))
+
+		for _, row := range assertion.SampleRows {
+			fmt.Fprintf(builder, "      %s\n", formatRow(row))
+		}
+	}
+}
+
+// formatRow renders a result row with sorted column names for stable output.
+func formatRow(row map[string]interface{}) string {
+	keys := make([]string, 0, len(row))
+	for key := range row {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, row[key]))
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
 }
 
 // FormatSummary formats summary statistics as a table.
@@ -376,8 +424,42 @@ func FormatSummary(renderer *TableRenderer, summary SummaryMetric) string {
 		{"Failed", failedValue},
 		{"Total Duration", formatDuration(summary.TotalDuration)},
 		{"Cache Hit Rate", cacheValue},
-		{"Total Data Loaded", formatBytes(summary.TotalDataSize)},
+		{"Total Data Loaded", FormatBytes(summary.TotalDataSize)},
 	}
 
 	return "\n" + colorHeader("▸ Summary") + "\n\n" + renderer.RenderToString(headers, rows)
 }
+
+// FormatDiffResults formats table comparisons between two versions of the models.
+func FormatDiffResults(renderer *TableRenderer, diffMetrics []TableDiffMetric) string {
+	if len(diffMetrics) == 0 {
+		return "No tables compared"
+	}
+
+	headers := []string{"Test", "Table", "Status", "Rows (base → head)", "Only Base", "Only Head", "Details"}
+	rows := make([][]string, 0, len(diffMetrics))
+
+	for _, metric := range diffMetrics {
+		status := colorSuccess("= SAME")
+		if !metric.Identical {
+			status = colorFailure("≠ DIFF")
+		}
+
+		details := strings.Join(metric.Details, "; ")
+		if metric.Error != "" {
+			details = colorFailure(metric.Error)
+		}
+
+		rows = append(rows, []string{
+			metric.Test,
+			metric.Table,
+			status,
+			fmt.Sprintf("%d → %d", metric.BaseRows, metric.HeadRows),
+			fmt.Sprintf("%d", metric.OnlyBase),
+			fmt.Sprintf("%d", metric.OnlyHead),
+			details,
+		})
+	}
+
+	return "\n" + colorHeader("▸ Diff Results") + "\n\n" + renderer.RenderToString(headers, rows)
+}
@@ -0,0 +1,198 @@
+package testing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var errFixtureDrift = errors.New("fixture drift")
+
+// schemaColumn is a column name and ClickHouse type from a table or parquet file schema.
+type schemaColumn struct {
+	Name string
+	Type string
+}
+
+// parquetInsertPlan describes how a parquet file maps onto a target table.
+type parquetInsertPlan struct {
+	// Columns are loaded by name from the parquet file, in table order.
+	Columns []string
+	// Missing table columns are absent from the parquet file and filled with table defaults.
+	Missing []string
+	// Extra parquet columns do not exist in the table and are dropped.
+	Extra []string
+}
+
+// planParquetInsert matches parquet columns to insertable table columns by name.
+// Columns whose types cannot be converted are reported as fixture drift.
+func planParquetInsert(table string, fileColumns, tableColumns []schemaColumn) (*parquetInsertPlan, error) {
+	fileTypes := make(map[string]string, len(fileColumns))
+	for _, col := range fileColumns {
+		fileTypes[col.Name] = col.Type
+	}
+
+	plan := &parquetInsertPlan{
+		Columns: make([]string, 0, len(tableColumns)),
+		Missing: make([]string, 0),
+		Extra:   make([]string, 0),
+	}
+
+	tableNames := make(map[string]bool, len(tableColumns))
+	incompatible := make([]string, 0)
+
+	for _, col := range tableColumns {
+		tableNames[col.Name] = true
+
+		fileType, ok := fileTypes[col.Name]
+		if !ok {
+			plan.Missing = append(plan.Missing, col.Name)
+
+			continue
+		}
+
+		if !typesCompatible(fileType, col.Type) {
+			incompatible = append(incompatible, fmt.Sprintf("%s (parquet %s, table %s)", col.Name, fileType, col.Type))
+
+			continue
+		}
+
+		plan.Columns = append(plan.Columns, col.Name)
+	}
+
+	for _, col := range fileColumns {
+		if !tableNames[col.Name] {
+			plan.Extra = append(plan.Extra, col.Name)
+		}
+	}
+
+	sort.Strings(plan.Extra)
+
+	if len(incompatible) > 0 {
+		return nil, fmt.Errorf("%w: table %s has incompatible column(s): %s",
+			errFixtureDrift, table, strings.Join(incompatible, ", "))
+	}
+
+	if len(plan.Columns) == 0 {
+		return nil, fmt.Errorf("%w: table %s shares no columns with the parquet file", errFixtureDrift, table)
+	}
+
+	return plan, nil
+}
+
+// typeFamily groups ClickHouse types by how values convert between them on insert.
+type typeFamily int
+
+const (
+	familyString typeFamily = iota
+	familyFixedString
+	familyNumeric
+	familyTime
+	familyEnum
+	familyArray
+	familyMap
+	familyTuple
+	familyOther
+)
+
+// typesCompatible reports whether a parquet column type can be inserted into a table
+// column type. Scalars convert freely except plain strings into numbers; nested types
+// must have the same shape and compatible element types.
+func typesCompatible(source, target string) bool {
+	sourceFamily, sourceArgs := classifyType(source)
+	targetFamily, targetArgs := classifyType(target)
+
+	switch {
+	case sourceFamily == familyArray || targetFamily == familyArray:
+		return sourceFamily == targetFamily && len(sourceArgs) == 1 && len(targetArgs) == 1 &&
+			typesCompatible(sourceArgs[0], targetArgs[0])
+	case sourceFamily == familyMap || targetFamily == familyMap:
+		return sourceFamily == targetFamily && len(sourceArgs) == 2 && len(targetArgs) == 2 &&
+			typesCompatible(sourceArgs[0], targetArgs[0]) && typesCompatible(sourceArgs[1], targetArgs[1])
+	case sourceFamily == familyTuple || targetFamily == familyTuple:
+		return sourceFamily == targetFamily && len(sourceArgs) == len(targetArgs)
+	case sourceFamily == familyString && targetFamily == familyNumeric:
+		// Binary encodings (e.g. UInt256 as FixedString) are fine, free text is not.
+		return false
+	default:
+		return true
+	}
+}
+
+// classifyType returns the family of a ClickHouse type and its type arguments,
+// unwrapping Nullable and LowCardinality.
+func classifyType(typ string) (typeFamily, []string) {
+	name, args := splitType(strings.TrimSpace(typ))
+
+	switch {
+	case name == "Nullable" || name == "LowCardinality":
+		if len(args) == 1 {
+			return classifyType(args[0])
+		}
+
+		return familyOther, nil
+	case name == "String":
+		return familyString, nil
+	case name == "FixedString", name == "UUID", name == "IPv4", name == "IPv6":
+		return familyFixedString, nil
+	case name == "Bool", strings.HasPrefix(name, "Int"), strings.HasPrefix(name, "UInt"),
+		strings.HasPrefix(name, "Float"), strings.HasPrefix(name, "Decimal"):
+		return familyNumeric, nil
+	case strings.HasPrefix(name, "Date"):
+		return familyTime, nil
+	case strings.HasPrefix(name, "Enum"):
+		return familyEnum, nil
+	case name == "Array":
+		return familyArray, args
+	case name == "Map":
+		return familyMap, args
+	case name == "Tuple", name == "Nested":
+		return familyTuple, args
+	default:
+		return familyOther, nil
+	}
+}
+
+// splitType splits "Name(arg1, arg2)" into its name and top-level arguments.
+func splitType(typ string) (string, []string) {
+	open := strings.IndexByte(typ, '(')
+	if open < 0 || !strings.HasSuffix(typ, ")") {
+		return typ, nil
+	}
+
+	var (
+		name  = typ[:open]
+		inner = typ[open+1 : len(typ)-1]
+		args  = make([]string, 0, 2)
+		depth = 0
+		start = 0
+	)
+
+	for i, r := range inner {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(inner[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	args = append(args, strings.TrimSpace(inner[start:]))
+
+	// Named tuple elements ("name Type") keep only the type.
+	if name == "Tuple" || name == "Nested" {
+		for i, arg := range args {
+			if space := strings.IndexByte(arg, ' '); space > 0 && !strings.Contains(arg[:space], "(") {
+				args[i] = strings.TrimSpace(arg[space+1:])
+			}
+		}
+	}
+
+	return name, args
+}
@@ -0,0 +1,84 @@
+package testing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanParquetInsert(t *testing.T) {
+	t.Parallel()
+
+	tableColumns := []schemaColumn{
+		{Name: "updated_date_time", Type: "DateTime"},
+		{Name: "slot", Type: "UInt32"},
+		{Name: "block_root", Type: "FixedString(66)"},
+		{Name: "meta_network_name", Type: "LowCardinality(String)"},
+		{Name: "new_column", Type: "UInt64"},
+	}
+
+	fileColumns := []schemaColumn{
+		{Name: "meta_network_name", Type: "Nullable(String)"},
+		{Name: "slot", Type: "Nullable(Int64)"},
+		{Name: "updated_date_time", Type: "Nullable(DateTime64(3, 'UTC'))"},
+		{Name: "block_root", Type: "Nullable(String)"},
+		{Name: "dropped_column", Type: "Nullable(String)"},
+	}
+
+	plan, err := planParquetInsert("canonical_beacon_block", fileColumns, tableColumns)
+	require.NoError(t, err)
+	require.Equal(t, []string{"updated_date_time", "slot", "block_root", "meta_network_name"}, plan.Columns)
+	require.Equal(t, []string{"new_column"}, plan.Missing)
+	require.Equal(t, []string{"dropped_column"}, plan.Extra)
+}
+
+func TestPlanParquetInsert_FixtureDrift(t *testing.T) {
+	t.Parallel()
+
+	tableColumns := []schemaColumn{
+		{Name: "slot", Type: "UInt32"},
+		{Name: "validators", Type: "Array(UInt32)"},
+	}
+
+	fileColumns := []schemaColumn{
+		{Name: "slot", Type: "Nullable(String)"},
+		{Name: "validators", Type: "Nullable(UInt32)"},
+	}
+
+	_, err := planParquetInsert("fct_block", fileColumns, tableColumns)
+	require.ErrorIs(t, err, errFixtureDrift)
+	require.ErrorContains(t, err, "fct_block")
+	require.ErrorContains(t, err, "slot (parquet Nullable(String), table UInt32)")
+	require.ErrorContains(t, err, "validators")
+}
+
+func TestTypesCompatible(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		source string
+		target string
+		want   bool
+	}{
+		{source: "Nullable(Int64)", target: "UInt32", want: true},
+		{source: "Nullable(UInt32)", target: "DateTime", want: true},
+		{source: "Nullable(String)", target: "LowCardinality(String)", want: true},
+		{source: "Nullable(FixedString(32))", target: "UInt256", want: true},
+		{source: "Nullable(Int8)", target: "Enum8('a' = 1, 'b' = 2)", want: true},
+		{source: "Array(Nullable(String))", target: "Array(LowCardinality(String))", want: true},
+		{source: "Map(String, Nullable(UInt64))", target: "Map(LowCardinality(String), UInt64)", want: true},
+		{source: "Tuple(a Nullable(String), b Nullable(Int32))", target: "Tuple(a String, b Int32)", want: true},
+		{source: "Nullable(String)", target: "UInt64", want: false},
+		{source: "Nullable(String)", target: "Array(String)", want: false},
+		{source: "Array(Nullable(String))", target: "Array(Float64)", want: false},
+		{source: "Tuple(a String)", target: "Tuple(a String, b Int32)", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source+"->"+tt.target, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, typesCompatible(tt.source, tt.target))
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/runtime"
+	"github.com/sirupsen/logrus"
+)
+
+var errNotEnoughRedisDBs = errors.New("no free redis DB for CBT containers (raise the databases setting of redis)")
+
+const (
+	// firstCBTRedisDB is the first Redis DB leased to CBT containers. DB 0 is left to other
+	// users of the platform Redis and holds the leases.
+	firstCBTRedisDB = 1
+	// redisLeaseTTL is how long a lease outlives a run that stopped renewing it, e.g. one
+	// that was killed.
+	redisLeaseTTL = time.Minute
+	// redisLeaseRenewal is how often a run renews its leases.
+	redisLeaseRenewal = 15 * time.Second
+)
+
+// redisLeaseKey is the key in DB 0 leasing a Redis DB to a test run.
+func redisLeaseKey(db int) string {
+	return fmt.Sprintf("xatu-cbt:test:redis-db:%d", db)
+}
+
+// redisDBs gives each concurrent CBT container a Redis DB of its own. CBT's Redis prefix
+// doesn't isolate containers sharing a DB: its task queues are asynq queues named after
+// the models, whose asynq:{<queue>}:* keys take no prefix, so a container would run
+// another's tasks. The DBs are leased in DB 0 for the whole run, so test runs sharing a
+// Redis never share a DB, and a leased DB is flushed as a whole before and after each
+// container uses it. Containers wait for a free DB, which bounds them to the configured
+// concurrency, or to the DBs the server has free when that is lower.
+type redisDBs struct {
+	owner string
+	size  int
+	dial  func(db int) (runtime.Redis, error)
+	free  chan int
+
+	mu      sync.Mutex
+	clients map[int]runtime.Redis // DB 0 and the leased DBs
+	leased  []int
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// newRedisDBs creates a pool of size Redis DBs on the server at redisURL. Nothing is
+// leased until lease is called.
+func newRedisDBs(redisURL string, size int) *redisDBs {
+	return &redisDBs{
+		owner: fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano()),
+		size:  size,
+		dial: func(db int) (runtime.Redis, error) {
+			return runtime.NewRedisClient(redisURL, db)
+		},
+		free:    make(chan int, size),
+		clients: make(map[int]runtime.Redis),
+	}
+}
+
+// client returns the client of db, creating it on first use.
+func (p *redisDBs) client(db int) (runtime.Redis, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if client, ok := p.clients[db]; ok {
+		return client, nil
+	}
+
+	client, err := p.dial(db)
+	if err != nil {
+		return nil, fmt.Errorf("creating redis client for db %d: %w", db, err)
+	}
+
+	p.clients[db] = client
+
+	return client, nil
+}
+
+// lease leases the first size Redis DBs no other run holds, flushing each, and keeps the
+// leases alive until release. DBs the server doesn't have end the search, leaving fewer
+// DBs, and so fewer concurrent containers, than size; leasing none is an error.
+func (p *redisDBs) lease(ctx context.Context, log logrus.FieldLogger) error {
+	leases, err := p.client(0)
+	if err != nil {
+		return err
+	}
+
+	leased := make([]int, 0, p.size)
+
+	for db := firstCBTRedisDB; len(leased) < p.size; db++ {
+		set, err := leases.SetNX(ctx, redisLeaseKey(db), p.owner, redisLeaseTTL)
+		if err != nil {
+			return fmt.Errorf("leasing redis db %d: %w", db, err)
+		}
+
+		if !set {
+			log.WithField("redis_db", db).Debug("redis db leased by another run")
+
+			continue
+		}
+
+		p.mu.Lock()
+		p.leased = append(p.leased, db)
+		p.mu.Unlock()
+
+		// Clears whatever a run that held the DB before left behind. Selecting a DB the
+		// server doesn't have fails with an error reply.
+		if _, err := p.flush(ctx, db); err != nil {
+			var redisErr *runtime.RedisError
+			if !errors.As(err, &redisErr) {
+				return fmt.Errorf("flushing redis db %d: %w", db, err)
+			}
+
+			p.unlease(ctx, leases, db)
+
+			if len(leased) == 0 {
+				return fmt.Errorf("%w: %w", errNotEnoughRedisDBs, err)
+			}
+
+			log.WithFields(logrus.Fields{
+				"redis_dbs":   len(leased),
+				"concurrency": p.size,
+			}).Warn("redis has fewer free DBs than the CBT concurrency, running fewer CBT containers at once (raise the databases setting of redis)")
+
+			break
+		}
+
+		leased = append(leased, db)
+		p.free <- db
+	}
+
+	log.WithField("redis_dbs", leased).Debug("leased redis dbs")
+
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go p.renew(leases, log)
+
+	return nil
+}
+
+// unlease gives up the lease of a DB the server doesn't have.
+func (p *redisDBs) unlease(ctx context.Context, leases runtime.Redis, db int) {
+	p.mu.Lock()
+	p.leased = slices.DeleteFunc(p.leased, func(leased int) bool { return leased == db })
+	client, ok := p.clients[db]
+	delete(p.clients, db)
+	p.mu.Unlock()
+
+	if ok {
+		_ = client.Close()
+	}
+
+	_, _ = leases.Unlink(ctx, redisLeaseKey(db))
+}
+
+// renew resets the ttl of the leases until stop is closed.
+func (p *redisDBs) renew(leases runtime.Redis, log logrus.FieldLogger) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(redisLeaseRenewal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), redisLeaseRenewal)
+
+		p.mu.Lock()
+		leased := append([]int(nil), p.leased...)
+		p.mu.Unlock()
+
+		for _, db := range leased {
+			exists, err := leases.PExpire(ctx, redisLeaseKey(db), redisLeaseTTL)
+			if err != nil {
+				log.WithError(err).WithField("redis_db", db).Warn("failed to renew redis db lease")
+
+				continue
+			}
+
+			if !exists {
+				log.WithField("redis_db", db).Warn("redis db lease expired")
+			}
+		}
+
+		cancel()
+	}
+}
+
+// take waits for a leased DB no container is using.
+func (p *redisDBs) take(ctx context.Context) (int, error) {
+	p.mu.Lock()
+	leased := len(p.leased)
+	p.mu.Unlock()
+
+	if leased == 0 {
+		return 0, errCBTEngineNotStarted
+	}
+
+	select {
+	case db := <-p.free:
+		return db, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// put returns a DB taken with take.
+func (p *redisDBs) put(db int) {
+	p.free <- db
+}
+
+// flush deletes every key of a leased DB.
+func (p *redisDBs) flush(ctx context.Context, db int) (int64, error) {
+	client, err := p.client(db)
+	if err != nil {
+		return 0, err
+	}
+
+	return runtime.UnlinkMatching(ctx, client, "*")
+}
+
+// release flushes the leased DBs, gives up their leases and closes the clients.
+func (p *redisDBs) release(ctx context.Context) error {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.done
+		p.stop = nil
+	}
+
+	p.mu.Lock()
+	leased := p.leased
+	p.leased = nil
+	p.mu.Unlock()
+
+	var errs []error
+
+	for _, db := range leased {
+		if _, err := p.flush(ctx, db); err != nil {
+			errs = append(errs, fmt.Errorf("flushing redis db %d: %w", db, err))
+		}
+
+		leases, err := p.client(0)
+		if err == nil {
+			_, err = leases.Unlink(ctx, redisLeaseKey(db))
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("releasing redis db %d: %w", db, err))
+		}
+	}
+
+	for len(p.free) > 0 {
+		<-p.free
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for db, client := range p.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing redis client for db %d: %w", db, err))
+		}
+
+		delete(p.clients, db)
+	}
+
+	return errors.Join(errs...)
+}
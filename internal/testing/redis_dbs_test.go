@@ -0,0 +1,133 @@
+package testing
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ethpandaops/xatu-cbt/internal/runtime"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedisServer returns a dial func serving DBs 0 to dbs-1 of an in-memory Redis with the
+// given keys per DB. Selecting any other DB fails as Redis does.
+func fakeRedisServer(dbs int, keys map[int][]string) (func(db int) (runtime.Redis, error), map[int]*fakeRedis) {
+	server := make(map[int]*fakeRedis, dbs)
+
+	for db := 0; db < dbs; db++ {
+		server[db] = &fakeRedis{keys: make(map[string]bool)}
+		for _, key := range keys[db] {
+			server[db].keys[key] = true
+		}
+	}
+
+	var mu sync.Mutex
+
+	return func(db int) (runtime.Redis, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r, ok := server[db]; ok {
+			return r, nil
+		}
+
+		return &fakeRedis{err: &runtime.RedisError{Message: "ERR DB index is out of range"}}, nil
+	}, server
+}
+
+func TestRedisDBs_Lease(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	dial, server := fakeRedisServer(4, map[int][]string{
+		0: {"platform:key", redisLeaseKey(1)},
+		1: {"asynq:{fct_block}:pending"},
+		2: {"asynq:{fct_block}:pending", "test:cbt_old:task"},
+	})
+
+	pool := newRedisDBs("", 2)
+	pool.dial = dial
+
+	require.NoError(t, pool.lease(ctx, logrus.New()))
+	require.Equal(t, []int{2, 3}, pool.leased, "a DB leased by another run is skipped")
+	require.Equal(t, map[string]bool{"asynq:{fct_block}:pending": true}, server[1].keys, "another run's DB is untouched")
+	require.Empty(t, server[2].keys, "stale keys of an earlier run are flushed")
+	require.Equal(t, map[string]bool{
+		"platform:key":   true,
+		redisLeaseKey(1): true,
+		redisLeaseKey(2): true,
+		redisLeaseKey(3): true,
+	}, server[0].keys)
+
+	db, err := pool.take(ctx)
+	require.NoError(t, err)
+	require.Contains(t, []int{2, 3}, db)
+
+	other, err := pool.take(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, db, other, "concurrent containers never share a DB")
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = pool.take(canceled)
+	require.ErrorIs(t, err, context.Canceled, "a third container waits for a free DB")
+
+	server[db].keys["asynq:{fct_block}:pending"] = true
+
+	deleted, err := pool.flush(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	pool.put(db)
+	pool.put(other)
+
+	server[other].keys["test:cbt_2:task"] = true
+
+	require.NoError(t, pool.release(ctx))
+	require.Empty(t, server[other].keys, "released DBs are flushed")
+	require.Equal(t, map[string]bool{"platform:key": true, redisLeaseKey(1): true}, server[0].keys)
+
+	_, err = pool.take(ctx)
+	require.ErrorIs(t, err, errCBTEngineNotStarted)
+}
+
+func TestRedisDBs_FewerThanConcurrency(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	dial, server := fakeRedisServer(4, map[int][]string{0: {redisLeaseKey(1)}})
+
+	pool := newRedisDBs("", 3)
+	pool.dial = dial
+
+	require.NoError(t, pool.lease(ctx, logrus.New()), "a server with too few DBs caps the concurrency")
+	require.Equal(t, []int{2, 3}, pool.leased)
+	require.Len(t, pool.free, 2)
+	require.Equal(t, map[string]bool{
+		redisLeaseKey(1): true,
+		redisLeaseKey(2): true,
+		redisLeaseKey(3): true,
+	}, server[0].keys, "the lease of the missing DB is given up")
+
+	require.NoError(t, pool.release(ctx))
+	require.Equal(t, map[string]bool{redisLeaseKey(1): true}, server[0].keys)
+}
+
+func TestRedisDBs_NoneFree(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	dial, server := fakeRedisServer(2, map[int][]string{0: {redisLeaseKey(1)}})
+
+	pool := newRedisDBs("", 3)
+	pool.dial = dial
+
+	require.ErrorIs(t, pool.lease(ctx, logrus.New()), errNotEnoughRedisDBs)
+	require.Empty(t, pool.leased)
+	require.Equal(t, map[string]bool{redisLeaseKey(1): true}, server[0].keys)
+}
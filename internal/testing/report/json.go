@@ -0,0 +1,146 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type jsonReport struct {
+	Network      string            `json:"network"`
+	GeneratedAt  time.Time         `json:"generated_at"`
+	Error        string            `json:"error,omitempty"`
+	Summary      jsonSummary       `json:"summary"`
+	Tests        []jsonTest        `json:"tests"`
+	ParquetLoads []jsonParquetLoad `json:"parquet_loads"`
+}
+
+type jsonSummary struct {
+	Total      int     `json:"total"`
+	Passed     int     `json:"passed"`
+	Failed     int     `json:"failed"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+type jsonTest struct {
+	Model            string          `json:"model"`
+	Network          string          `json:"network"`
+	Passed           bool            `json:"passed"`
+	DurationMs       float64         `json:"duration_ms"`
+	Error            string          `json:"error,omitempty"`
+	CBTLog           string          `json:"cbt_log,omitempty"`
+	ExternalTables   []string        `json:"external_tables,omitempty"`
+	Transformations  []string        `json:"transformations,omitempty"`
+	AssertionsTotal  int             `json:"assertions_total"`
+	AssertionsPassed int             `json:"assertions_passed"`
+	AssertionsFailed int             `json:"assertions_failed"`
+	Assertions       []jsonAssertion `json:"assertions"`
+}
+
+type jsonAssertion struct {
+	Name       string                   `json:"name"`
+	Passed     bool                     `json:"passed"`
+	DurationMs float64                  `json:"duration_ms"`
+	Expected   map[string]interface{}   `json:"expected,omitempty"`
+	Actual     map[string]interface{}   `json:"actual,omitempty"`
+	Diff       []string                 `json:"diff,omitempty"`
+	Error      string                   `json:"error,omitempty"`
+	SampleRows []map[string]interface{} `json:"sample_rows,omitempty"`
+}
+
+type jsonParquetLoad struct {
+	Table      string    `json:"table"`
+	Source     string    `json:"source"`
+	SizeBytes  int64     `json:"size_bytes"`
+	DurationMs float64   `json:"duration_ms"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// WriteJSON renders the report as indented JSON.
+func WriteJSON(w io.Writer, r *Report) error {
+	out := jsonReport{
+		Network:      r.Network,
+		GeneratedAt:  r.GeneratedAt.UTC(),
+		Error:        r.Error,
+		Tests:        make([]jsonTest, 0, len(r.Suites)),
+		ParquetLoads: make([]jsonParquetLoad, 0, len(r.ParquetLoads)),
+	}
+
+	var total time.Duration
+
+	for _, suite := range r.Suites {
+		out.Tests = append(out.Tests, buildJSONTest(suite))
+		out.Summary.Total++
+
+		if suite.Success {
+			out.Summary.Passed++
+		} else {
+			out.Summary.Failed++
+		}
+
+		total += suite.Duration
+	}
+
+	out.Summary.DurationMs = milliseconds(total)
+
+	for _, load := range r.ParquetLoads {
+		out.ParquetLoads = append(out.ParquetLoads, jsonParquetLoad{
+			Table:      load.Table,
+			Source:     load.Source,
+			SizeBytes:  load.SizeBytes,
+			DurationMs: milliseconds(load.Duration),
+			Timestamp:  load.Timestamp.UTC(),
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(out); err != nil {
+		return fmt.Errorf("encoding json report: %w", err)
+	}
+
+	return nil
+}
+
+func buildJSONTest(suite *Suite) jsonTest {
+	test := jsonTest{
+		Model:           suite.Name,
+		Network:         suite.Network,
+		Passed:          suite.Success,
+		DurationMs:      milliseconds(suite.Duration),
+		Error:           suite.Error,
+		CBTLog:          suite.CBTLog,
+		ExternalTables:  suite.ExternalTables,
+		Transformations: suite.Transformations,
+		Assertions:      make([]jsonAssertion, 0),
+	}
+
+	if suite.Assertions == nil {
+		return test
+	}
+
+	test.AssertionsTotal = suite.Assertions.Total
+	test.AssertionsPassed = suite.Assertions.Passed
+	test.AssertionsFailed = suite.Assertions.Failed
+
+	for _, result := range suite.Assertions.Results {
+		test.Assertions = append(test.Assertions, jsonAssertion{
+			Name:       result.Name,
+			Passed:     result.Passed,
+			DurationMs: milliseconds(result.Duration),
+			Expected:   result.Expected,
+			Actual:     result.Actual,
+			Diff:       diffValues(result.Expected, result.Actual),
+			Error:      errString(result.Error),
+			SampleRows: result.SampleRows,
+		})
+	}
+
+	return test
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000.0
+}
@@ -0,0 +1,236 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// parquetSuiteName is the JUnit suite that lists parquet files loaded during the run.
+	parquetSuiteName = "parquet_loads"
+	// runSuiteName is the JUnit suite that reports an error of the run as a whole.
+	runSuiteName = "run"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit renders the report as JUnit XML. Each model becomes a testsuite and
+// each assertion a testcase; setup errors are reported as a single errored testcase.
+// An error of the run as a whole gets a testsuite of its own.
+func WriteJUnit(w io.Writer, r *Report) error {
+	root := junitTestSuites{
+		Name:   fmt.Sprintf("xatu-cbt %s", r.Network),
+		Suites: make([]junitTestSuite, 0, len(r.Suites)+1),
+	}
+
+	var total time.Duration
+
+	for _, suite := range r.Suites {
+		js := buildJUnitSuite(suite, r.GeneratedAt)
+
+		root.Tests += js.Tests
+		root.Failures += js.Failures
+		root.Errors += js.Errors
+		total += suite.Duration
+
+		root.Suites = append(root.Suites, js)
+	}
+
+	if r.Error != "" {
+		root.Suites = append(root.Suites, buildRunSuite(r.Error, r.GeneratedAt))
+		root.Tests++
+		root.Errors++
+	}
+
+	if len(r.ParquetLoads) > 0 {
+		root.Suites = append(root.Suites, buildParquetSuite(r.ParquetLoads))
+		root.Tests += len(r.ParquetLoads)
+	}
+
+	root.Time = formatSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("writing xml header: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(root); err != nil {
+		return fmt.Errorf("encoding junit xml: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("writing junit xml: %w", err)
+	}
+
+	return nil
+}
+
+func buildJUnitSuite(suite *Suite, generatedAt time.Time) junitTestSuite {
+	js := junitTestSuite{
+		Name:      suite.Name,
+		Time:      formatSeconds(suite.Duration),
+		Timestamp: generatedAt.UTC().Format(time.RFC3339),
+		Properties: []junitProperty{
+			{Name: "network", Value: suite.Network},
+		},
+	}
+
+	for _, table := range suite.ExternalTables {
+		js.Properties = append(js.Properties, junitProperty{Name: "external_table", Value: table})
+	}
+
+	for _, transformation := range suite.Transformations {
+		js.Properties = append(js.Properties, junitProperty{Name: "transformation", Value: transformation})
+	}
+
+	if suite.CBTLog != "" {
+		js.Properties = append(js.Properties, junitProperty{Name: "cbt_log", Value: suite.CBTLog})
+	}
+
+	// The test failed before assertions could run (parquet load, CBT, etc).
+	if suite.Assertions == nil {
+		message := suite.Error
+		if message == "" && !suite.Success {
+			message = "test failed (no details available)"
+		}
+
+		tc := junitTestCase{
+			Name:      "setup",
+			ClassName: suite.Name,
+			Time:      formatSeconds(suite.Duration),
+		}
+
+		if message != "" {
+			tc.Error = &junitFailure{Message: message, Type: "error", Body: message}
+			js.Errors++
+		}
+
+		js.TestCases = append(js.TestCases, tc)
+		js.Tests++
+
+		return js
+	}
+
+	for _, result := range suite.Assertions.Results {
+		tc := junitTestCase{
+			Name:      result.Name,
+			ClassName: suite.Name,
+			Time:      formatSeconds(result.Duration),
+		}
+
+		if !result.Passed {
+			message := "assertion failed"
+			if result.Error != nil {
+				message = result.Error.Error()
+			}
+
+			tc.Failure = &junitFailure{Message: message, Type: "assertion", Body: failureBody(result)}
+			js.Failures++
+		}
+
+		js.TestCases = append(js.TestCases, tc)
+		js.Tests++
+	}
+
+	// An error after assertions ran (e.g. a post-assertion check) still fails the suite.
+	if suite.Error != "" {
+		js.TestCases = append(js.TestCases, junitTestCase{
+			Name:      "teardown",
+			ClassName: suite.Name,
+			Time:      formatSeconds(0),
+			Error:     &junitFailure{Message: suite.Error, Type: "error", Body: suite.Error},
+		})
+		js.Tests++
+		js.Errors++
+	}
+
+	return js
+}
+
+// buildRunSuite reports an error that ended the run, e.g. while preparing databases, as
+// a single errored testcase.
+func buildRunSuite(message string, generatedAt time.Time) junitTestSuite {
+	return junitTestSuite{
+		Name:      runSuiteName,
+		Tests:     1,
+		Errors:    1,
+		Time:      formatSeconds(0),
+		Timestamp: generatedAt.UTC().Format(time.RFC3339),
+		TestCases: []junitTestCase{{
+			Name:      "setup",
+			ClassName: runSuiteName,
+			Time:      formatSeconds(0),
+			Error:     &junitFailure{Message: message, Type: "error", Body: message},
+		}},
+	}
+}
+
+func buildParquetSuite(loads []ParquetLoad) junitTestSuite {
+	js := junitTestSuite{
+		Name:      parquetSuiteName,
+		Tests:     len(loads),
+		TestCases: make([]junitTestCase, 0, len(loads)),
+	}
+
+	var total time.Duration
+
+	for _, load := range loads {
+		total += load.Duration
+
+		js.TestCases = append(js.TestCases, junitTestCase{
+			Name:      fmt.Sprintf("%s (%s, %d bytes)", load.Table, load.Source, load.SizeBytes),
+			ClassName: fmt.Sprintf("%s.%s", parquetSuiteName, load.Source),
+			Time:      formatSeconds(load.Duration),
+		})
+	}
+
+	js.Time = formatSeconds(total)
+
+	return js
+}
+
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,246 @@
+// Package report writes machine-readable test reports (JUnit XML and JSON) so CI
+// systems can display per-model and per-assertion results.
+package report
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/assertion"
+)
+
+var (
+	errEmptyTarget       = errors.New("report target must be in the form format=path")
+	errUnknownFormat     = errors.New("unknown report format")
+	errDuplicateFormat   = errors.New("report format specified more than once")
+	errMissingReportPath = errors.New("report target is missing a path")
+)
+
+// Format is a machine-readable report format.
+type Format string
+
+const (
+	// FormatJUnit writes a JUnit XML report.
+	FormatJUnit Format = "junit"
+	// FormatJSON writes a JSON report.
+	FormatJSON Format = "json"
+)
+
+// Target is a single report destination parsed from the --report flag.
+type Target struct {
+	Format Format
+	Path   string
+}
+
+// Suite holds the results of a single model test.
+type Suite struct {
+	Name            string
+	Network         string
+	ExternalTables  []string
+	Transformations []string
+	Duration        time.Duration
+	Success         bool
+	Error           string
+	CBTLog          string // Path of the test's CBT container logs, if captured
+	Assertions      *assertion.RunResult
+}
+
+// ParquetLoad describes a parquet file fetched during the run.
+type ParquetLoad struct {
+	Table     string
+	Source    string
+	SizeBytes int64
+	Duration  time.Duration
+	Timestamp time.Time
+}
+
+// Report is the complete set of results for a test run.
+type Report struct {
+	Network      string
+	GeneratedAt  time.Time
+	Error        string // Error that ended the run before or around its tests, if any
+	Suites       []*Suite
+	ParquetLoads []ParquetLoad
+}
+
+// ParseTargets parses a report specification such as
+// "junit=report.xml,json=report.json" into report targets.
+func ParseTargets(spec string) ([]Target, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	var (
+		parts   = strings.Split(spec, ",")
+		targets = make([]Target, 0, len(parts))
+		seen    = make(map[Format]bool, len(parts))
+	)
+
+	for _, part := range parts {
+		format, path, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", errEmptyTarget, part)
+		}
+
+		f := Format(strings.ToLower(strings.TrimSpace(format)))
+		if f != FormatJUnit && f != FormatJSON {
+			return nil, fmt.Errorf("%w: %q (must be one of: junit, json)", errUnknownFormat, format)
+		}
+
+		if seen[f] {
+			return nil, fmt.Errorf("%w: %s", errDuplicateFormat, f)
+		}
+
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil, fmt.Errorf("%w: %s", errMissingReportPath, f)
+		}
+
+		seen[f] = true
+		targets = append(targets, Target{Format: f, Path: path})
+	}
+
+	return targets, nil
+}
+
+// Write renders the report to every target, with suites in name order. The caller's
+// report is left unchanged.
+func Write(r *Report, targets []Target) error {
+	sorted := *r
+	sorted.Suites = make([]*Suite, len(r.Suites))
+	copy(sorted.Suites, r.Suites)
+
+	sort.Slice(sorted.Suites, func(i, j int) bool {
+		return sorted.Suites[i].Name < sorted.Suites[j].Name
+	})
+
+	for _, target := range targets {
+		if err := writeTarget(&sorted, target); err != nil {
+			return fmt.Errorf("writing %s report to %s: %w", target.Format, target.Path, err)
+		}
+	}
+
+	return nil
+}
+
+func writeTarget(r *Report, target Target) error {
+	if dir := filepath.Dir(target.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gosec // G301: Report directory with standard permissions
+			return fmt.Errorf("creating report directory: %w", err)
+		}
+	}
+
+	file, err := os.Create(target.Path) //nolint:gosec // G304: Report path supplied by the user
+	if err != nil {
+		return fmt.Errorf("creating report file: %w", err)
+	}
+
+	switch target.Format {
+	case FormatJUnit:
+		err = WriteJUnit(file, r)
+	case FormatJSON:
+		err = WriteJSON(file, r)
+	}
+
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("closing report file: %w", closeErr)
+	}
+
+	return err
+}
+
+// failureBody renders the expected/actual diff of a failed assertion.
+func failureBody(result *assertion.Result) string {
+	var builder strings.Builder
+
+	if len(result.Expected) > 0 {
+		fmt.Fprintf(&builder, "expected: %s\n", formatValues(result.Expected))
+	}
+
+	if len(result.Actual) > 0 {
+		fmt.Fprintf(&builder, "actual:   %s\n", formatValues(result.Actual))
+	}
+
+	if diff := diffValues(result.Expected, result.Actual); len(diff) > 0 {
+		builder.WriteString("diff:\n")
+
+		for _, line := range diff {
+			fmt.Fprintf(&builder, "  %s\n", line)
+		}
+	}
+
+	if result.Error != nil {
+		fmt.Fprintf(&builder, "error: %s\n", result.Error)
+	}
+
+	if len(result.SampleRows) > 0 {
+		builder.WriteString("sample rows:\n")
+
+		for _, row := range result.SampleRows {
+			fmt.Fprintf(&builder, "  %s\n", formatValues(row))
+		}
+	}
+
+	return builder.String()
+}
+
+// formatValues renders a result map with sorted keys for stable output.
+func formatValues(values map[string]interface{}) string {
+	keys := sortedKeys(values)
+	pairs := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, values[key]))
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// diffValues returns one line per column whose expected value differs from the actual value.
+func diffValues(expected, actual map[string]interface{}) []string {
+	if len(expected) == 0 {
+		return nil
+	}
+
+	diff := make([]string, 0)
+
+	for _, key := range sortedKeys(expected) {
+		actualVal, ok := actual[key]
+		if !ok {
+			diff = append(diff, fmt.Sprintf("%s: expected %v, column missing", key, expected[key]))
+
+			continue
+		}
+
+		if fmt.Sprintf("%v", expected[key]) != fmt.Sprintf("%v", actualVal) {
+			diff = append(diff, fmt.Sprintf("%s: expected %v, got %v", key, expected[key], actualVal))
+		}
+	}
+
+	return diff
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,197 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/assertion"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTargets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    []Target
+		wantErr bool
+	}{
+		{
+			name: "empty spec",
+			spec: "",
+			want: nil,
+		},
+		{
+			name: "junit and json",
+			spec: "junit=out/report.xml, json=report.json",
+			want: []Target{
+				{Format: FormatJUnit, Path: "out/report.xml"},
+				{Format: FormatJSON, Path: "report.json"},
+			},
+		},
+		{
+			name:    "missing separator",
+			spec:    "junit",
+			wantErr: true,
+		},
+		{
+			name:    "unknown format",
+			spec:    "html=report.html",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate format",
+			spec:    "json=a.json,json=b.json",
+			wantErr: true,
+		},
+		{
+			name:    "missing path",
+			spec:    "json=",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseTargets(tt.spec)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func testReport() *Report {
+	return &Report{
+		Network:     "mainnet",
+		GeneratedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Suites: []*Suite{
+			{
+				Name:     "fct_block",
+				Network:  "mainnet",
+				Duration: 2 * time.Second,
+				CBTLog:   ".test_runs/run-20250101-000000/mainnet/fct_block.cbt.log",
+				Assertions: &assertion.RunResult{
+					Total:  2,
+					Passed: 1,
+					Failed: 1,
+					Results: []*assertion.Result{
+						{Name: "row count", Passed: true, Duration: time.Millisecond},
+						{
+							Name:     "bad rows",
+							Passed:   false,
+							Expected: map[string]interface{}{"bad_rows": 0},
+							Actual:   map[string]interface{}{"bad_rows": 37},
+							Error:    errors.New("assertion failed"),
+							SampleRows: []map[string]interface{}{
+								{"slot": 100, "block_root": ""},
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:    "fct_attestation",
+				Network: "mainnet",
+				Error:   "loading parquet data: boom",
+			},
+		},
+		ParquetLoads: []ParquetLoad{
+			{Table: "canonical_beacon_block", Source: "cache", SizeBytes: 1024, Duration: time.Second},
+		},
+	}
+}
+
+func TestWrite_LeavesReportUnchanged(t *testing.T) {
+	t.Parallel()
+
+	r := testReport()
+	path := filepath.Join(t.TempDir(), "report.json")
+
+	require.NoError(t, Write(r, []Target{{Format: FormatJSON, Path: path}}))
+	require.Equal(t, "fct_block", r.Suites[0].Name, "the caller's suites keep their order")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var written struct {
+		Tests []struct {
+			Model string `json:"model"`
+		} `json:"tests"`
+	}
+
+	require.NoError(t, json.Unmarshal(data, &written))
+	require.Equal(t, "fct_attestation", written.Tests[0].Model, "the report lists suites in name order")
+}
+
+func TestWriteJUnit(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJUnit(&buf, testReport()))
+
+	out := buf.String()
+	require.Contains(t, out, `<testsuites name="xatu-cbt mainnet" tests="4" failures="1" errors="1"`)
+	require.Contains(t, out, `<testcase name="bad rows" classname="fct_block"`)
+	require.Contains(t, out, "bad_rows: expected 0, got 37")
+	require.Contains(t, out, "sample rows:&#xA;  {block_root=, slot=100}")
+	require.Contains(t, out, `<testcase name="setup" classname="fct_attestation"`)
+	require.Contains(t, out, `<testsuite name="parquet_loads"`)
+	require.Contains(t, out, `<property name="cbt_log" value=".test_runs/run-20250101-000000/mainnet/fct_block.cbt.log">`)
+}
+
+func TestWriteJSON(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSON(&buf, testReport()))
+
+	var decoded jsonReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+
+	require.Equal(t, 2, decoded.Summary.Total)
+	require.Equal(t, 2, decoded.Summary.Failed)
+	require.Len(t, decoded.Tests, 2)
+	require.Equal(t, []string{"bad_rows: expected 0, got 37"}, decoded.Tests[0].Assertions[1].Diff)
+	require.Len(t, decoded.Tests[0].Assertions[1].SampleRows, 1)
+	require.Equal(t, ".test_runs/run-20250101-000000/mainnet/fct_block.cbt.log", decoded.Tests[0].CBTLog)
+	require.Empty(t, decoded.Tests[1].CBTLog)
+	require.Len(t, decoded.ParquetLoads, 1)
+}
+
+func TestWrite_RunError(t *testing.T) {
+	t.Parallel()
+
+	r := &Report{
+		Network:     "mainnet",
+		GeneratedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Error:       "pre-cloning databases: clickhouse unreachable",
+	}
+
+	var junit bytes.Buffer
+	require.NoError(t, WriteJUnit(&junit, r))
+	require.Contains(t, junit.String(), `<testsuites name="xatu-cbt mainnet" tests="1" failures="0" errors="1"`)
+	require.Contains(t, junit.String(), `<testcase name="setup" classname="run"`)
+	require.Contains(t, junit.String(), `message="pre-cloning databases: clickhouse unreachable"`)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSON(&buf, r))
+
+	var decoded jsonReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, "pre-cloning databases: clickhouse unreachable", decoded.Error)
+	require.Empty(t, decoded.Tests)
+}
@@ -0,0 +1,153 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ethpandaops/xatu-cbt/internal/config"
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+)
+
+// identifierPattern matches sorting key entries that are plain column names.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Scaffolder generates starting test definitions for models.
+// This is the concrete implementation without an interface abstraction.
+type Scaffolder struct {
+	modelCache *ModelCache
+	dbManager  *DatabaseManager
+	log        logrus.FieldLogger
+}
+
+// NewScaffolder creates a test definition scaffolder. The database manager must be started.
+func NewScaffolder(log logrus.FieldLogger, modelCache *ModelCache, dbManager *DatabaseManager) *Scaffolder {
+	return &Scaffolder{
+		modelCache: modelCache,
+		dbManager:  dbManager,
+		log:        log.WithField("component", "scaffolder"),
+	}
+}
+
+// Scaffold builds a test definition for a model with placeholder external_data URLs
+// and baseline assertions derived from the model's table DDL.
+func (s *Scaffolder) Scaffold(ctx context.Context, model, network string) (*testdef.TestDefinition, error) {
+	tables, err := s.modelCache.LeafExternalTables(model)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := s.dbManager.DescribeModelTable(ctx, model, s.modelCache.IsExternalModel(model))
+	if err != nil {
+		return nil, fmt.Errorf("reading table schema for %s: %w", model, err)
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"model":           model,
+		"external_tables": len(tables),
+		"sorting_key":     strings.Join(schema.SortingKey, ", "),
+	}).Info("scaffolding test definition")
+
+	return buildScaffold(model, network, tables, schema), nil
+}
+
+// buildScaffold assembles a test definition from a model's external tables and schema.
+func buildScaffold(model, network string, externalTables []string, schema *tableSchema) *testdef.TestDefinition {
+	definition := &testdef.TestDefinition{
+		Model:        model,
+		Network:      network,
+		ExternalData: make(map[string]*testdef.ExternalTable, len(externalTables)),
+		Assertions:   make([]*testdef.Assertion, 0, 3),
+	}
+
+	sort.Strings(externalTables)
+
+	for _, table := range externalTables {
+		definition.ExternalData[table] = &testdef.ExternalTable{
+			URL:           fmt.Sprintf("%s/%s/%s_%s.parquet", config.FixtureBaseURL, network, model, table),
+			NetworkColumn: DefaultNetworkColumn,
+		}
+	}
+
+	definition.Assertions = append(definition.Assertions, &testdef.Assertion{
+		Name: "Row count should be greater than zero",
+		SQL:  fmt.Sprintf("SELECT COUNT(*) AS row_count FROM %s FINAL\n", model),
+		Assertions: []*testdef.TypedCheck{
+			{Type: "greater_than", Column: "row_count", Value: 0},
+		},
+	})
+
+	columnTypes := make(map[string]string, len(schema.Columns))
+	for _, col := range schema.Columns {
+		columnTypes[col.Name] = col.Type
+	}
+
+	conditions := make([]string, 0, len(schema.SortingKey))
+	checked := make([]string, 0, len(schema.SortingKey))
+
+	for _, key := range schema.SortingKey {
+		if !identifierPattern.MatchString(key) {
+			continue
+		}
+
+		if condition := emptyCondition(key, columnTypes[key]); condition != "" {
+			conditions = append(conditions, condition)
+			checked = append(checked, key)
+		}
+	}
+
+	if len(conditions) > 0 {
+		definition.Assertions = append(definition.Assertions, &testdef.Assertion{
+			Name: fmt.Sprintf("No empty key columns (%s)", strings.Join(checked, ", ")),
+			SQL: fmt.Sprintf("SELECT COUNT(*) AS bad_rows FROM %s FINAL\nWHERE %s\n",
+				model, strings.Join(conditions, "\n   OR ")),
+			Assertions: []*testdef.TypedCheck{
+				{Type: "equals", Column: "bad_rows", Value: 0},
+			},
+		})
+	}
+
+	if len(schema.SortingKey) > 0 {
+		key := strings.Join(schema.SortingKey, ", ")
+
+		definition.Assertions = append(definition.Assertions, &testdef.Assertion{
+			Name: "No duplicate keys under FINAL",
+			SQL: fmt.Sprintf("SELECT COUNT(*) AS duplicate_keys FROM (\n"+
+				"    SELECT %s\n    FROM %s FINAL\n    GROUP BY %s\n    HAVING COUNT(*) > 1\n)\n",
+				key, model, key),
+			Assertions: []*testdef.TypedCheck{
+				{Type: "equals", Column: "duplicate_keys", Value: 0},
+			},
+		})
+	}
+
+	return definition
+}
+
+// emptyCondition returns a SQL condition matching empty values of a key column, or ""
+// when the type has no meaningful empty value (numbers, where zero is valid).
+func emptyCondition(column, typ string) string {
+	nullable := strings.HasPrefix(strings.TrimSpace(typ), "Nullable(")
+	family, _ := classifyType(typ)
+
+	var condition string
+
+	switch family {
+	case familyString, familyFixedString, familyArray, familyMap:
+		condition = fmt.Sprintf("empty(%s)", column)
+	case familyTime:
+		condition = fmt.Sprintf("toUnixTimestamp(%s) = 0", column)
+	}
+
+	switch {
+	case nullable && condition != "":
+		return fmt.Sprintf("%s IS NULL OR %s", column, condition)
+	case nullable:
+		return fmt.Sprintf("%s IS NULL", column)
+	default:
+		return condition
+	}
+}
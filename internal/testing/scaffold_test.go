@@ -0,0 +1,59 @@
+package testing
+
+import (
+	"testing"
+
+	"github.com/ethpandaops/xatu-cbt/internal/testing/testdef"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildScaffold(t *testing.T) {
+	t.Parallel()
+
+	schema := &tableSchema{
+		Database:   "cbt_template",
+		Table:      "fct_block_local",
+		SortingKey: []string{"slot_start_date_time", "block_root", "slot", "cityHash64(proposer)"},
+		Columns: []schemaColumn{
+			{Name: "slot_start_date_time", Type: "DateTime"},
+			{Name: "block_root", Type: "String"},
+			{Name: "slot", Type: "UInt32"},
+			{Name: "proposer", Type: "Nullable(String)"},
+		},
+	}
+
+	definition := buildScaffold("fct_block", "mainnet", []string{"canonical_beacon_block", "beacon_api_eth_v2_beacon_block"}, schema)
+
+	require.Len(t, definition.ExternalData, 2)
+	require.Equal(t,
+		"https://data.ethpandaops.io/xatu-cbt/tests/mainnet/fct_block_canonical_beacon_block.parquet",
+		definition.ExternalData["canonical_beacon_block"].URL,
+	)
+	require.Equal(t, DefaultNetworkColumn, definition.ExternalData["canonical_beacon_block"].NetworkColumn)
+
+	require.Len(t, definition.Assertions, 3)
+	require.Equal(t, "No empty key columns (slot_start_date_time, block_root)", definition.Assertions[1].Name)
+	require.Equal(t,
+		"SELECT COUNT(*) AS bad_rows FROM fct_block FINAL\nWHERE toUnixTimestamp(slot_start_date_time) = 0\n   OR empty(block_root)\n",
+		definition.Assertions[1].SQL,
+	)
+	require.Contains(t, definition.Assertions[2].SQL, "GROUP BY slot_start_date_time, block_root, slot, cityHash64(proposer)")
+
+	// The scaffold passes validation unchanged.
+	baseDir := t.TempDir()
+	require.NoError(t, testdef.Write(testdef.Path(baseDir, "mainnet", "fct_block"), definition))
+
+	loaded, err := testdef.NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+	require.NoError(t, err)
+	require.Len(t, loaded.Assertions, 3)
+	require.Equal(t, definition.Assertions[2].SQL, loaded.Assertions[2].SQL)
+}
+
+func TestSplitSortingKey(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"slot", "toStartOfDay(slot_start_date_time, 'UTC')", "block_root"},
+		splitSortingKey("slot, toStartOfDay(slot_start_date_time, 'UTC'), block_root"))
+	require.Nil(t, splitSortingKey(""))
+}
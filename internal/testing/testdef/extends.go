@@ -0,0 +1,164 @@
+package testdef
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CommonLayer is the directory under the tests base directory holding definitions
+// shared between networks. Network definitions pull them in with `extends: _common`.
+const CommonLayer = "_common"
+
+var (
+	errExtendsInvalid = errors.New("extends must be " + CommonLayer + " or " + CommonLayer + "/<model>")
+	errExtendsCycle   = errors.New("extends cycle")
+)
+
+// loadLayers reads the definition at path and merges it over the definitions it
+// extends, base first. chain holds the files already being loaded, for cycle detection.
+func (l *loader) loadLayers(path string, chain []string) (*TestDefinition, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Reading test config from trusted paths
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	var definition TestDefinition
+	if err := yaml.Unmarshal(data, &definition); err != nil {
+		return nil, fmt.Errorf("parsing yaml: %w", err)
+	}
+
+	definition.Sources = []string{path}
+
+	// Scenarios are merged by name across layers, so duplicates are caught per file.
+	if err := validateScenarios(definition.Scenarios); err != nil {
+		return nil, err
+	}
+
+	// Snapshots are relative to the file declaring the assertion.
+	resolveSnapshotPaths(definition.Assertions, path)
+
+	for _, scenario := range definition.Scenarios {
+		if scenario != nil {
+			resolveSnapshotPaths(scenario.Assertions, path)
+		}
+	}
+
+	if definition.Extends == "" {
+		return &definition, nil
+	}
+
+	basePath, err := l.extendsPath(&definition, path)
+	if err != nil {
+		return nil, err
+	}
+
+	chain = append(chain, path)
+	if slices.Contains(chain, basePath) {
+		return nil, fmt.Errorf("%w: %s -> %s", errExtendsCycle, strings.Join(chain, " -> "), basePath)
+	}
+
+	base, err := l.loadLayers(basePath, chain)
+	if err != nil {
+		return nil, fmt.Errorf("extending %s: %w", definition.Extends, err)
+	}
+
+	return mergeDefinitions(base, &definition), nil
+}
+
+// extendsPath resolves the extends reference of the definition at path. Only the common
+// layer can be extended, so a network's tests never depend on another network's fixtures.
+// A bare layer refers to the same model, defaulting to the file name when the definition
+// leaves its model to the base.
+func (l *loader) extendsPath(definition *TestDefinition, path string) (string, error) {
+	layer, model, found := strings.Cut(definition.Extends, "/")
+	if !found {
+		model = definition.Model
+		if model == "" {
+			model = strings.TrimSuffix(filepath.Base(path), ".yaml")
+		}
+	}
+
+	if layer != CommonLayer || model == "" || strings.Contains(model, "/") || strings.Contains(model, "..") {
+		return "", fmt.Errorf("%w: %q", errExtendsInvalid, definition.Extends)
+	}
+
+	return Path(l.baseDir, layer, model), nil
+}
+
+// mergeDefinitions overlays a definition on the one it extends. Scalars set in the
+// override win; external_data is overridden per table (a null entry removes the table);
+// assertions and scenarios are merged by name, replacing the base entry in place or
+// appending; uses are concatenated.
+func mergeDefinitions(base, override *TestDefinition) *TestDefinition {
+	merged := *base
+
+	if override.Model != "" {
+		merged.Model = override.Model
+	}
+
+	if override.Network != "" {
+		merged.Network = override.Network
+	}
+
+	merged.Extends = override.Extends
+
+	merged.ExternalData = make(map[string]*ExternalTable, len(base.ExternalData)+len(override.ExternalData))
+	for table, ext := range base.ExternalData {
+		merged.ExternalData[table] = ext
+	}
+
+	for table, ext := range override.ExternalData {
+		if ext == nil {
+			delete(merged.ExternalData, table)
+
+			continue
+		}
+
+		merged.ExternalData[table] = ext
+	}
+
+	merged.Assertions = slices.Clone(base.Assertions)
+
+	index := make(map[string]int, len(merged.Assertions))
+	for i, assertion := range merged.Assertions {
+		if assertion != nil {
+			index[assertion.Name] = i
+		}
+	}
+
+	for _, assertion := range override.Assertions {
+		if assertion == nil {
+			continue
+		}
+
+		if i, ok := index[assertion.Name]; ok {
+			merged.Assertions[i] = assertion
+
+			continue
+		}
+
+		index[assertion.Name] = len(merged.Assertions)
+		merged.Assertions = append(merged.Assertions, assertion)
+	}
+
+	merged.Scenarios = mergeScenarios(base.Scenarios, override.Scenarios)
+	merged.Uses = append(slices.Clone(base.Uses), override.Uses...)
+	merged.Sources = append(slices.Clone(base.Sources), override.Sources...)
+
+	return &merged
+}
+
+// resolveSnapshotPaths resolves unresolved snapshot paths against the directory of path.
+func resolveSnapshotPaths(assertions []*Assertion, path string) {
+	for _, assertion := range assertions {
+		if assertion != nil && assertion.Snapshot != "" && assertion.SnapshotPath == "" {
+			assertion.SnapshotPath = filepath.Join(filepath.Dir(path), filepath.FromSlash(assertion.Snapshot))
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package testdef
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+const commonFctBlock = `model: fct_block
+external_data:
+    canonical_beacon_block:
+        url: https://example.com/common/canonical_beacon_block.parquet
+        network_column: meta_network_name
+    beacon_api_eth_v2_beacon_block:
+        url: https://example.com/common/beacon_api_eth_v2_beacon_block.parquet
+        network_column: meta_network_name
+assertions:
+    - name: Row count
+      sql: SELECT COUNT(*) AS row_count FROM fct_block FINAL
+      row_count: 1
+    - name: Output
+      sql: SELECT slot FROM fct_block FINAL ORDER BY slot
+      snapshot: snapshots/fct_block.csv
+    - name: No empty block roots
+      sql: SELECT COUNT(*) AS bad_rows FROM fct_block FINAL WHERE block_root = ''
+      expected:
+        bad_rows: 0
+`
+
+func TestLoadForModel_Extends(t *testing.T) {
+	t.Parallel()
+
+	baseDir := t.TempDir()
+	writeDefinition(t, baseDir, CommonLayer, "fct_block", commonFctBlock)
+	writeDefinition(t, baseDir, "hoodi", "fct_block", `extends: _common
+network: hoodi
+external_data:
+    canonical_beacon_block:
+        url: https://example.com/hoodi/canonical_beacon_block.parquet
+        network_column: meta_network_name
+    beacon_api_eth_v2_beacon_block: null
+assertions:
+    - name: Row count
+      sql: SELECT COUNT(*) AS row_count FROM fct_block FINAL
+      row_count: {min: 10}
+    - name: Hoodi only
+      sql: SELECT COUNT(*) AS bad_rows FROM fct_block FINAL WHERE slot = 0
+      expect_empty: true
+`)
+
+	definition, err := NewLoader(logrus.New(), baseDir).LoadForModel("hoodi", "fct_block")
+	require.NoError(t, err)
+
+	require.Equal(t, "fct_block", definition.Model)
+	require.Equal(t, "hoodi", definition.Network)
+	require.Equal(t, []string{
+		Path(baseDir, CommonLayer, "fct_block"),
+		Path(baseDir, "hoodi", "fct_block"),
+	}, definition.Sources)
+
+	// external_data is overridden per table and null removes a table.
+	require.Len(t, definition.ExternalData, 1)
+	require.Equal(t, "https://example.com/hoodi/canonical_beacon_block.parquet", definition.ExternalData["canonical_beacon_block"].URL)
+
+	// Assertions are merged by name, keeping the base order.
+	names := make([]string, 0, len(definition.Assertions))
+	for _, assertion := range definition.Assertions {
+		names = append(names, assertion.Name)
+	}
+
+	require.Equal(t, []string{"Row count", "Output", "No empty block roots", "Hoodi only"}, names)
+	require.Equal(t, 10, *definition.Assertions[0].RowCount.Min)
+
+	// Snapshots stay relative to the file declaring them.
+	require.Equal(t,
+		filepath.Join(baseDir, CommonLayer, "models", "snapshots", "fct_block.csv"),
+		definition.Assertions[1].SnapshotPath,
+	)
+}
+
+func TestLoadForModel_ExtendsErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		common  map[string]string
+		extends string
+		err     error
+	}{
+		{name: "other network", extends: "mainnet", err: errExtendsInvalid},
+		{name: "path traversal", extends: "_common/../mainnet", err: errExtendsInvalid},
+		{
+			name:    "cycle",
+			extends: "_common",
+			common: map[string]string{
+				"fct_block":      "extends: _common/fct_block_head\n",
+				"fct_block_head": "extends: _common/fct_block\n",
+			},
+			err: errExtendsCycle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			baseDir := t.TempDir()
+			for model, content := range tt.common {
+				writeDefinition(t, baseDir, CommonLayer, model, content)
+			}
+
+			writeDefinition(t, baseDir, "mainnet", "fct_block", "extends: "+tt.extends+"\nnetwork: mainnet\n")
+
+			_, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoadForModel_ExtendsMissingBase(t *testing.T) {
+	t.Parallel()
+
+	baseDir := t.TempDir()
+	writeDefinition(t, baseDir, "mainnet", "fct_block", "extends: _common\nnetwork: mainnet\n")
+
+	_, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "extending _common")
+}
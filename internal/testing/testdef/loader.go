@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -19,48 +21,186 @@ var (
 	errNetworkRequired                   = errors.New("network is required")
 	errExternalTableMissingURL           = errors.New("external table missing URL")
 	errExternalTableMissingNetworkColumn = errors.New("external table missing network_column")
+	errExternalTableInvalidNetworkMode   = errors.New("external table has invalid network_mode")
 	errAssertionMissingName              = errors.New("assertion missing name")
 	errAssertionMissingSQL               = errors.New("assertion missing SQL")
-	errAssertionMissingChecks            = errors.New("assertion must have either 'expected' values or 'assertions' checks")
+	errAssertionMissingChecks            = errors.New("assertion must have one of 'expected', 'assertions', 'snapshot', 'expected_rows', 'row_count' or 'expect_empty'")
+	errAssertionMultipleForms            = errors.New("assertion mixes result forms")
+	errAssertionInvalidSnapshot          = errors.New("assertion snapshot must be a .csv or .jsonl file")
+	errAssertionUnorderedWithoutRows     = errors.New("assertion 'unordered' requires 'expected_rows'")
+	errAssertionInvalidRowCount          = errors.New("assertion has invalid row_count")
 	errTypedCheckMissingType             = errors.New("typed check missing type")
 	errTypedCheckInvalidType             = errors.New("typed check has invalid type")
 	errTypedCheckMissingColumn           = errors.New("typed check missing column")
 	errTypedCheckMissingValue            = errors.New("typed check missing value")
+	errTypedCheckInvalidValue            = errors.New("typed check has invalid value")
+	errTypedCheckInvalidTolerance        = errors.New("typed check has invalid tolerance")
 )
 
 // TestDefinition represents a complete per-model test specification.
 // This defines what a test should do (model, data, assertions)
 // rather than how it should execute (see testing.TestConfig).
 type TestDefinition struct {
+	Extends      string                    `yaml:"extends,omitempty"` // Base definition: _common or _common/<model>
 	Model        string                    `yaml:"model"`
 	Network      string                    `yaml:"network"`
 	ExternalData map[string]*ExternalTable `yaml:"external_data"`
 	Assertions   []*Assertion              `yaml:"assertions"`
+	Uses         []*TemplateUse            `yaml:"uses,omitempty"`      // Assertion templates from tests/assertions
+	Scenarios    []*Scenario               `yaml:"scenarios,omitempty"` // Fixture sets run as separate tests
+
+	// Sources are the files the definition was merged from, base first.
+	Sources []string `yaml:"-"`
+	// Scenario is the scenario this definition was resolved for, if any.
+	Scenario string `yaml:"-"`
+
+	tests []*TestDefinition // Resolved scenarios, see Tests
 }
 
+const (
+	// NetworkModeFilter drops parquet rows whose network column is not the test network.
+	NetworkModeFilter = "filter"
+	// NetworkModeRewrite sets the network column of every parquet row to the test network.
+	NetworkModeRewrite = "rewrite"
+)
+
 // ExternalTable defines parquet data for an external table.
 type ExternalTable struct {
 	URL           string `yaml:"url"`
 	NetworkColumn string `yaml:"network_column"`
+	NetworkMode   string `yaml:"network_mode,omitempty"` // filter (default) or rewrite
 	Optional      bool   `yaml:"optional,omitempty"`
 }
 
 // Assertion represents a single SQL test.
-// Supports two formats:
-// 1. Exact match: Uses Expected map for exact value comparison
-// 2. Typed checks: Uses Assertions list for comparison operators (>, <, >=, <=, ==, !=)
+// Supports these formats:
+// 1. Exact match: Uses Expected map for exact value comparison of the first row
+// 2. Typed checks: Uses Assertions list for comparison operators (>, <, >=, <=, ==, !=) on the first row
+// 3. Snapshot: Compares the full ordered result set against a CSV or JSONL file
+// 4. Expected rows: Compares every row, in order or (with Unordered) as a set
+// 5. Row count: Checks the number of rows returned, exactly or within a range
+// 6. Expect empty: Passes only when the query returns no rows
 type Assertion struct {
-	Name       string                 `yaml:"name"`
-	SQL        string                 `yaml:"sql"`
-	Expected   map[string]interface{} `yaml:"expected,omitempty"`   // Format 1: Exact match
-	Assertions []*TypedCheck          `yaml:"assertions,omitempty"` // Format 2: Typed checks
+	Name         string                   `yaml:"name"`
+	SQL          string                   `yaml:"sql"`
+	Expected     map[string]interface{}   `yaml:"expected,omitempty"`      // Format 1: Exact match
+	Assertions   []*TypedCheck            `yaml:"assertions,omitempty"`    // Format 2: Typed checks
+	Snapshot     string                   `yaml:"snapshot,omitempty"`      // Format 3: Snapshot file, relative to the test YAML
+	ExpectedRows []map[string]interface{} `yaml:"expected_rows,omitempty"` // Format 4: Expected rows
+	Unordered    bool                     `yaml:"unordered,omitempty"`     // Format 4: Match expected rows in any order
+	RowCount     *RowCount                `yaml:"row_count,omitempty"`     // Format 5: Row count
+	ExpectEmpty  bool                     `yaml:"expect_empty,omitempty"`  // Format 6: No rows
+
+	// DiagnosticSQL samples offending rows when the assertion fails. Defaults to a
+	// SELECT * rewrite of simple COUNT(*) queries.
+	DiagnosticSQL string `yaml:"diagnostic_sql,omitempty"`
+
+	// SnapshotPath is Snapshot resolved against the test definition's directory.
+	SnapshotPath string `yaml:"-"`
+	// Template is the assertion template this assertion was expanded from, if any.
+	Template string `yaml:"-"`
 }
 
 // TypedCheck represents a typed assertion check with a comparison operator.
 type TypedCheck struct {
-	Type   string      `yaml:"type"`   // Comparison type: equals, greater_than, less_than, etc.
-	Column string      `yaml:"column"` // Column name from SQL result
-	Value  interface{} `yaml:"value"`  // Expected value to compare against
+	Type   string      `yaml:"type"`            // Comparison type: equals, greater_than, approx, between, in, matches, etc.
+	Column string      `yaml:"column"`          // Column name from SQL result
+	Value  interface{} `yaml:"value,omitempty"` // Expected value; a [min, max] list for between, a list for in/not_in
+
+	// Tolerance is the allowed absolute difference for approx (seconds for timestamps).
+	Tolerance *float64 `yaml:"tolerance,omitempty"`
+	// RelativeTolerance is the allowed difference for approx as a fraction of the value (0.01 = 1%).
+	RelativeTolerance *float64 `yaml:"relative_tolerance,omitempty"`
+}
+
+// RowCount is an exact number of rows or an inclusive range. In YAML it is either a
+// number (row_count: 10) or a mapping with min and/or max (row_count: {min: 1, max: 10}).
+type RowCount struct {
+	Exact *int `yaml:"-"`
+	Min   *int `yaml:"min,omitempty"`
+	Max   *int `yaml:"max,omitempty"`
+}
+
+// rowCountRange is the mapping form of RowCount, without its YAML methods.
+type rowCountRange struct {
+	Min *int `yaml:"min,omitempty"`
+	Max *int `yaml:"max,omitempty"`
+}
+
+// UnmarshalYAML decodes either form of row_count.
+func (c *RowCount) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var exact int
+		if err := value.Decode(&exact); err != nil {
+			return fmt.Errorf("row_count: %w", err)
+		}
+
+		*c = RowCount{Exact: &exact}
+
+		return nil
+	}
+
+	var r rowCountRange
+	if err := value.Decode(&r); err != nil {
+		return fmt.Errorf("row_count: %w", err)
+	}
+
+	*c = RowCount{Min: r.Min, Max: r.Max}
+
+	return nil
+}
+
+// MarshalYAML encodes an exact count as a number and a range as a mapping.
+func (c RowCount) MarshalYAML() (interface{}, error) {
+	if c.Exact != nil {
+		return *c.Exact, nil
+	}
+
+	return rowCountRange{Min: c.Min, Max: c.Max}, nil
+}
+
+// Matches reports whether n rows satisfy the count.
+func (c *RowCount) Matches(n int) bool {
+	if c.Exact != nil {
+		return n == *c.Exact
+	}
+
+	return (c.Min == nil || n >= *c.Min) && (c.Max == nil || n <= *c.Max)
+}
+
+// String describes the count, e.g. "10", "between 1 and 10" or ">= 1".
+func (c *RowCount) String() string {
+	switch {
+	case c.Exact != nil:
+		return fmt.Sprintf("%d", *c.Exact)
+	case c.Min != nil && c.Max != nil:
+		return fmt.Sprintf("between %d and %d", *c.Min, *c.Max)
+	case c.Min != nil:
+		return fmt.Sprintf(">= %d", *c.Min)
+	case c.Max != nil:
+		return fmt.Sprintf("<= %d", *c.Max)
+	default:
+		return "any"
+	}
+}
+
+// validate checks the count is non-negative and the range non-empty.
+func (c *RowCount) validate() error {
+	for _, bound := range []*int{c.Exact, c.Min, c.Max} {
+		if bound != nil && *bound < 0 {
+			return fmt.Errorf("negative count %d", *bound) //nolint:err113 // Wrapped by errAssertionInvalidRowCount
+		}
+	}
+
+	if c.Exact == nil && c.Min == nil && c.Max == nil {
+		return fmt.Errorf("requires a number or min/max") //nolint:err113 // Wrapped by errAssertionInvalidRowCount
+	}
+
+	if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+		return fmt.Errorf("min %d is greater than max %d", *c.Min, *c.Max) //nolint:err113 // Wrapped by errAssertionInvalidRowCount
+	}
+
+	return nil
 }
 
 // Loader loads test definition files.
@@ -73,6 +213,9 @@ type Loader interface {
 type loader struct {
 	baseDir string
 	log     logrus.FieldLogger
+
+	templatesMu sync.Mutex
+	templates   map[string]*AssertionTemplate // Loaded on first use
 }
 
 // NewLoader creates a new test definition loader.
@@ -162,25 +305,80 @@ func (l *loader) LoadForModels(network string, modelNames []string) (map[string]
 	return configs, nil
 }
 
-// loadFile reads and parses a YAML test definition file
+// loadFile reads and parses a YAML test definition file, merging the definitions it
+// extends, resolving its scenarios and expanding their assertion templates.
 func (l *loader) loadFile(path string) (*TestDefinition, error) {
-	data, err := os.ReadFile(path) //nolint:gosec // G304: Reading test config from trusted paths
+	config, err := l.loadLayers(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(config.Scenarios) > 0 {
+		resolveScenarios(config)
+	}
+
+	for _, test := range config.Tests() {
+		if len(test.Uses) == 0 {
+			continue
+		}
+
+		templates, err := l.loadTemplates()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := expandTemplates(test, templates); err != nil {
+			return nil, fmt.Errorf("expanding assertion templates for %s: %w", test.ID(), err)
+		}
+
+		resolveSnapshotPaths(test.Assertions, path)
+	}
+
+	return config, nil
+}
+
+// loadTemplates returns the assertion templates, reading them on first use.
+func (l *loader) loadTemplates() (map[string]*AssertionTemplate, error) {
+	l.templatesMu.Lock()
+	defer l.templatesMu.Unlock()
+
+	if l.templates != nil {
+		return l.templates, nil
+	}
+
+	templates, err := LoadTemplates(filepath.Join(l.baseDir, TemplatesDir))
 	if err != nil {
-		return nil, fmt.Errorf("reading file: %w", err)
+		return nil, fmt.Errorf("loading assertion templates: %w", err)
 	}
 
-	var config TestDefinition
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("parsing yaml: %w", err)
+	l.templates = templates
+
+	return templates, nil
+}
+
+// validateDefinition ensures the test definition, or each of its scenarios, is valid.
+func (l *loader) validateDefinition(definition *TestDefinition) error {
+	if err := validateScenarios(definition.Scenarios); err != nil {
+		return err
+	}
+
+	for _, test := range definition.Tests() {
+		if err := l.validateTest(test); err != nil {
+			if test.Scenario != "" {
+				return fmt.Errorf("scenario %s: %w", test.Scenario, err)
+			}
+
+			return err
+		}
 	}
 
-	return &config, nil
+	return nil
 }
 
-// validateDefinition ensures the test definition is valid.
+// validateTest ensures a single test is valid.
 //
 //nolint:gocyclo // conditionals in loop, fine.
-func (l *loader) validateDefinition(definition *TestDefinition) error {
+func (l *loader) validateTest(definition *TestDefinition) error {
 	if definition.Model == "" {
 		return errModelNameRequired
 	}
@@ -199,12 +397,20 @@ func (l *loader) validateDefinition(definition *TestDefinition) error {
 			if extData.NetworkColumn == "" {
 				return fmt.Errorf("%w: %s", errExternalTableMissingNetworkColumn, tableName)
 			}
+
+			switch extData.NetworkMode {
+			case "":
+				extData.NetworkMode = NetworkModeFilter
+			case NetworkModeFilter, NetworkModeRewrite:
+			default:
+				return fmt.Errorf("%w: %s (%q)", errExternalTableInvalidNetworkMode, tableName, extData.NetworkMode)
+			}
 		}
 	}
 
 	// Validate assertions
 	if len(definition.Assertions) == 0 {
-		l.log.WithField("model", definition.Model).Warn("no assertions defined for model")
+		l.log.WithField("model", definition.ID()).Warn("no assertions defined for model")
 	}
 
 	for i, assertion := range definition.Assertions {
@@ -216,12 +422,33 @@ func (l *loader) validateDefinition(definition *TestDefinition) error {
 			return fmt.Errorf("%w: %s", errAssertionMissingSQL, assertion.Name)
 		}
 
-		// Must have either Expected (exact match) OR Assertions (typed checks)
-		hasExpected := len(assertion.Expected) > 0
+		// Must have exactly one result form (Expected and typed checks may be combined)
 		hasTypedChecks := len(assertion.Assertions) > 0
 
-		if !hasExpected && !hasTypedChecks {
+		forms := assertionForms(assertion)
+		switch {
+		case len(forms) == 0:
 			return fmt.Errorf("%w: %s", errAssertionMissingChecks, assertion.Name)
+		case len(forms) > 1:
+			return fmt.Errorf("%w: %s (%s)", errAssertionMultipleForms, assertion.Name, strings.Join(forms, ", "))
+		}
+
+		if assertion.Unordered && len(assertion.ExpectedRows) == 0 {
+			return fmt.Errorf("%w: %s", errAssertionUnorderedWithoutRows, assertion.Name)
+		}
+
+		if assertion.Snapshot != "" {
+			switch strings.ToLower(filepath.Ext(assertion.Snapshot)) {
+			case ".csv", ".jsonl":
+			default:
+				return fmt.Errorf("%w: %s (%q)", errAssertionInvalidSnapshot, assertion.Name, assertion.Snapshot)
+			}
+		}
+
+		if assertion.RowCount != nil {
+			if err := assertion.RowCount.validate(); err != nil {
+				return fmt.Errorf("%w: %s: %w", errAssertionInvalidRowCount, assertion.Name, err)
+			}
 		}
 
 		// Validate typed checks if present
@@ -235,6 +462,33 @@ func (l *loader) validateDefinition(definition *TestDefinition) error {
 	return nil
 }
 
+// assertionForms lists the result forms an assertion uses.
+func assertionForms(assertion *Assertion) []string {
+	forms := make([]string, 0, 1)
+
+	if len(assertion.Expected) > 0 || len(assertion.Assertions) > 0 {
+		forms = append(forms, "expected/assertions")
+	}
+
+	if assertion.Snapshot != "" {
+		forms = append(forms, "snapshot")
+	}
+
+	if len(assertion.ExpectedRows) > 0 {
+		forms = append(forms, "expected_rows")
+	}
+
+	if assertion.RowCount != nil {
+		forms = append(forms, "row_count")
+	}
+
+	if assertion.ExpectEmpty {
+		forms = append(forms, "expect_empty")
+	}
+
+	return forms
+}
+
 // validateTypedChecks validates typed assertion checks
 func (l *loader) validateTypedChecks(assertionName string, checks []*TypedCheck) error {
 	validTypes := map[string]bool{
@@ -250,6 +504,14 @@ func (l *loader) validateTypedChecks(assertionName string, checks []*TypedCheck)
 		"lt":                    true,
 		"less_than_or_equal":    true,
 		"lte":                   true,
+		"approx":                true,
+		"between":               true,
+		"in":                    true,
+		"not_in":                true,
+		"matches":               true,
+		"is_null":               true,
+		"not_null":              true,
+		"length":                true,
 	}
 
 	for i, check := range checks {
@@ -258,7 +520,7 @@ func (l *loader) validateTypedChecks(assertionName string, checks []*TypedCheck)
 		}
 
 		if !validTypes[check.Type] {
-			return fmt.Errorf("%w: assertion %s, check %d has type '%s' (must be one of: equals, not_equals, greater_than, greater_than_or_equal, less_than, less_than_or_equal)",
+			return fmt.Errorf("%w: assertion %s, check %d has type '%s' (must be one of: equals, not_equals, greater_than, greater_than_or_equal, less_than, less_than_or_equal, approx, between, in, not_in, matches, is_null, not_null, length)",
 				errTypedCheckInvalidType, assertionName, i, check.Type)
 		}
 
@@ -266,9 +528,65 @@ func (l *loader) validateTypedChecks(assertionName string, checks []*TypedCheck)
 			return fmt.Errorf("%w: assertion %s, check %d", errTypedCheckMissingColumn, assertionName, i)
 		}
 
+		// Null checks take no value.
+		if check.Type == "is_null" || check.Type == "not_null" {
+			continue
+		}
+
 		if check.Value == nil {
 			return fmt.Errorf("%w: assertion %s, check %d", errTypedCheckMissingValue, assertionName, i)
 		}
+
+		if err := validateCheckValue(check); err != nil {
+			return fmt.Errorf("assertion %s, check %d: %w", assertionName, i, err)
+		}
+	}
+
+	return nil
+}
+
+// validateCheckValue validates the value shape and tolerance each check type requires.
+func validateCheckValue(check *TypedCheck) error {
+	if (check.Tolerance != nil || check.RelativeTolerance != nil) && check.Type != "approx" {
+		return fmt.Errorf("%w: tolerance is only valid for approx", errTypedCheckInvalidTolerance)
+	}
+
+	switch check.Type {
+	case "approx":
+		if (check.Tolerance == nil) == (check.RelativeTolerance == nil) {
+			return fmt.Errorf("%w: approx requires exactly one of tolerance or relative_tolerance", errTypedCheckInvalidTolerance)
+		}
+
+		for _, tolerance := range []*float64{check.Tolerance, check.RelativeTolerance} {
+			if tolerance != nil && *tolerance < 0 {
+				return fmt.Errorf("%w: %v is negative", errTypedCheckInvalidTolerance, *tolerance)
+			}
+		}
+
+	case "between":
+		if bounds, ok := check.Value.([]interface{}); !ok || len(bounds) != 2 {
+			return fmt.Errorf("%w: between requires a [min, max] list", errTypedCheckInvalidValue)
+		}
+
+	case "in", "not_in":
+		if values, ok := check.Value.([]interface{}); !ok || len(values) == 0 {
+			return fmt.Errorf("%w: %s requires a non-empty list", errTypedCheckInvalidValue, check.Type)
+		}
+
+	case "matches":
+		pattern, ok := check.Value.(string)
+		if !ok {
+			return fmt.Errorf("%w: matches requires a string pattern", errTypedCheckInvalidValue)
+		}
+
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%w: %w", errTypedCheckInvalidValue, err)
+		}
+
+	case "length":
+		if length, ok := check.Value.(int); !ok || length < 0 {
+			return fmt.Errorf("%w: length requires a non-negative integer", errTypedCheckInvalidValue)
+		}
 	}
 
 	return nil
@@ -276,5 +594,10 @@ func (l *loader) validateTypedChecks(assertionName string, checks []*TypedCheck)
 
 // buildPath constructs the file path for a model's test definition
 func (l *loader) buildPath(network, modelName string) string {
-	return filepath.Join(l.baseDir, network, "models", modelName+".yaml")
+	return Path(l.baseDir, network, modelName)
+}
+
+// Path returns the test definition path for a model: <baseDir>/<network>/models/<model>.yaml.
+func Path(baseDir, network, modelName string) string {
+	return filepath.Join(baseDir, network, "models", modelName+".yaml")
 }
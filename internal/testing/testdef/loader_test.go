@@ -0,0 +1,222 @@
+package testdef
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// writeDefinition writes a raw test definition for a model under baseDir.
+func writeDefinition(t *testing.T, baseDir, network, model, content string) {
+	t.Helper()
+
+	path := Path(baseDir, network, model)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestLoadForModel_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	baseDir := t.TempDir()
+	writeDefinition(t, baseDir, "mainnet", "fct_block", `model: fct_block
+network: mainnet
+assertions:
+    - name: Block output
+      sql: SELECT slot, block_root FROM fct_block FINAL ORDER BY slot
+      snapshot: snapshots/fct_block.csv
+`)
+
+	definition, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+	require.NoError(t, err)
+	require.Equal(t, "snapshots/fct_block.csv", definition.Assertions[0].Snapshot)
+	require.Equal(t,
+		filepath.Join(baseDir, "mainnet", "models", "snapshots", "fct_block.csv"),
+		definition.Assertions[0].SnapshotPath,
+	)
+}
+
+func TestLoadForModel_InvalidSnapshot(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		assertion string
+		err       error
+	}{
+		{
+			name:      "unsupported extension",
+			assertion: "      snapshot: snapshots/fct_block.txt\n",
+			err:       errAssertionInvalidSnapshot,
+		},
+		{
+			name:      "combined with typed checks",
+			assertion: "      snapshot: snapshots/fct_block.csv\n      assertions:\n        - type: equals\n          column: slot\n          value: 1\n",
+			err:       errAssertionMultipleForms,
+		},
+		{
+			name:      "no checks",
+			assertion: "",
+			err:       errAssertionMissingChecks,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			baseDir := t.TempDir()
+			writeDefinition(t, baseDir, "mainnet", "fct_block",
+				"model: fct_block\nnetwork: mainnet\nassertions:\n    - name: Block output\n      sql: SELECT 1\n"+tt.assertion)
+
+			_, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoadForModel_ResultSetForms(t *testing.T) {
+	t.Parallel()
+
+	baseDir := t.TempDir()
+	writeDefinition(t, baseDir, "mainnet", "fct_block", `model: fct_block
+network: mainnet
+assertions:
+    - name: Proposers
+      sql: SELECT slot, proposer FROM fct_block FINAL
+      unordered: true
+      expected_rows:
+        - slot: 1
+          proposer: a
+        - slot: 2
+          proposer: b
+    - name: Exact count
+      sql: SELECT slot FROM fct_block FINAL
+      row_count: 10
+    - name: Count range
+      sql: SELECT slot FROM fct_block FINAL
+      row_count:
+        min: 1
+        max: 10
+    - name: No orphans
+      sql: SELECT slot FROM fct_block FINAL WHERE block_root = ''
+      expect_empty: true
+`)
+
+	definition, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+	require.NoError(t, err)
+	require.Len(t, definition.Assertions, 4)
+
+	require.True(t, definition.Assertions[0].Unordered)
+	require.Len(t, definition.Assertions[0].ExpectedRows, 2)
+
+	require.Equal(t, "10", definition.Assertions[1].RowCount.String())
+	require.True(t, definition.Assertions[1].RowCount.Matches(10))
+
+	require.Equal(t, "between 1 and 10", definition.Assertions[2].RowCount.String())
+	require.False(t, definition.Assertions[2].RowCount.Matches(0))
+
+	require.True(t, definition.Assertions[3].ExpectEmpty)
+
+	// Row counts round-trip through the writer in their original form.
+	path := Path(t.TempDir(), "mainnet", "fct_block")
+	require.NoError(t, Write(path, definition))
+
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(written), "row_count: 10\n")
+	require.Contains(t, string(written), "row_count:\n        min: 1\n        max: 10\n")
+}
+
+func TestLoadForModel_InvalidResultSetForms(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		assertion string
+		err       error
+	}{
+		{
+			name:      "unordered without rows",
+			assertion: "      unordered: true\n      row_count: 1\n",
+			err:       errAssertionUnorderedWithoutRows,
+		},
+		{
+			name:      "inverted range",
+			assertion: "      row_count:\n        min: 5\n        max: 1\n",
+			err:       errAssertionInvalidRowCount,
+		},
+		{
+			name:      "negative count",
+			assertion: "      row_count: -1\n",
+			err:       errAssertionInvalidRowCount,
+		},
+		{
+			name:      "empty and count",
+			assertion: "      row_count: 0\n      expect_empty: true\n",
+			err:       errAssertionMultipleForms,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			baseDir := t.TempDir()
+			writeDefinition(t, baseDir, "mainnet", "fct_block",
+				"model: fct_block\nnetwork: mainnet\nassertions:\n    - name: Rows\n      sql: SELECT 1\n"+tt.assertion)
+
+			_, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoadForModel_TypedCheckValues(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		check string
+		err   error
+	}{
+		{name: "approx", check: "type: approx\n          column: rate\n          value: 0.5\n          tolerance: 0.01"},
+		{name: "approx relative", check: "type: approx\n          column: rate\n          value: 0.5\n          relative_tolerance: 0.01"},
+		{name: "between", check: "type: between\n          column: slot\n          value: [1, 10]"},
+		{name: "in", check: "type: in\n          column: client\n          value: [prysm, lighthouse]"},
+		{name: "matches", check: "type: matches\n          column: root\n          value: ^0x[0-9a-f]{64}$"},
+		{name: "is_null without value", check: "type: is_null\n          column: root"},
+		{name: "length", check: "type: length\n          column: validators\n          value: 0"},
+		{name: "unknown type", check: "type: contains\n          column: root\n          value: x", err: errTypedCheckInvalidType},
+		{name: "approx without tolerance", check: "type: approx\n          column: rate\n          value: 0.5", err: errTypedCheckInvalidTolerance},
+		{name: "approx with both tolerances", check: "type: approx\n          column: rate\n          value: 0.5\n          tolerance: 1\n          relative_tolerance: 0.1", err: errTypedCheckInvalidTolerance},
+		{name: "tolerance on equals", check: "type: equals\n          column: rate\n          value: 0.5\n          tolerance: 1", err: errTypedCheckInvalidTolerance},
+		{name: "between scalar", check: "type: between\n          column: slot\n          value: 10", err: errTypedCheckInvalidValue},
+		{name: "in empty", check: "type: in\n          column: client\n          value: []", err: errTypedCheckInvalidValue},
+		{name: "matches invalid pattern", check: "type: matches\n          column: root\n          value: \"[\"", err: errTypedCheckInvalidValue},
+		{name: "length negative", check: "type: length\n          column: validators\n          value: -1", err: errTypedCheckInvalidValue},
+		{name: "missing value", check: "type: gt\n          column: slot", err: errTypedCheckMissingValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			baseDir := t.TempDir()
+			writeDefinition(t, baseDir, "mainnet", "fct_block",
+				"model: fct_block\nnetwork: mainnet\nassertions:\n    - name: Checks\n      sql: SELECT 1\n      assertions:\n        - "+tt.check+"\n")
+
+			_, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+			if tt.err == nil {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package testdef
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+var (
+	errScenarioMissingName   = errors.New("scenario missing name")
+	errScenarioInvalidName   = errors.New("scenario name must contain only letters, digits, '_' and '-'")
+	errScenarioDuplicateName = errors.New("duplicate scenario name")
+
+	scenarioNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// Scenario is a named fixture set for a model, such as pectra-era data or a reorg.
+// It overrides the definition it belongs to the same way a network definition
+// overrides the common layer: external_data per table and assertions by name.
+type Scenario struct {
+	Name         string                    `yaml:"name"`
+	Description  string                    `yaml:"description,omitempty"`
+	ExternalData map[string]*ExternalTable `yaml:"external_data,omitempty"`
+	Assertions   []*Assertion              `yaml:"assertions,omitempty"`
+	Uses         []*TemplateUse            `yaml:"uses,omitempty"`
+}
+
+// TestID identifies a test run: the model, or model[scenario] for a scenario.
+func TestID(model, scenario string) string {
+	if scenario == "" {
+		return model
+	}
+
+	return model + "[" + scenario + "]"
+}
+
+// ID identifies the test this definition runs.
+func (d *TestDefinition) ID() string {
+	return TestID(d.Model, d.Scenario)
+}
+
+// Tests returns the tests a definition runs: one per scenario, or the definition itself
+// when it has none.
+func (d *TestDefinition) Tests() []*TestDefinition {
+	if len(d.tests) == 0 {
+		return []*TestDefinition{d}
+	}
+
+	return d.tests
+}
+
+// resolveScenarios builds the test of every scenario by merging it over the definition.
+func resolveScenarios(definition *TestDefinition) {
+	definition.tests = make([]*TestDefinition, 0, len(definition.Scenarios))
+
+	for _, scenario := range definition.Scenarios {
+		if scenario == nil {
+			continue
+		}
+
+		test := mergeDefinitions(definition, &TestDefinition{
+			ExternalData: scenario.ExternalData,
+			Assertions:   scenario.Assertions,
+			Uses:         scenario.Uses,
+		})
+
+		test.Extends = definition.Extends
+		test.Scenario = scenario.Name
+		test.Scenarios = nil
+		test.tests = nil
+
+		definition.tests = append(definition.tests, test)
+	}
+}
+
+// mergeScenarios merges scenarios by name, replacing base scenarios in place or appending.
+func mergeScenarios(base, override []*Scenario) []*Scenario {
+	merged := make([]*Scenario, 0, len(base)+len(override))
+	index := make(map[string]int, len(base))
+
+	for _, scenario := range slices.Concat(base, override) {
+		if scenario == nil {
+			continue
+		}
+
+		if i, ok := index[scenario.Name]; ok {
+			merged[i] = scenario
+
+			continue
+		}
+
+		index[scenario.Name] = len(merged)
+		merged = append(merged, scenario)
+	}
+
+	return merged
+}
+
+// validateScenarios checks scenario names are present, well formed and unique.
+func validateScenarios(scenarios []*Scenario) error {
+	seen := make(map[string]bool, len(scenarios))
+
+	for i, scenario := range scenarios {
+		switch {
+		case scenario == nil || scenario.Name == "":
+			return fmt.Errorf("%w at index %d", errScenarioMissingName, i)
+		case !scenarioNamePattern.MatchString(scenario.Name):
+			return fmt.Errorf("%w: %q", errScenarioInvalidName, scenario.Name)
+		case seen[scenario.Name]:
+			return fmt.Errorf("%w: %s", errScenarioDuplicateName, scenario.Name)
+		}
+
+		seen[scenario.Name] = true
+	}
+
+	return nil
+}
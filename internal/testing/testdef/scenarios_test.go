@@ -0,0 +1,163 @@
+package testdef
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadForModel_Scenarios(t *testing.T) {
+	t.Parallel()
+
+	baseDir := t.TempDir()
+	writeTemplate(t, baseDir, "not_empty", `name: not_empty
+params:
+    - column
+assertions:
+    - name: No empty {{column}} values
+      sql: SELECT COUNT(*) AS bad_rows FROM {{table}} FINAL WHERE {{column}} = ''
+      expected:
+        bad_rows: 0
+`)
+	writeDefinition(t, baseDir, "mainnet", "fct_block", `model: fct_block
+network: mainnet
+external_data:
+    canonical_beacon_block:
+        url: https://example.com/pectra/canonical_beacon_block.parquet
+        network_column: meta_network_name
+    beacon_api_eth_v2_beacon_block:
+        url: https://example.com/pectra/beacon_api_eth_v2_beacon_block.parquet
+        network_column: meta_network_name
+assertions:
+    - name: Row count
+      sql: SELECT COUNT(*) AS row_count FROM fct_block FINAL
+      row_count: {min: 1}
+scenarios:
+    - name: pectra
+    - name: empty_epoch
+      description: An epoch without any proposed blocks
+      external_data:
+        canonical_beacon_block:
+            url: https://example.com/empty_epoch/canonical_beacon_block.parquet
+            network_column: meta_network_name
+            optional: true
+      assertions:
+        - name: Row count
+          sql: SELECT COUNT(*) AS row_count FROM fct_block FINAL
+          expect_empty: true
+      uses:
+        - template: not_empty
+          with:
+            column: block_root
+`)
+
+	definition, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+	require.NoError(t, err)
+
+	tests := definition.Tests()
+	require.Len(t, tests, 2)
+	require.Equal(t, "fct_block[pectra]", tests[0].ID())
+	require.Equal(t, "fct_block[empty_epoch]", tests[1].ID())
+
+	// A scenario without overrides runs the top-level fixtures and assertions.
+	require.Equal(t, definition.ExternalData, tests[0].ExternalData)
+	require.Len(t, tests[0].Assertions, 1)
+	require.NotNil(t, tests[0].Assertions[0].RowCount)
+
+	// Overrides replace tables and assertions by name; uses only apply to their scenario.
+	require.Equal(t, "https://example.com/empty_epoch/canonical_beacon_block.parquet", tests[1].ExternalData["canonical_beacon_block"].URL)
+	require.Equal(t, "https://example.com/pectra/beacon_api_eth_v2_beacon_block.parquet", tests[1].ExternalData["beacon_api_eth_v2_beacon_block"].URL)
+	require.Len(t, tests[1].Assertions, 2)
+	require.True(t, tests[1].Assertions[0].ExpectEmpty)
+	require.Equal(t, "not_empty", tests[1].Assertions[1].Template)
+}
+
+func TestLoadForModel_ScenariosWithoutScenarios(t *testing.T) {
+	t.Parallel()
+
+	definition := &TestDefinition{Model: "fct_block"}
+	require.Equal(t, []*TestDefinition{definition}, definition.Tests())
+	require.Equal(t, "fct_block", definition.ID())
+}
+
+func TestLoadForModel_ExtendsScenarios(t *testing.T) {
+	t.Parallel()
+
+	baseDir := t.TempDir()
+	writeDefinition(t, baseDir, CommonLayer, "fct_block", `model: fct_block
+assertions:
+    - name: Row count
+      sql: SELECT COUNT(*) AS row_count FROM fct_block FINAL
+      row_count: {min: 1}
+scenarios:
+    - name: reorg
+      assertions:
+        - name: Orphaned blocks
+          sql: SELECT COUNT(*) AS orphaned FROM fct_block FINAL WHERE status = 'orphaned'
+          expected:
+            orphaned: 1
+`)
+	writeDefinition(t, baseDir, "mainnet", "fct_block", `extends: _common
+network: mainnet
+external_data:
+    canonical_beacon_block:
+        url: https://example.com/canonical_beacon_block.parquet
+        network_column: meta_network_name
+scenarios:
+    - name: reorg
+      external_data:
+        canonical_beacon_block:
+            url: https://example.com/reorg/canonical_beacon_block.parquet
+            network_column: meta_network_name
+`)
+
+	definition, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+	require.NoError(t, err)
+
+	// The network's reorg scenario replaces the common one wholesale.
+	tests := definition.Tests()
+	require.Len(t, tests, 1)
+	require.Equal(t, "fct_block[reorg]", tests[0].ID())
+	require.Equal(t, "https://example.com/reorg/canonical_beacon_block.parquet", tests[0].ExternalData["canonical_beacon_block"].URL)
+	require.Len(t, tests[0].Assertions, 1)
+	require.Equal(t, "Row count", tests[0].Assertions[0].Name)
+}
+
+func TestLoadForModel_InvalidScenarios(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		scenarios string
+		err       error
+	}{
+		{name: "missing name", scenarios: "    - description: no name\n", err: errScenarioMissingName},
+		{name: "invalid name", scenarios: "    - name: empty epoch\n", err: errScenarioInvalidName},
+		{name: "duplicate name", scenarios: "    - name: reorg\n    - name: reorg\n", err: errScenarioDuplicateName},
+		{
+			name:      "invalid scenario assertion",
+			scenarios: "    - name: reorg\n      assertions:\n        - name: Broken\n          sql: SELECT 1\n",
+			err:       errAssertionMissingChecks,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			baseDir := t.TempDir()
+			writeDefinition(t, baseDir, "mainnet", "fct_block", `model: fct_block
+network: mainnet
+assertions:
+    - name: Row count
+      sql: SELECT COUNT(*) AS row_count FROM fct_block FINAL
+      row_count: {min: 1}
+scenarios:
+`+tt.scenarios)
+
+			_, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package testdef
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplatesDir is the directory under the tests base directory holding assertion templates.
+const TemplatesDir = "assertions"
+
+// tableParam is the built-in template parameter naming the table under test.
+// It defaults to the definition's model and can be overridden with `with: {table: ...}`.
+const tableParam = "table"
+
+var (
+	errTemplateNotFound      = errors.New("assertion template not found")
+	errTemplateMissingParam  = errors.New("assertion template missing parameter")
+	errTemplateUnknownParam  = errors.New("assertion template given unknown parameter")
+	errTemplateUnknownPlace  = errors.New("assertion template references undeclared parameter")
+	errTemplateUnknownFormat = errors.New("assertion template placeholder has unknown modifier")
+	errTemplateDuplicateName = errors.New("expanded assertion name is not unique")
+	errTemplateNameMismatch  = errors.New("assertion template name must match its file name")
+	errTemplateEmpty         = errors.New("assertion template has no assertions")
+
+	// placeholderPattern matches {{param}} and {{param:modifier}}.
+	placeholderPattern = regexp.MustCompile(`\{\{\s*(\w+)(?::(\w+))?\s*\}\}`)
+)
+
+// AssertionTemplate is a parameterised set of assertions in tests/assertions/<name>.yaml.
+// Placeholders {{param}} render the parameter value (lists comma-separated) and
+// {{param:quoted}} renders it as SQL string literals. {{table}} is always available.
+type AssertionTemplate struct {
+	Name        string       `yaml:"name"`
+	Description string       `yaml:"description,omitempty"`
+	Params      []string     `yaml:"params,omitempty"`
+	Assertions  []*Assertion `yaml:"assertions"`
+}
+
+// TemplateUse pulls an assertion template into a test definition.
+type TemplateUse struct {
+	Template string                 `yaml:"template"`
+	With     map[string]interface{} `yaml:"with,omitempty"`
+}
+
+// LoadTemplates reads every assertion template in dir, keyed by name.
+// A missing directory yields no templates.
+func LoadTemplates(dir string) (map[string]*AssertionTemplate, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]*AssertionTemplate{}, nil
+		}
+
+		return nil, fmt.Errorf("reading directory %s: %w", dir, err)
+	}
+
+	templates := make(map[string]*AssertionTemplate, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		data, err := os.ReadFile(path) //nolint:gosec // G304: Reading templates from trusted paths
+		if err != nil {
+			return nil, fmt.Errorf("reading template %s: %w", path, err)
+		}
+
+		var template AssertionTemplate
+		if err := yaml.Unmarshal(data, &template); err != nil {
+			return nil, fmt.Errorf("parsing template %s: %w", path, err)
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		if template.Name != name {
+			return nil, fmt.Errorf("%w: %s declares %q", errTemplateNameMismatch, path, template.Name)
+		}
+
+		if len(template.Assertions) == 0 {
+			return nil, fmt.Errorf("%w: %s", errTemplateEmpty, name)
+		}
+
+		templates[name] = &template
+	}
+
+	return templates, nil
+}
+
+// expandTemplates appends the assertions of every template a definition uses.
+// Expanded assertions record their template and keep the rendered template names,
+// which stay stable as long as the parameters do.
+func expandTemplates(definition *TestDefinition, templates map[string]*AssertionTemplate) error {
+	names := make(map[string]bool, len(definition.Assertions))
+	for _, assertion := range definition.Assertions {
+		if assertion != nil {
+			names[assertion.Name] = true
+		}
+	}
+
+	for i, use := range definition.Uses {
+		template, ok := templates[use.Template]
+		if !ok {
+			return fmt.Errorf("%w: uses[%d] %q", errTemplateNotFound, i, use.Template)
+		}
+
+		params, err := templateParams(definition.Model, template, use.With)
+		if err != nil {
+			return fmt.Errorf("uses[%d] %s: %w", i, use.Template, err)
+		}
+
+		for _, tmpl := range template.Assertions {
+			assertion, err := renderAssertion(tmpl, params)
+			if err != nil {
+				return fmt.Errorf("uses[%d] %s: %w", i, use.Template, err)
+			}
+
+			if names[assertion.Name] {
+				return fmt.Errorf("%w: %q (from template %s)", errTemplateDuplicateName, assertion.Name, use.Template)
+			}
+
+			names[assertion.Name] = true
+			assertion.Template = use.Template
+			definition.Assertions = append(definition.Assertions, assertion)
+		}
+	}
+
+	return nil
+}
+
+// templateParams checks the parameters passed to a template and adds the built-in table.
+func templateParams(model string, template *AssertionTemplate, with map[string]interface{}) (map[string]interface{}, error) {
+	declared := make(map[string]bool, len(template.Params)+1)
+	declared[tableParam] = true
+
+	for _, param := range template.Params {
+		declared[param] = true
+
+		if _, ok := with[param]; !ok {
+			return nil, fmt.Errorf("%w: %s", errTemplateMissingParam, param)
+		}
+	}
+
+	params := map[string]interface{}{tableParam: model}
+
+	keys := make([]string, 0, len(with))
+	for key := range with {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !declared[key] {
+			return nil, fmt.Errorf("%w: %s", errTemplateUnknownParam, key)
+		}
+
+		params[key] = with[key]
+	}
+
+	return params, nil
+}
+
+// renderAssertion copies a template assertion with placeholders substituted in its name,
+// SQL, diagnostic SQL, snapshot path and check columns and string values.
+func renderAssertion(tmpl *Assertion, params map[string]interface{}) (*Assertion, error) {
+	rendered := *tmpl
+
+	for _, field := range []*string{&rendered.Name, &rendered.SQL, &rendered.DiagnosticSQL, &rendered.Snapshot} {
+		value, err := renderPlaceholders(*field, params)
+		if err != nil {
+			return nil, err
+		}
+
+		*field = value
+	}
+
+	rendered.Assertions = make([]*TypedCheck, 0, len(tmpl.Assertions))
+
+	for _, check := range tmpl.Assertions {
+		renderedCheck := *check
+
+		column, err := renderPlaceholders(check.Column, params)
+		if err != nil {
+			return nil, err
+		}
+
+		renderedCheck.Column = column
+
+		if value, ok := check.Value.(string); ok {
+			if renderedCheck.Value, err = renderPlaceholders(value, params); err != nil {
+				return nil, err
+			}
+		}
+
+		rendered.Assertions = append(rendered.Assertions, &renderedCheck)
+	}
+
+	return &rendered, nil
+}
+
+// renderPlaceholders substitutes {{param}} and {{param:quoted}} placeholders.
+func renderPlaceholders(text string, params map[string]interface{}) (string, error) {
+	var renderErr error
+
+	rendered := placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		match := placeholderPattern.FindStringSubmatch(placeholder)
+		name, modifier := match[1], match[2]
+
+		value, ok := params[name]
+		if !ok {
+			renderErr = fmt.Errorf("%w: %s", errTemplateUnknownPlace, name)
+
+			return placeholder
+		}
+
+		switch modifier {
+		case "":
+			return joinParam(value, func(v interface{}) string { return fmt.Sprintf("%v", v) })
+		case "quoted":
+			return joinParam(value, quoteSQLString)
+		default:
+			renderErr = fmt.Errorf("%w: %s", errTemplateUnknownFormat, placeholder)
+
+			return placeholder
+		}
+	})
+
+	return rendered, renderErr
+}
+
+// joinParam renders a parameter value, joining lists with ", ".
+func joinParam(value interface{}, render func(interface{}) string) string {
+	values, ok := value.([]interface{})
+	if !ok {
+		return render(value)
+	}
+
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, render(v))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// quoteSQLString renders a value as a single-quoted ClickHouse string literal.
+func quoteSQLString(value interface{}) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(fmt.Sprintf("%v", value))
+
+	return "'" + escaped + "'"
+}
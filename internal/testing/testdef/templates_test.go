@@ -0,0 +1,179 @@
+package testdef
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTemplate writes a raw assertion template under baseDir.
+func writeTemplate(t *testing.T, baseDir, name, content string) {
+	t.Helper()
+
+	dir := filepath.Join(baseDir, TemplatesDir)
+	require.NoError(t, os.MkdirAll(dir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(content), 0o600))
+}
+
+func TestLoadForModel_ExpandsTemplates(t *testing.T) {
+	t.Parallel()
+
+	baseDir := t.TempDir()
+	writeTemplate(t, baseDir, "enum", `name: enum
+params:
+    - column
+    - values
+assertions:
+    - name: "{{column}} contains only valid values"
+      sql: |
+        SELECT COUNT(*) AS bad_rows FROM {{table}} FINAL
+        WHERE {{column}} NOT IN ({{values:quoted}})
+      assertions:
+        - type: equals
+          column: bad_rows
+          value: 0
+`)
+	writeDefinition(t, baseDir, "mainnet", "fct_block", `model: fct_block
+network: mainnet
+assertions:
+    - name: Row count should be greater than zero
+      sql: SELECT COUNT(*) AS row_count FROM fct_block FINAL
+      assertions:
+        - type: greater_than
+          column: row_count
+          value: 0
+uses:
+    - template: enum
+      with:
+        column: status
+        values: [canonical, "it's orphaned"]
+    - template: enum
+      with:
+        table: fct_block_head
+        column: status
+        values: [canonical]
+`)
+
+	definition, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+	require.Error(t, err, "two uses rendering the same name must be rejected")
+	require.ErrorIs(t, err, errTemplateDuplicateName)
+	require.Nil(t, definition)
+
+	writeDefinition(t, baseDir, "mainnet", "fct_block", `model: fct_block
+network: mainnet
+assertions: []
+uses:
+    - template: enum
+      with:
+        column: status
+        values: [canonical, "it's orphaned"]
+    - template: enum
+      with:
+        table: fct_block_head
+        column: head_status
+        values: [canonical]
+`)
+
+	definition, err = NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+	require.NoError(t, err)
+	require.Len(t, definition.Assertions, 2)
+
+	require.Equal(t, "status contains only valid values", definition.Assertions[0].Name)
+	require.Equal(t, "enum", definition.Assertions[0].Template)
+	require.Equal(t,
+		"SELECT COUNT(*) AS bad_rows FROM fct_block FINAL\nWHERE status NOT IN ('canonical', 'it\\'s orphaned')\n",
+		definition.Assertions[0].SQL,
+	)
+	require.Contains(t, definition.Assertions[1].SQL, "FROM fct_block_head FINAL\nWHERE head_status NOT IN ('canonical')")
+
+	// Writing keeps the uses and drops the expanded assertions.
+	path := Path(t.TempDir(), "mainnet", "fct_block")
+	require.NoError(t, Write(path, definition))
+
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(written), "NOT IN")
+	require.Contains(t, string(written), "template: enum")
+}
+
+func TestExpandTemplates_Errors(t *testing.T) {
+	t.Parallel()
+
+	templates := map[string]*AssertionTemplate{
+		"not_empty": {
+			Name:   "not_empty",
+			Params: []string{"column"},
+			Assertions: []*Assertion{{
+				Name: "No empty {{column}} values",
+				SQL:  "SELECT COUNT(*) AS bad_rows FROM {{table}} WHERE empty({{column}})",
+			}},
+		},
+		"typo": {
+			Name:       "typo",
+			Assertions: []*Assertion{{Name: "Broken", SQL: "SELECT {{colum}}"}},
+		},
+		"modifier": {
+			Name:       "modifier",
+			Assertions: []*Assertion{{Name: "Broken", SQL: "SELECT {{table:upper}}"}},
+		},
+	}
+
+	tests := []struct {
+		name string
+		use  *TemplateUse
+		err  error
+	}{
+		{name: "unknown template", use: &TemplateUse{Template: "missing"}, err: errTemplateNotFound},
+		{name: "missing param", use: &TemplateUse{Template: "not_empty"}, err: errTemplateMissingParam},
+		{
+			name: "unknown param",
+			use:  &TemplateUse{Template: "not_empty", With: map[string]interface{}{"column": "a", "colour": "b"}},
+			err:  errTemplateUnknownParam,
+		},
+		{name: "undeclared placeholder", use: &TemplateUse{Template: "typo"}, err: errTemplateUnknownPlace},
+		{name: "unknown modifier", use: &TemplateUse{Template: "modifier"}, err: errTemplateUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			definition := &TestDefinition{Model: "fct_block", Uses: []*TemplateUse{tt.use}}
+			require.ErrorIs(t, expandTemplates(definition, templates), tt.err)
+		})
+	}
+}
+
+func TestLoadTemplates_Library(t *testing.T) {
+	t.Parallel()
+
+	templates, err := LoadTemplates(filepath.Join("..", "..", "..", "tests", TemplatesDir))
+	require.NoError(t, err)
+
+	for _, name := range []string{"not_empty", "unique_key", "enum", "non_negative", "not_greater_than"} {
+		require.Contains(t, templates, name)
+	}
+
+	definition := &TestDefinition{
+		Model:   "fct_block",
+		Network: "mainnet",
+		Uses: []*TemplateUse{
+			{Template: "not_empty", With: map[string]interface{}{"column": "block_root"}},
+			{Template: "unique_key", With: map[string]interface{}{"columns": []interface{}{"slot", "block_root"}}},
+			{Template: "enum", With: map[string]interface{}{"column": "status", "values": []interface{}{"canonical", "orphaned"}}},
+			{Template: "non_negative", With: map[string]interface{}{"column": "slot"}},
+			{Template: "not_greater_than", With: map[string]interface{}{
+				"column": "execution_payload_gas_used",
+				"limit":  "execution_payload_gas_limit",
+			}},
+		},
+	}
+
+	require.NoError(t, expandTemplates(definition, templates))
+	require.NoError(t, (&loader{log: logrus.New()}).validateDefinition(definition))
+	require.Len(t, definition.Assertions, 5)
+	require.Equal(t, "Unique key (slot, block_root)", definition.Assertions[1].Name)
+}
@@ -0,0 +1,170 @@
+package testdef
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlIndent matches the indentation of the checked-in test definitions.
+const yamlIndent = 4
+
+// WriteExternalData writes or refreshes the external_data section of a test definition.
+// Entries for the given tables are replaced (keeping network_mode and optional), other
+// entries and the rest of the file are preserved. A new definition is created if the
+// file does not exist.
+func WriteExternalData(path, model, network string, tables map[string]*ExternalTable) error {
+	doc := &yaml.Node{}
+
+	data, err := os.ReadFile(path) //nolint:gosec // G304: Writing test config to trusted paths
+	switch {
+	case err == nil:
+		if unmarshalErr := yaml.Unmarshal(data, doc); unmarshalErr != nil {
+			return fmt.Errorf("parsing %s: %w", path, unmarshalErr)
+		}
+	case os.IsNotExist(err):
+	default:
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	if len(doc.Content) == 0 {
+		doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: expected a mapping at the document root", path) //nolint:err113 // Include path for debugging
+	}
+
+	setScalar(root, "model", model)
+	setScalar(root, "network", network)
+
+	externalData := mappingValue(root, "external_data")
+
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		table := *tables[name]
+
+		// Keep settings chosen by hand when refreshing an existing entry.
+		if existing := mappingValue(externalData, name); len(existing.Content) > 0 {
+			var previous ExternalTable
+			if decodeErr := existing.Decode(&previous); decodeErr == nil {
+				if table.NetworkMode == "" {
+					table.NetworkMode = previous.NetworkMode
+				}
+
+				table.Optional = table.Optional || previous.Optional
+			}
+		}
+
+		node := &yaml.Node{}
+		if encodeErr := node.Encode(&table); encodeErr != nil {
+			return fmt.Errorf("encoding external_data.%s: %w", name, encodeErr)
+		}
+
+		setValue(externalData, name, node)
+	}
+
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(yamlIndent)
+
+	if encodeErr := encoder.Encode(doc); encodeErr != nil {
+		return fmt.Errorf("encoding %s: %w", path, encodeErr)
+	}
+
+	if closeErr := encoder.Close(); closeErr != nil {
+		return fmt.Errorf("encoding %s: %w", path, closeErr)
+	}
+
+	return writeFile(path, buf.Bytes())
+}
+
+// Write encodes a test definition to path in the layout of the checked-in definitions.
+// Assertions expanded from templates are left to the definition's uses.
+func Write(path string, definition *TestDefinition) error {
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(yamlIndent)
+
+	out := *definition
+	out.Assertions = make([]*Assertion, 0, len(definition.Assertions))
+
+	for _, assertion := range definition.Assertions {
+		if assertion.Template == "" {
+			out.Assertions = append(out.Assertions, assertion)
+		}
+	}
+
+	if err := encoder.Encode(&out); err != nil {
+		return fmt.Errorf("encoding %s: %w", path, err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("encoding %s: %w", path, err)
+	}
+
+	return writeFile(path, buf.Bytes())
+}
+
+// writeFile writes data to path, creating parent directories.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:gosec // G301: Test directory with standard permissions
+		return fmt.Errorf("creating directory for %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec // G306: Test config with standard permissions
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// mappingValue returns the mapping stored under key, adding an empty one if absent.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			if value.Kind != yaml.MappingNode {
+				*value = yaml.Node{Kind: yaml.MappingNode}
+			}
+
+			return value
+		}
+	}
+
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+
+	return value
+}
+
+// setValue sets key to value in a mapping, replacing any existing value in place.
+func setValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+
+			return
+		}
+	}
+
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// setScalar sets key to a string scalar in a mapping.
+func setScalar(mapping *yaml.Node, key, value string) {
+	setValue(mapping, key, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+}
@@ -0,0 +1,72 @@
+package testdef
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteExternalData_RefreshesExistingDefinition(t *testing.T) {
+	t.Parallel()
+
+	baseDir := t.TempDir()
+	path := Path(baseDir, "mainnet", "fct_block")
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+	require.NoError(t, os.WriteFile(path, []byte(`model: fct_block
+network: mainnet
+external_data:
+    canonical_beacon_block:
+        url: https://example.com/old.parquet
+        network_column: meta_network_name
+        network_mode: rewrite
+    manual_table:
+        url: https://example.com/manual.parquet
+        network_column: meta_network_name
+        optional: true
+assertions:
+    - name: Row count should be greater than zero
+      sql: |
+        SELECT COUNT(*) AS row_count FROM fct_block FINAL
+      assertions:
+        - type: greater_than
+          column: row_count
+          value: 0
+`), 0o600))
+
+	require.NoError(t, WriteExternalData(path, "fct_block", "mainnet", map[string]*ExternalTable{
+		"canonical_beacon_block":         {URL: "https://example.com/new.parquet", NetworkColumn: "meta_network_name"},
+		"beacon_api_eth_v2_beacon_block": {URL: "https://example.com/v2.parquet", NetworkColumn: "meta_network_name"},
+	}))
+
+	definition, err := NewLoader(logrus.New(), baseDir).LoadForModel("mainnet", "fct_block")
+	require.NoError(t, err)
+
+	require.Len(t, definition.ExternalData, 3)
+	require.Equal(t, "https://example.com/new.parquet", definition.ExternalData["canonical_beacon_block"].URL)
+	require.Equal(t, NetworkModeRewrite, definition.ExternalData["canonical_beacon_block"].NetworkMode)
+	require.True(t, definition.ExternalData["manual_table"].Optional)
+	require.Equal(t, "https://example.com/v2.parquet", definition.ExternalData["beacon_api_eth_v2_beacon_block"].URL)
+	require.Len(t, definition.Assertions, 1)
+	require.Equal(t, "SELECT COUNT(*) AS row_count FROM fct_block FINAL\n", definition.Assertions[0].SQL)
+}
+
+func TestWriteExternalData_CreatesDefinition(t *testing.T) {
+	t.Parallel()
+
+	baseDir := t.TempDir()
+	path := Path(baseDir, "sepolia", "canonical_beacon_block")
+
+	require.NoError(t, WriteExternalData(path, "canonical_beacon_block", "sepolia", map[string]*ExternalTable{
+		"canonical_beacon_block": {URL: "https://example.com/a.parquet", NetworkColumn: "meta_network_name"},
+	}))
+
+	definition, err := NewLoader(logrus.New(), baseDir).LoadForModel("sepolia", "canonical_beacon_block")
+	require.NoError(t, err)
+	require.Equal(t, "canonical_beacon_block", definition.Model)
+	require.Equal(t, "sepolia", definition.Network)
+	require.Len(t, definition.ExternalData, 1)
+}